@@ -6,6 +6,10 @@ dev=$1
 mask=$2
 [ -n "${mask}" ] || { echo "The mask argument is missing" >&2 ; exit 1; }
 
+# optional XPS mask and RFS flow entries per receive queue, the "-" value leaves the setting unchanged
+xps_mask=${3:--}
+rfs_flow_entries=${4:--}
+
 dev_dir="/sys/class/net/${dev}"
 
 function find_dev_dir {
@@ -33,4 +37,12 @@ function find_dev_dir {
 [ -d "${dev_dir}" ] || { sleep 5; find_dev_dir; }  # search failed, wait a little and try again
 [ -d "${dev_dir}" ] || { echo "${dev_dir}" directory not found >&2 ; exit 0; } # the interface disappeared, not an error
 
-find "${dev_dir}"/queues -type f -name rps_cpus -exec sh -c "echo ${mask} | cat > {}" \;
\ No newline at end of file
+find "${dev_dir}"/queues -type f -name rps_cpus -exec sh -c "echo ${mask} | cat > {}" \;
+
+if [ "${xps_mask}" != "-" ]; then
+  find "${dev_dir}"/queues -type f -name xps_cpus -exec sh -c "echo ${xps_mask} | cat > {}" \;
+fi
+
+if [ "${rfs_flow_entries}" != "-" ]; then
+  find "${dev_dir}"/queues -type f -name rps_flow_cnt -exec sh -c "echo ${rfs_flow_entries} | cat > {}" \;
+fi
\ No newline at end of file
@@ -27,14 +27,18 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 
 	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/config"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/metrics"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/operator"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/operator/cmd/simulate"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/cmd/render"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/signals"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/tuned"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/tuned/cmd/lint"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
 	"github.com/openshift/cluster-node-tuning-operator/version"
 )
@@ -92,6 +96,8 @@ func prepareCommands() {
 	if !config.InHyperShift() {
 		rootCmd.AddCommand(render.NewRenderCommand())
 	}
+	rootCmd.AddCommand(simulate.NewSimulateCommand())
+	rootCmd.AddCommand(lint.NewLintCommand())
 }
 
 func operatorRun() {
@@ -149,7 +155,31 @@ func operatorRun() {
 	}
 	metrics.RegisterVersion(version.Version)
 
-	if !config.InHyperShift() {
+	if config.InHyperShift() {
+		// PerformanceProfiles are delivered through ConfigMaps in management's cluster hosted namespace.
+		managementConfig, err := ntoclient.GetInClusterConfig()
+		if err != nil {
+			klog.Exitf("unable to get management cluster config: %v", err)
+		}
+		managementCluster, err := cluster.New(managementConfig, func(opts *cluster.Options) {
+			opts.Scheme = scheme
+			opts.Namespace = config.OperatorNamespace()
+		})
+		if err != nil {
+			klog.Exitf("unable to create management cluster client: %v", err)
+		}
+		if err = (&paocontroller.PerformanceProfileHyperShiftReconciler{
+			PerformanceProfileReconciler: paocontroller.PerformanceProfileReconciler{
+				Client:   mgr.GetClient(),
+				Scheme:   mgr.GetScheme(),
+				Recorder: mgr.GetEventRecorderFor("performance-profile-controller"),
+			},
+			ManagementClient: managementCluster.GetClient(),
+			Namespace:        config.OperatorNamespace(),
+		}).SetupWithManager(mgr, managementCluster); err != nil {
+			klog.Exitf("unable to create PerformanceProfile HyperShift controller: %v", err)
+		}
+	} else {
 		if err = (&paocontroller.PerformanceProfileReconciler{
 			Client:   mgr.GetClient(),
 			Scheme:   mgr.GetScheme(),
@@ -16,6 +16,11 @@ const (
 	crdFilename        = "../../../manifests/20-performance-profile.crd.yaml"
 	lastHeartbeatPath  = "/status/conditions/lastHeartbeatTime"
 	lastTransitionPath = "/status/conditions/lastTransitionTime"
+	// resource.Quantity fields are represented as int-or-string in the CRD
+	memoryKubeReservedPath   = "/spec/memory/kubeReserved"
+	memorySystemReservedPath = "/spec/memory/systemReserved"
+	memoryEvictionHardPath   = "/spec/memory/evictionHard"
+	memoryReservedLimitPath  = "/spec/memory/reservedMemory/limit"
 )
 
 var _ = Describe("PerformanceProfile CR(D) Schema", func() {
@@ -33,6 +38,10 @@ var _ = Describe("PerformanceProfile CR(D) Schema", func() {
 		pathOmissions := []string{
 			lastHeartbeatPath,
 			lastTransitionPath,
+			memoryKubeReservedPath,
+			memorySystemReservedPath,
+			memoryEvictionHardPath,
+			memoryReservedLimitPath,
 		}
 		missingEntries := getMissingEntries(schema, &performancev2.PerformanceProfile{}, pathOmissions...)
 		Expect(missingEntries).To(BeEmpty())
@@ -172,7 +172,7 @@ type WorkloadHints struct {
 	// +optional
 	HighPowerConsumption *bool `json:"highPowerConsumption,omitempty"`
 	// RealTime defines if the node should be configured for the real time workload.
-	// +default=true
+	// When not set, the node is not tuned for the real time workload, but the RPS masks are set.
 	// +optional
 	RealTime *bool `json:"realTime,omitempty"`
 }
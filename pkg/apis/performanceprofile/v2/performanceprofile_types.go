@@ -18,6 +18,7 @@ package v2
 
 import (
 	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -78,6 +79,30 @@ type PerformanceProfileSpec struct {
 	// kernel arguments that should be applied on top of the node.
 	// +optional
 	WorkloadHints *WorkloadHints `json:"workloadHints,omitempty"`
+	// Memory defines a set of memory reservation related parameters. When set, the values override
+	// the kubelet kube-reserved, system-reserved and hard eviction memory defaults, and the per NUMA node
+	// reservations used by the memory manager.
+	// +optional
+	Memory *Memory `json:"memory,omitempty"`
+	// Power defines the power and frequency policies of the reserved, isolated and shared CPUs.
+	// When set, the policies replace the node wide C-state and frequency settings, so the CPUs
+	// without a policy keep the governor and energy performance bias of the profile only.
+	// It can not be used together with the high power consumption or the per pod power management workload hints.
+	// +optional
+	Power *PowerPolicies `json:"power,omitempty"`
+	// Sysctls defines additional sysctls that should be set on the node by the generated tuned profile.
+	// The sysctls managed by the performance profile itself can not be overridden.
+	// +optional
+	Sysctls []Sysctl `json:"sysctls,omitempty"`
+	// KernelModules defines the kernel modules that should be loaded or blacklisted on the node,
+	// and the parameters that should be passed to the kernel modules.
+	// +optional
+	KernelModules *KernelModules `json:"kernelModules,omitempty"`
+	// AdditionalTunedProfile defines a list of tuned profiles that the generated tuned profile should include,
+	// after the profiles it already includes. The settings of the generated tuned profile take precedence
+	// over the settings of the included profiles.
+	// +optional
+	AdditionalTunedProfile []string `json:"additionalTunedProfile,omitempty"`
 }
 
 // CPUSet defines the set of CPUs(0-3,8-11).
@@ -106,9 +131,35 @@ type CPU struct {
 	// Offline defines a set of CPUs that will be unused and set offline
 	// +optional
 	Offlined *CPUSet `json:"offlined,omitempty"`
+	// Shared defines a set of CPUs that will be shared between containers that opted in to the shared CPU pool,
+	// in addition to the CPUs exclusively allocated to them. It has to be disjoint from the reserved,
+	// isolated and offlined CPUs. Containers opt in with the "cpu-shared.crio.io" annotation under the
+	// performance profile runtime class.
+	// +optional
+	Shared *CPUSet `json:"shared,omitempty"`
+	// Housekeeping defines the CPUs that handle device interrupts, kernel threads and RCU callbacks.
+	// Each set has to be a subset of the reserved CPUs, when a set is not specified the related
+	// housekeeping work can run on any CPU which is not isolated.
+	// +optional
+	Housekeeping *HousekeepingCPUs `json:"housekeeping,omitempty"`
 }
 
-// HugePageSize defines size of huge pages, can be 2M or 1G.
+// HousekeepingCPUs defines the CPUs that handle the housekeeping work of the node.
+type HousekeepingCPUs struct {
+	// IRQ defines a set of CPUs that will handle device interrupts. It requires the IRQ load balancing
+	// to be globally disabled, otherwise the runtime manages the IRQ affinity per pod.
+	// +optional
+	IRQ *CPUSet `json:"irq,omitempty"`
+	// KernelThreads defines a set of CPUs that will run the unbound kernel threads and workqueues.
+	// +optional
+	KernelThreads *CPUSet `json:"kernelThreads,omitempty"`
+	// RCU defines a set of CPUs that will run the RCU callbacks, the callbacks of all other CPUs
+	// are offloaded to them.
+	// +optional
+	RCU *CPUSet `json:"rcu,omitempty"`
+}
+
+// HugePageSize defines size of huge pages, can be 2M or 1G on amd64, and 64K, 2M, 32M or 1G on arm64 with the 4K kernel page size or 2M, 512M or 16G on arm64 with the 64K kernel page size.
 type HugePageSize string
 
 // HugePages defines a set of huge pages that we want to allocate at boot.
@@ -117,6 +168,15 @@ type HugePages struct {
 	DefaultHugePagesSize *HugePageSize `json:"defaultHugepagesSize,omitempty"`
 	// Pages defines huge pages that we want to allocate at boot time.
 	Pages []HugePage `json:"pages,omitempty"`
+	// Architecture defines the CPU architecture of the nodes, the supported huge page sizes depend on it.
+	// When not set, the architecture is taken from the "kubernetes.io/arch" label of the nodes
+	// selected by the NodeSelector, and defaults to "amd64" when no nodes are found.
+	// +optional
+	Architecture *string `json:"architecture,omitempty"`
+	// KernelPageSize defines the page size of the kernel of the nodes, the supported huge page sizes depend on it.
+	// Can be "4K" or, on arm64, "64K". Defaults to "4K".
+	// +optional
+	KernelPageSize *string `json:"kernelPageSize,omitempty"`
 }
 
 // HugePage defines the number of allocated huge pages of the specific size.
@@ -139,6 +199,32 @@ type NUMA struct {
 	TopologyPolicy *string `json:"topologyPolicy,omitempty"`
 }
 
+// Memory defines a set of memory reservation related parameters.
+type Memory struct {
+	// KubeReserved defines the amount of memory reserved for kubernetes system daemons. Defaults to "500Mi".
+	// +optional
+	KubeReserved *resource.Quantity `json:"kubeReserved,omitempty"`
+	// SystemReserved defines the amount of memory reserved for non-kubernetes system daemons. Defaults to "500Mi".
+	// +optional
+	SystemReserved *resource.Quantity `json:"systemReserved,omitempty"`
+	// EvictionHard defines the memory.available hard eviction threshold. Defaults to "100Mi".
+	// +optional
+	EvictionHard *resource.Quantity `json:"evictionHard,omitempty"`
+	// ReservedMemory defines the amount of memory reserved on each NUMA node. It is used by the memory manager
+	// when the topology policy is "restricted" or "single-numa-node". The sum of all reservations must be equal
+	// to the sum of KubeReserved, SystemReserved and EvictionHard. Defaults to the whole reservation on NUMA node 0.
+	// +optional
+	ReservedMemory []NUMAReservedMemory `json:"reservedMemory,omitempty"`
+}
+
+// NUMAReservedMemory defines the amount of memory reserved on a NUMA node.
+type NUMAReservedMemory struct {
+	// NUMANode defines the NUMA node the memory is reserved on.
+	NUMANode int32 `json:"numaNode"`
+	// Limit defines the amount of reserved memory.
+	Limit resource.Quantity `json:"limit"`
+}
+
 // Net defines a set of network related features
 type Net struct {
 	// UserLevelNetworking when enabled - sets either all or specified network devices queue size to the amount of reserved CPUs. Defaults to "false".
@@ -150,7 +236,7 @@ type Net struct {
 }
 
 // Device defines a way to represent a network device in several options:
-// device name, vendor ID, model ID, PCI path and MAC address
+// device name, vendor ID, model ID, PCI address, kernel driver and MAC address
 type Device struct {
 	// Network device name to be matched. It uses a syntax of shell-style wildcards which are either positive or negative.
 	// +optional
@@ -161,6 +247,33 @@ type Device struct {
 	// Network device ID (model) represnted as a 16 bit hexmadecimal number.
 	// +optional
 	DeviceID *string `json:"deviceID,omitempty"`
+	// Network device PCI address in the <domain>:<bus>:<device>.<function> format, for example 0000:3b:00.0.
+	// +optional
+	PCIAddress *string `json:"pciAddress,omitempty"`
+	// Network device kernel driver name, for example ice.
+	// +optional
+	Driver *string `json:"driver,omitempty"`
+	// Network device permanent MAC address, for example 52:54:00:12:34:56.
+	// The devices with an assigned MAC address, for example VFs or bond members, are matched only by their
+	// permanent MAC address.
+	// +optional
+	MACAddress *string `json:"macAddress,omitempty"`
+	// Channels defines the number of the combined queues of the matching network devices.
+	// Defaults to the number of the reserved CPUs.
+	// +optional
+	Channels *int32 `json:"channels,omitempty"`
+	// RPSCPUs defines the CPUs that process the packets received by the matching network devices (RPS).
+	// Defaults to the reserved CPUs.
+	// +optional
+	RPSCPUs *CPUSet `json:"rpsCPUs,omitempty"`
+	// XPSCPUs defines the CPUs that can use the transmit queues of the matching network devices (XPS).
+	// The XPS configuration of the devices is left unchanged when not specified.
+	// +optional
+	XPSCPUs *CPUSet `json:"xpsCPUs,omitempty"`
+	// RFSFlowEntries defines the number of the flow entries of every receive queue of the matching network devices (RFS).
+	// RFS is left disabled when not specified. Can not exceed 536870912, the kernel limit of the RFS socket flow table.
+	// +optional
+	RFSFlowEntries *int32 `json:"rfsFlowEntries,omitempty"`
 }
 
 // RealTimeKernel defines the set of parameters relevant for the real time kernel.
@@ -169,6 +282,66 @@ type RealTimeKernel struct {
 	Enabled *bool `json:"enabled,omitempty"`
 }
 
+// PowerPolicies defines the power and frequency policies per CPU set.
+type PowerPolicies struct {
+	// Reserved defines the power policy of the reserved CPUs.
+	// +optional
+	Reserved *CPUPowerPolicy `json:"reserved,omitempty"`
+	// Isolated defines the power policy of the isolated CPUs.
+	// +optional
+	Isolated *CPUPowerPolicy `json:"isolated,omitempty"`
+	// Shared defines the power policy of the shared CPUs, it requires the shared CPUs to be set.
+	// +optional
+	Shared *CPUPowerPolicy `json:"shared,omitempty"`
+}
+
+// Sysctl defines a kernel parameter to be set.
+type Sysctl struct {
+	// Name of the kernel parameter, for example net.core.somaxconn.
+	Name string `json:"name"`
+	// Value of the kernel parameter.
+	Value string `json:"value"`
+}
+
+// KernelModules defines the kernel modules that should be loaded, blacklisted or configured.
+type KernelModules struct {
+	// Load defines the names of the kernel modules that should be loaded on boot.
+	// +optional
+	Load []string `json:"load,omitempty"`
+	// Blacklist defines the names of the kernel modules that should be prevented from loading. A blacklisted module can not be loaded or configured.
+	// +optional
+	Blacklist []string `json:"blacklist,omitempty"`
+	// Options defines the parameters that should be passed to the kernel modules when they are loaded. The modules that are already loaded are not reloaded, they get the parameters on their next load.
+	// +optional
+	Options []KernelModuleOptions `json:"options,omitempty"`
+}
+
+// KernelModuleOptions defines the parameters of a kernel module.
+type KernelModuleOptions struct {
+	// Name of the kernel module, for example ixgbe.
+	Name string `json:"name"`
+	// Parameters defines the space separated list of the kernel module parameters, for example "allow_unsupported_sfp=1".
+	Parameters string `json:"parameters"`
+}
+
+// CPUPowerPolicy defines the power and frequency policy of a CPU set.
+type CPUPowerPolicy struct {
+	// Governor defines the CPU frequency scaling governor, for example "performance" or "powersave".
+	// Defaults to "performance"
+	// +optional
+	Governor *string `json:"governor,omitempty"`
+	// MinFrequency defines the minimum CPU frequency in kHz.
+	// +optional
+	MinFrequency *int32 `json:"minFrequency,omitempty"`
+	// MaxFrequency defines the maximum CPU frequency in kHz.
+	// +optional
+	MaxFrequency *int32 `json:"maxFrequency,omitempty"`
+	// MaxCState defines the index of the deepest idle state (C-state) the CPUs can enter,
+	// all deeper idle states are disabled. When not set, all idle states are enabled.
+	// +optional
+	MaxCState *int32 `json:"maxCState,omitempty"`
+}
+
 // WorkloadHints defines the set of upper level flags for different type of workloads.
 type WorkloadHints struct {
 	// HighPowerConsumption defines if the node should be configured in high power consumption mode.
@@ -176,9 +349,15 @@ type WorkloadHints struct {
 	// +optional
 	HighPowerConsumption *bool `json:"highPowerConsumption,omitempty"`
 	// RealTime defines if the node should be configured for the real time workload.
-	// +default=true
+	// When not set, the node is not tuned for the real time workload, but the RPS masks are set.
 	// +optional
 	RealTime *bool `json:"realTime,omitempty"`
+	// PerPodPowerManagement defines if the node should save power on the CPUs that are not used by
+	// the low latency pods, while the pods can disable the C-states and set the performance governor
+	// on their CPUs with the cpu-c-states.crio.io and cpu-freq-governor.crio.io annotations.
+	// It can not be used together with the high power consumption hint or the power policies.
+	// +optional
+	PerPodPowerManagement *bool `json:"perPodPowerManagement,omitempty"`
 }
 
 // PerformanceProfileStatus defines the observed state of PerformanceProfile.
@@ -191,6 +370,59 @@ type PerformanceProfileStatus struct {
 	Tuned *string `json:"tuned,omitempty"`
 	// RuntimeClass contains the name of the RuntimeClass resource created by the operator.
 	RuntimeClass *string `json:"runtimeClass,omitempty"`
+	// WorkloadHints contains the resolved workload hints and the tuning they enable on the node.
+	// +optional
+	WorkloadHints *WorkloadHintsStatus `json:"workloadHints,omitempty"`
+}
+
+// CStatesMode defines how the CPU idle states (C-states) are managed.
+type CStatesMode string
+
+const (
+	// CStatesLatencyLimited limits the C-states to the ones with a low exit latency
+	CStatesLatencyLimited CStatesMode = "LatencyLimited"
+	// CStatesDisabled disables the C-states deeper than C1 via kernel arguments
+	CStatesDisabled CStatesMode = "Disabled"
+	// CStatesPolling keeps the idle CPUs polling instead of entering any C-state
+	CStatesPolling CStatesMode = "Polling"
+	// CStatesPerPod lets the pods disable the C-states of their CPUs via the cpu-c-states.crio.io annotation
+	CStatesPerPod CStatesMode = "PerPod"
+)
+
+// CPUFrequencyMode defines how the CPU frequency is managed.
+type CPUFrequencyMode string
+
+const (
+	// CPUFrequencyPerformance sets the performance governor and the maximum P-state on all CPUs
+	CPUFrequencyPerformance CPUFrequencyMode = "Performance"
+	// CPUFrequencyPStatesDisabled disables the intel_pstate driver
+	CPUFrequencyPStatesDisabled CPUFrequencyMode = "PStatesDisabled"
+	// CPUFrequencyPerPod lets the pods set the governor of their CPUs via the cpu-freq-governor.crio.io annotation
+	CPUFrequencyPerPod CPUFrequencyMode = "PerPod"
+)
+
+// WorkloadHintsStatus defines the resolved workload hints and the tuning they enable.
+type WorkloadHintsStatus struct {
+	// RealTime contains the resolved real time workload hint.
+	RealTime bool `json:"realTime"`
+	// HighPowerConsumption contains the resolved high power consumption workload hint.
+	HighPowerConsumption bool `json:"highPowerConsumption"`
+	// PerPodPowerManagement contains the resolved per pod power management workload hint.
+	PerPodPowerManagement bool `json:"perPodPowerManagement"`
+	// Stalld defines if the stalld service is enabled to boost the starving threads.
+	Stalld bool `json:"stalld"`
+	// UnlimitedRTRuntime defines if the runtime of the real time tasks is unlimited (sched_rt_runtime_us=-1).
+	UnlimitedRTRuntime bool `json:"unlimitedRTRuntime"`
+	// RealTimeTuning defines if the isolated CPUs are tuned for the real time workload, with the nohz_full
+	// kernel argument and disabled watchdogs.
+	RealTimeTuning bool `json:"realTimeTuning"`
+	// RPS defines if the RPS masks of the network devices and of the containers network interfaces
+	// are set to the reserved CPUs.
+	RPS bool `json:"rps"`
+	// CStates defines how the CPU idle states are managed.
+	CStates CStatesMode `json:"cStates"`
+	// CPUFrequency defines how the CPU frequency is managed.
+	CPUFrequency CPUFrequencyMode `json:"cpuFrequency"`
 }
 
 // +kubebuilder:object:root=true
@@ -21,22 +21,84 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog"
 	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	hugepagesSize2M = "2M"
-	hugepagesSize1G = "1G"
+	hugepagesSize64K  = "64K"
+	hugepagesSize2M   = "2M"
+	hugepagesSize32M  = "32M"
+	hugepagesSize512M = "512M"
+	hugepagesSize1G   = "1G"
+	hugepagesSize16G  = "16G"
+
+	kernelPageSize4K  = "4K"
+	kernelPageSize64K = "64K"
+
+	archAMD64 = "amd64"
+	archARM64 = "arm64"
+
+	// maxRFSFlowEntries is the kernel limit of net.core.rps_sock_flow_entries, the flow entries
+	// of a single receive queue can not exceed the global socket flow table
+	maxRFSFlowEntries = 1 << 29
 )
 
+// supportedHugePagesSizes contains the huge page sizes supported by each architecture and kernel
+// page size, the kernel page size of the nodes is not known to the webhook and defaults to 4K
+var supportedHugePagesSizes = map[string]map[string][]HugePageSize{
+	archAMD64: {
+		kernelPageSize4K: {hugepagesSize1G, hugepagesSize2M},
+	},
+	archARM64: {
+		kernelPageSize4K:  {hugepagesSize64K, hugepagesSize2M, hugepagesSize32M, hugepagesSize1G},
+		kernelPageSize64K: {hugepagesSize2M, hugepagesSize512M, hugepagesSize16G},
+	},
+}
+
+// supportedCPUGovernors contains the CPU frequency scaling governors supported by the kernel
+var supportedCPUGovernors = []string{"performance", "powersave", "schedutil", "ondemand", "conservative", "userspace"}
+
+// operatorManagedSysctls contains the sysctls set by the tuned profile generated for the performance profile,
+// they can not be overridden with spec.sysctls
+var operatorManagedSysctls = []string{
+	"kernel.hung_task_timeout_secs",
+	"kernel.nmi_watchdog",
+	"kernel.numa_balancing",
+	"kernel.sched_migration_cost_ns",
+	"kernel.sched_min_granularity_ns",
+	"kernel.sched_rt_runtime_us",
+	"kernel.timer_migration",
+	"net.core.busy_poll",
+	"net.core.busy_read",
+	"net.core.rps_sock_flow_entries",
+	"net.ipv4.tcp_fastopen",
+	"vm.dirty_background_ratio",
+	"vm.dirty_ratio",
+	"vm.stat_interval",
+	"vm.swappiness",
+}
+
+// operatorManagedKernelModuleParameters contains the kernel modules parameters set by the tuned profile
+// generated for the performance profile, they can not be overridden with spec.kernelModules.options
+var operatorManagedKernelModuleParameters = map[string][]string{
+	"nf_conntrack": {"hashsize"},
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *PerformanceProfile) ValidateCreate() error {
 	klog.Infof("Create validation for the performance profile %q", r.Name)
@@ -62,8 +124,16 @@ func (r *PerformanceProfile) validateCreateOrUpdate() error {
 
 	allErrs = append(allErrs, r.validateNodeSelectorDuplication(ppList)...)
 
+	// get the nodes selected by the profile to learn their architecture, only the labels are
+	// needed, so list the metadata from the cache rather than the full node objects
+	nodeList := &metav1.PartialObjectMetadataList{}
+	nodeList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("NodeList"))
+	if err := validatorClient.List(context.TODO(), nodeList, client.MatchingLabels(r.Spec.NodeSelector)); err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
 	// validate basic fields
-	allErrs = append(allErrs, r.validateFields()...)
+	allErrs = append(allErrs, r.validateFields(nodeList.Items)...)
 
 	if len(allErrs) == 0 {
 		return nil
@@ -100,14 +170,20 @@ func (r *PerformanceProfile) validateNodeSelectorDuplication(ppList *Performance
 	return allErrs
 }
 
-func (r *PerformanceProfile) validateFields() field.ErrorList {
+func (r *PerformanceProfile) validateFields(nodes []metav1.PartialObjectMetadata) field.ErrorList {
 	var allErrs field.ErrorList
 
 	allErrs = append(allErrs, r.validateCPUs()...)
 	allErrs = append(allErrs, r.validateSelectors()...)
-	allErrs = append(allErrs, r.validateHugePages()...)
+	allErrs = append(allErrs, r.validateHugePages(nodes)...)
 	allErrs = append(allErrs, r.validateNUMA()...)
 	allErrs = append(allErrs, r.validateNet()...)
+	allErrs = append(allErrs, r.validateMemory()...)
+	allErrs = append(allErrs, r.validatePower()...)
+	allErrs = append(allErrs, r.validateWorkloadHints()...)
+	allErrs = append(allErrs, r.validateSysctls()...)
+	allErrs = append(allErrs, r.validateKernelModules()...)
+	allErrs = append(allErrs, r.validateAdditionalTunedProfile()...)
 
 	return allErrs
 }
@@ -159,9 +235,72 @@ func (r *PerformanceProfile) validateCPUs() field.ErrorList {
 					allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.cpu"), fmt.Sprintf("isolated and offlined cpus overlap: %v", overlap)))
 				}
 			}
+
+			if r.Spec.CPU.Shared != nil && cpuLists != nil {
+				shared, err := cpuset.Parse(string(*r.Spec.CPU.Shared))
+				if err != nil {
+					allErrs = append(allErrs, field.Invalid(field.NewPath("spec.cpu.shared"), r.Spec.CPU.Shared, fmt.Sprintf("failed to parse shared CPUs: %v", err)))
+				} else {
+					if shared.IsEmpty() {
+						allErrs = append(allErrs, field.Invalid(field.NewPath("spec.cpu.shared"), r.Spec.CPU.Shared, "shared CPUs can not be empty"))
+					}
+					if overlap := components.Intersect(cpuLists.GetReserved(), shared); len(overlap) != 0 {
+						allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.cpu"), fmt.Sprintf("reserved and shared cpus overlap: %v", overlap)))
+					}
+					if overlap := components.Intersect(cpuLists.GetIsolated(), shared); len(overlap) != 0 {
+						allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.cpu"), fmt.Sprintf("isolated and shared cpus overlap: %v", overlap)))
+					}
+					if overlap := components.Intersect(cpuLists.GetOfflined(), shared); len(overlap) != 0 {
+						allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.cpu"), fmt.Sprintf("offlined and shared cpus overlap: %v", overlap)))
+					}
+				}
+			}
+
+			if r.Spec.CPU.Housekeeping != nil && cpuLists != nil {
+				allErrs = append(allErrs, r.validateHousekeepingCPUs(cpuLists.GetReserved())...)
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (r *PerformanceProfile) validateHousekeepingCPUs(reserved cpuset.CPUSet) field.ErrorList {
+	var allErrs field.ErrorList
+
+	housekeeping := r.Spec.CPU.Housekeeping
+	for _, v := range []struct {
+		path string
+		cpus *CPUSet
+	}{
+		{path: "spec.cpu.housekeeping.irq", cpus: housekeeping.IRQ},
+		{path: "spec.cpu.housekeeping.kernelThreads", cpus: housekeeping.KernelThreads},
+		{path: "spec.cpu.housekeeping.rcu", cpus: housekeeping.RCU},
+	} {
+		if v.cpus == nil {
+			continue
+		}
+
+		cpus, err := cpuset.Parse(string(*v.cpus))
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(v.path), v.cpus, fmt.Sprintf("failed to parse housekeeping CPUs: %v", err)))
+			continue
+		}
+
+		if cpus.IsEmpty() {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(v.path), v.cpus, "housekeeping CPUs can not be empty"))
+		}
+
+		if !cpus.IsSubsetOf(reserved) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(v.path), v.cpus, fmt.Sprintf("housekeeping CPUs should be a subset of the reserved CPUs, found non reserved CPUs: %v", cpus.Difference(reserved).ToSlice())))
 		}
 	}
 
+	// the runtime changes the IRQ affinity per pod when IRQ load balancing is not globally disabled
+	if housekeeping.IRQ != nil && (r.Spec.GloballyDisableIrqLoadBalancing == nil || !*r.Spec.GloballyDisableIrqLoadBalancing) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec.cpu.housekeeping.irq"), housekeeping.IRQ, "IRQ housekeeping CPUs require globallyDisableIrqLoadBalancing to be enabled"))
+	}
+
 	return allErrs
 }
 
@@ -199,24 +338,43 @@ func (r *PerformanceProfile) validateSelectors() field.ErrorList {
 	return allErrs
 }
 
-func (r *PerformanceProfile) validateHugePages() field.ErrorList {
+func (r *PerformanceProfile) validateHugePages(nodes []metav1.PartialObjectMetadata) field.ErrorList {
 	var allErrs field.ErrorList
 
 	if r.Spec.HugePages == nil {
 		return allErrs
 	}
 
-	// validate that default hugepages size has correct value, currently we support only 2M and 1G(x86_64 architecture)
+	arch, err := r.getHugePagesArchitecture(nodes)
+	if err != nil {
+		return append(allErrs, err)
+	}
+
+	kernelPageSize := kernelPageSize4K
+	if r.Spec.HugePages.KernelPageSize != nil {
+		kernelPageSize = *r.Spec.HugePages.KernelPageSize
+	}
+	supportedSizes, ok := supportedHugePagesSizes[arch][kernelPageSize]
+	if !ok {
+		var kernelPageSizes []string
+		for size := range supportedHugePagesSizes[arch] {
+			kernelPageSizes = append(kernelPageSizes, size)
+		}
+		sort.Strings(kernelPageSizes)
+		return append(allErrs, field.NotSupported(field.NewPath("spec.hugepages.kernelPageSize"), kernelPageSize, kernelPageSizes))
+	}
+
+	// validate that default hugepages size has correct value, the supported sizes depend on the architecture
 	if r.Spec.HugePages.DefaultHugePagesSize != nil {
 		defaultSize := *r.Spec.HugePages.DefaultHugePagesSize
-		if defaultSize != hugepagesSize1G && defaultSize != hugepagesSize2M {
-			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.hugepages.defaultHugepagesSize"), r.Spec.HugePages.DefaultHugePagesSize, fmt.Sprintf("hugepages default size should be equal to %q or %q", hugepagesSize1G, hugepagesSize2M)))
+		if !isHugePageSizeSupported(defaultSize, supportedSizes) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.hugepages.defaultHugepagesSize"), r.Spec.HugePages.DefaultHugePagesSize, fmt.Sprintf("hugepages default size should be equal to %s on %s architecture with the %s kernel page size", hugePageSizesString(supportedSizes), arch, kernelPageSize)))
 		}
 	}
 
 	for i, page := range r.Spec.HugePages.Pages {
-		if page.Size != hugepagesSize1G && page.Size != hugepagesSize2M {
-			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.hugepages.pages"), r.Spec.HugePages.Pages, fmt.Sprintf("the page size should be equal to %q or %q", hugepagesSize1G, hugepagesSize2M)))
+		if !isHugePageSizeSupported(page.Size, supportedSizes) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.hugepages.pages"), r.Spec.HugePages.Pages, fmt.Sprintf("the page size should be equal to %s on %s architecture with the %s kernel page size", hugePageSizesString(supportedSizes), arch, kernelPageSize)))
 		}
 
 		allErrs = append(allErrs, r.validatePageDuplication(&page, r.Spec.HugePages.Pages[i+1:])...)
@@ -225,6 +383,66 @@ func (r *PerformanceProfile) validateHugePages() field.ErrorList {
 	return allErrs
 }
 
+// getHugePagesArchitecture returns the architecture used to validate huge page sizes, either the one
+// specified under the huge pages section or the one of the nodes selected by the profile.
+func (r *PerformanceProfile) getHugePagesArchitecture(nodes []metav1.PartialObjectMetadata) (string, *field.Error) {
+	if r.Spec.HugePages.Architecture != nil {
+		arch := *r.Spec.HugePages.Architecture
+		if _, ok := supportedHugePagesSizes[arch]; !ok {
+			return "", field.NotSupported(field.NewPath("spec.hugepages.architecture"), arch, []string{archAMD64, archARM64})
+		}
+		return arch, nil
+	}
+
+	if len(nodes) == 0 {
+		// the profile can be created before the nodes join the cluster
+		klog.Infof("the performance profile %q selects no nodes, validating huge page sizes for %s architecture", r.Name, archAMD64)
+		return archAMD64, nil
+	}
+
+	arch := ""
+	for _, node := range nodes {
+		nodeArch, ok := node.Labels[corev1.LabelArchStable]
+		if !ok {
+			continue
+		}
+		// nodes with other architectures keep the amd64 huge page sizes
+		if _, ok := supportedHugePagesSizes[nodeArch]; !ok {
+			nodeArch = archAMD64
+		}
+		if arch != "" && arch != nodeArch {
+			return "", field.Invalid(field.NewPath("spec.nodeSelector"), r.Spec.NodeSelector, fmt.Sprintf("the profile selects nodes with different architectures %q and %q, huge page sizes can not be validated", arch, nodeArch))
+		}
+		arch = nodeArch
+	}
+
+	if arch == "" {
+		return archAMD64, nil
+	}
+	return arch, nil
+}
+
+func isHugePageSizeSupported(size HugePageSize, supportedSizes []HugePageSize) bool {
+	for _, s := range supportedSizes {
+		if size == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hugePageSizesString returns the sizes in the form of "1G" or "2M"
+func hugePageSizesString(sizes []HugePageSize) string {
+	quoted := make([]string, 0, len(sizes))
+	for _, size := range sizes {
+		quoted = append(quoted, fmt.Sprintf("%q", size))
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
 func (r *PerformanceProfile) validatePageDuplication(page *HugePage, pages []HugePage) field.ErrorList {
 	var allErrs field.ErrorList
 
@@ -286,7 +504,16 @@ func (r *PerformanceProfile) validateNet() field.ErrorList {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net"), r.Spec.Net, "can not set network devices queues count without specifiying spec.cpu.reserved"))
 	}
 
+	steeringDevices := map[string]bool{}
 	for _, device := range r.Spec.Net.Devices {
+		if device.RPSCPUs != nil || device.XPSCPUs != nil || device.RFSFlowEntries != nil {
+			// the RPS, XPS and RFS settings of the matching network devices are applied by a service named after the device matches
+			key := netDeviceMatchesKey(device)
+			if steeringDevices[key] {
+				allErrs = append(allErrs, field.Duplicate(field.NewPath("spec.net.devices"), key))
+			}
+			steeringDevices[key] = true
+		}
 		if device.InterfaceName != nil && *device.InterfaceName == "" {
 			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, "device name cannot be empty"))
 		}
@@ -299,16 +526,365 @@ func (r *PerformanceProfile) validateNet() field.ErrorList {
 		if device.DeviceID != nil && device.VendorID == nil {
 			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device model ID can not be used without specifying the device vendor ID.")))
 		}
+		if device.PCIAddress != nil && !isValidPCIAddress(*device.PCIAddress) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device PCI address %s has an invalid format. PCI address should be represented as <domain>:<bus>:<device>.<function>, for example 0000:3b:00.0", *device.PCIAddress)))
+		}
+		if device.Driver != nil && !isValidDriverName(*device.Driver) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device driver %q has an invalid format. Driver name should contain only alphanumeric characters, '_' and '-'", *device.Driver)))
+		}
+		if device.MACAddress != nil && !isValidMACAddress(*device.MACAddress) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device MAC address %s has an invalid format. MAC address should be represented as 6 colon separated hexadecimal octets, for example 52:54:00:12:34:56", *device.MACAddress)))
+		}
+		if device.Channels != nil && *device.Channels <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device channels %d should be greater than 0", *device.Channels)))
+		}
+		if device.RFSFlowEntries != nil && *device.RFSFlowEntries <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device RFS flow entries %d should be greater than 0", *device.RFSFlowEntries)))
+		}
+		if device.RFSFlowEntries != nil && *device.RFSFlowEntries > maxRFSFlowEntries {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), r.Spec.Net.Devices, fmt.Sprintf("device RFS flow entries %d should not be greater than %d", *device.RFSFlowEntries, maxRFSFlowEntries)))
+		}
+		allErrs = append(allErrs, r.validateNetDeviceCPUs("RPS", device.RPSCPUs)...)
+		allErrs = append(allErrs, r.validateNetDeviceCPUs("XPS", device.XPSCPUs)...)
+
+		hasQueuesSettings := device.Channels != nil || device.RPSCPUs != nil || device.XPSCPUs != nil || device.RFSFlowEntries != nil
+		if hasQueuesSettings && (r.Spec.Net.UserLevelNetworking == nil || !*r.Spec.Net.UserLevelNetworking) {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.net.devices"), "device channels, RPS, XPS and RFS settings can not be used without enabling spec.net.userLevelNetworking"))
+		}
+	}
+	return allErrs
+}
+
+// netDeviceMatchesKey returns a key identifying the network devices matched by the device
+func netDeviceMatchesKey(device Device) string {
+	var matches []string
+	for _, match := range []struct {
+		name  string
+		value *string
+		// the PCI and MAC addresses are matched case insensitively
+		caseInsensitive bool
+	}{
+		{name: "interfaceName", value: device.InterfaceName},
+		{name: "vendorID", value: device.VendorID},
+		{name: "deviceID", value: device.DeviceID},
+		{name: "pciAddress", value: device.PCIAddress, caseInsensitive: true},
+		{name: "driver", value: device.Driver},
+		{name: "macAddress", value: device.MACAddress, caseInsensitive: true},
+	} {
+		if match.value == nil {
+			continue
+		}
+		value := *match.value
+		if match.caseInsensitive {
+			value = strings.ToLower(value)
+		}
+		matches = append(matches, fmt.Sprintf("%s=%s", match.name, value))
+	}
+	return strings.Join(matches, ",")
+}
+
+func (r *PerformanceProfile) validateNetDeviceCPUs(steering string, cpus *CPUSet) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if cpus == nil {
+		return allErrs
+	}
+
+	cpusSet, err := cpuset.Parse(string(*cpus))
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), *cpus, fmt.Sprintf("device %s CPUs have an invalid format: %v", steering, err)))
+		return allErrs
+	}
+
+	if cpusSet.IsEmpty() {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec.net.devices"), *cpus, fmt.Sprintf("device %s CPUs can not be empty", steering)))
+	}
+
+	if r.Spec.CPU != nil && r.Spec.CPU.Offlined != nil {
+		offlined, err := cpuset.Parse(string(*r.Spec.CPU.Offlined))
+		if err == nil && !cpusSet.Intersection(offlined).IsEmpty() {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.net.devices"), fmt.Sprintf("device %s CPUs and offlined cpus overlap: %v", steering, cpusSet.Intersection(offlined))))
+		}
+	}
+
+	return allErrs
+}
+
+func (r *PerformanceProfile) validateMemory() field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.Memory == nil {
+		return allErrs
+	}
+
+	memory := r.Spec.Memory
+	reserved := resource.NewQuantity(0, resource.BinarySI)
+	for _, v := range []struct {
+		path         string
+		value        *resource.Quantity
+		defaultValue string
+	}{
+		{path: "spec.memory.kubeReserved", value: memory.KubeReserved, defaultValue: components.DefaultKubeReservedMemory},
+		{path: "spec.memory.systemReserved", value: memory.SystemReserved, defaultValue: components.DefaultSystemReservedMemory},
+		{path: "spec.memory.evictionHard", value: memory.EvictionHard, defaultValue: components.DefaultHardEvictionThreshold},
+	} {
+		if v.value == nil {
+			reserved.Add(resource.MustParse(v.defaultValue))
+			continue
+		}
+		if v.value.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(v.path), v.value.String(), "the value can not be negative"))
+		}
+		reserved.Add(*v.value)
+	}
+
+	if len(memory.ReservedMemory) == 0 {
+		return allErrs
+	}
+
+	// the memory manager requires the per NUMA node reservations to sum up to the kubelet reservation
+	numaReserved := resource.NewQuantity(0, resource.BinarySI)
+	numaNodes := map[int32]bool{}
+	for _, numaReservation := range memory.ReservedMemory {
+		if numaReservation.NUMANode < 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.memory.reservedMemory"), numaReservation.NUMANode, "the NUMA node can not be negative"))
+		}
+		if numaNodes[numaReservation.NUMANode] {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.memory.reservedMemory"), numaReservation.NUMANode, fmt.Sprintf("the NUMA node %d has duplication", numaReservation.NUMANode)))
+		}
+		numaNodes[numaReservation.NUMANode] = true
+
+		if numaReservation.Limit.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.memory.reservedMemory"), numaReservation.Limit.String(), fmt.Sprintf("the reserved memory of the NUMA node %d can not be negative", numaReservation.NUMANode)))
+		}
+		numaReserved.Add(numaReservation.Limit)
+	}
+
+	if numaReserved.Cmp(*reserved) != 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec.memory.reservedMemory"), numaReserved.String(), fmt.Sprintf("the sum of the per NUMA node reserved memory must be equal to the sum of kube-reserved, system-reserved and the hard eviction threshold (%s)", reserved.String())))
+	}
+
+	return allErrs
+}
+
+func (r *PerformanceProfile) validatePower() field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.Power == nil {
+		return allErrs
+	}
+
+	if r.Spec.WorkloadHints != nil && r.Spec.WorkloadHints.HighPowerConsumption != nil && *r.Spec.WorkloadHints.HighPowerConsumption {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.power"), "power policies can not be used together with the high power consumption workload hint"))
+	}
+
+	if r.Spec.WorkloadHints != nil && r.Spec.WorkloadHints.PerPodPowerManagement != nil && *r.Spec.WorkloadHints.PerPodPowerManagement {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.power"), "power policies can not be used together with the per pod power management workload hint"))
+	}
+
+	if r.Spec.Power.Shared != nil && r.Spec.CPU.Shared == nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.power.shared"), "the shared CPUs power policy requires the shared CPUs to be set"))
+	}
+
+	for _, v := range []struct {
+		path   string
+		policy *CPUPowerPolicy
+	}{
+		{path: "spec.power.reserved", policy: r.Spec.Power.Reserved},
+		{path: "spec.power.isolated", policy: r.Spec.Power.Isolated},
+		{path: "spec.power.shared", policy: r.Spec.Power.Shared},
+	} {
+		if v.policy != nil {
+			allErrs = append(allErrs, validateCPUPowerPolicy(field.NewPath(v.path), v.policy)...)
+		}
+	}
+
+	return allErrs
+}
+
+func validateCPUPowerPolicy(path *field.Path, policy *CPUPowerPolicy) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if policy.Governor != nil && !isCPUGovernorSupported(*policy.Governor) {
+		allErrs = append(allErrs, field.Invalid(path.Child("governor"), *policy.Governor, fmt.Sprintf("the governor should be one of %q", supportedCPUGovernors)))
+	}
+
+	if policy.MinFrequency != nil && *policy.MinFrequency <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("minFrequency"), *policy.MinFrequency, "the frequency should be greater than 0"))
+	}
+
+	if policy.MaxFrequency != nil && *policy.MaxFrequency <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxFrequency"), *policy.MaxFrequency, "the frequency should be greater than 0"))
+	}
+
+	if policy.MinFrequency != nil && policy.MaxFrequency != nil && *policy.MinFrequency > *policy.MaxFrequency {
+		allErrs = append(allErrs, field.Invalid(path.Child("minFrequency"), *policy.MinFrequency, fmt.Sprintf("the minimum frequency can not be greater than the maximum frequency %d", *policy.MaxFrequency)))
 	}
+
+	if policy.MaxCState != nil && *policy.MaxCState < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxCState"), *policy.MaxCState, "the C-state can not be negative"))
+	}
+
 	return allErrs
 }
 
+func isCPUGovernorSupported(governor string) bool {
+	for _, supported := range supportedCPUGovernors {
+		if governor == supported {
+			return true
+		}
+	}
+	return false
+}
+
 func isValid16bitsHexID(v string) bool {
 	re := regexp.MustCompile("^0x[0-9a-fA-F]+$")
 	return re.MatchString(v) && len(v) < 7
 }
 
-func (r *PerformanceProfile) validateWorkloadHints() field.ErrorList {
+func isValidPCIAddress(v string) bool {
+	re := regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-1][0-9a-fA-F]\.[0-7]$`)
+	return re.MatchString(v)
+}
+
+func isValidDriverName(v string) bool {
+	re := regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+	return re.MatchString(v)
+}
+
+func isValidMACAddress(v string) bool {
+	re := regexp.MustCompile("^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}$")
+	return re.MatchString(v)
+}
+
+func (r *PerformanceProfile) validateSysctls() field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := map[string]bool{}
+	for _, sysctl := range r.Spec.Sysctls {
+		if !isValidSysctlName(sysctl.Name) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.sysctls"), sysctl.Name, "the sysctl name should consist of lower case alphanumeric characters, '-' and '_', separated by '.'"))
+			continue
+		}
+
+		if names[sysctl.Name] {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec.sysctls"), sysctl.Name))
+		}
+		names[sysctl.Name] = true
+
+		for _, managed := range operatorManagedSysctls {
+			if sysctl.Name == managed {
+				allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.sysctls"), fmt.Sprintf("the sysctl %s is managed by the performance profile and can not be overridden", sysctl.Name)))
+			}
+		}
+
+		if sysctl.Value == "" || strings.ContainsAny(sysctl.Value, "\r\n") {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.sysctls"), sysctl.Value, fmt.Sprintf("the sysctl %s value should be a non empty single line", sysctl.Name)))
+		}
+	}
+
+	return allErrs
+}
+
+func isValidSysctlName(v string) bool {
+	// tuned gets the sysctl path by replacing every '.' with '/', so the names with the '/' separator are not supported
+	re := regexp.MustCompile(`^([a-z0-9]([-_a-z0-9]*[a-z0-9])?\.)*[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+	return re.MatchString(v) && len(v) <= 253
+}
+
+func (r *PerformanceProfile) validateKernelModules() field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.KernelModules == nil {
+		return allErrs
+	}
+
+	blacklisted := map[string]bool{}
+	for _, module := range r.Spec.KernelModules.Blacklist {
+		if !isValidKernelModuleName(module) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.kernelModules.blacklist"), module, "the kernel module name should contain only alphanumeric characters, '_' and '-'"))
+		}
+		if blacklisted[module] {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec.kernelModules.blacklist"), module))
+		}
+		blacklisted[module] = true
+	}
+
+	loaded := map[string]bool{}
+	for _, module := range r.Spec.KernelModules.Load {
+		if !isValidKernelModuleName(module) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.kernelModules.load"), module, "the kernel module name should contain only alphanumeric characters, '_' and '-'"))
+		}
+		if loaded[module] {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec.kernelModules.load"), module))
+		}
+		loaded[module] = true
+		if blacklisted[module] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.kernelModules.load"), fmt.Sprintf("the kernel module %s can not be loaded and blacklisted at the same time", module)))
+		}
+	}
+
+	configured := map[string]bool{}
+	for _, options := range r.Spec.KernelModules.Options {
+		if !isValidKernelModuleName(options.Name) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.kernelModules.options"), options.Name, "the kernel module name should contain only alphanumeric characters, '_' and '-'"))
+			continue
+		}
+		if configured[options.Name] {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec.kernelModules.options"), options.Name))
+		}
+		configured[options.Name] = true
+		if blacklisted[options.Name] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.kernelModules.options"), fmt.Sprintf("the kernel module %s can not be configured and blacklisted at the same time", options.Name)))
+		}
+
+		if options.Parameters == "" || strings.ContainsAny(options.Parameters, "\r\n") {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.kernelModules.options"), options.Parameters, fmt.Sprintf("the kernel module %s parameters should be a non empty single line", options.Name)))
+			continue
+		}
+
+		for _, parameter := range strings.Fields(options.Parameters) {
+			name := strings.SplitN(parameter, "=", 2)[0]
+			for _, managed := range operatorManagedKernelModuleParameters[options.Name] {
+				if name == managed {
+					allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.kernelModules.options"), fmt.Sprintf("the kernel module %s parameter %s is managed by the performance profile and can not be overridden", options.Name, name)))
+				}
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func isValidKernelModuleName(v string) bool {
+	re := regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+	return re.MatchString(v)
+}
+
+func (r *PerformanceProfile) validateAdditionalTunedProfile() field.ErrorList {
+	var allErrs field.ErrorList
+
+	name := components.GetComponentName(r.Name, components.ProfileNamePerformance)
+	for _, profile := range r.Spec.AdditionalTunedProfile {
+		if !isValidTunedProfileName(profile) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec.additionalTunedProfile"), profile, "the tuned profile name should contain only alphanumeric characters, '.', '_' and '-'"))
+		}
+		if profile == name {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.additionalTunedProfile"), fmt.Sprintf("the tuned profile %s is generated for the performance profile and can not include itself", profile)))
+		}
+	}
+
+	return allErrs
+}
+
+func isValidTunedProfileName(v string) bool {
+	re := regexp.MustCompile("^[a-zA-Z0-9_.-]+$")
+	return re.MatchString(v)
+}
+
+// validateRealTimeKernelWorkloadHint is not part of validateFields, the profiles enabling the realtime
+// kernel with the realtime workload hint explicitly disabled are not rejected.
+func (r *PerformanceProfile) validateRealTimeKernelWorkloadHint() field.ErrorList {
 	var allErrs field.ErrorList
 
 	if r.Spec.WorkloadHints == nil {
@@ -325,3 +901,17 @@ func (r *PerformanceProfile) validateWorkloadHints() field.ErrorList {
 
 	return allErrs
 }
+
+func (r *PerformanceProfile) validateWorkloadHints() field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.WorkloadHints == nil {
+		return allErrs
+	}
+
+	if _, err := ResolveWorkloadHints(r.Spec.WorkloadHints); err != nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec.workloadHints"), err.Error()))
+	}
+
+	return allErrs
+}
@@ -2,12 +2,18 @@ package v2
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
+
+	assets "github.com/openshift/cluster-node-tuning-operator/assets/performanceprofile"
 )
 
 const (
@@ -206,6 +212,64 @@ var _ = Describe("PerformanceProfile", func() {
 			Expect(errors).NotTo(BeEmpty(), "should have validation error when isolated and offlined CPUs have overlap")
 			Expect(errors[0].Error()).To(ContainSubstring("isolated and offlined cpus overlap"))
 		})
+		It("should allow cpus allocation with shared CPUs", func() {
+			sharedCPUs := CPUSet("8-9")
+			profile.Spec.CPU.Shared = &sharedCPUs
+			errors := profile.validateCPUs()
+			Expect(errors).To(BeEmpty())
+		})
+
+		It("should reject cpus allocation with overlapping sets between shared and other sets", func() {
+			sharedCPUs := CPUSet("3-4,7")
+			profile.Spec.CPU.Shared = &sharedCPUs
+			errors := profile.validateCPUs()
+			Expect(len(errors)).To(Equal(3), "should have validation errors when shared CPUs overlap with reserved, isolated and offlined CPUs")
+			Expect(errors[0].Error()).To(ContainSubstring("reserved and shared cpus overlap"))
+			Expect(errors[1].Error()).To(ContainSubstring("isolated and shared cpus overlap"))
+			Expect(errors[2].Error()).To(ContainSubstring("offlined and shared cpus overlap"))
+		})
+
+		It("should reject cpus allocation with empty shared CPUs", func() {
+			sharedCPUs := CPUSet("")
+			profile.Spec.CPU.Shared = &sharedCPUs
+			errors := profile.validateCPUs()
+			Expect(errors).NotTo(BeEmpty())
+			Expect(errors[0].Error()).To(ContainSubstring("shared CPUs can not be empty"))
+		})
+
+		It("should allow housekeeping CPUs confined to the reserved CPUs", func() {
+			irqCPUs := CPUSet("0")
+			kernelThreadsCPUs := CPUSet("0-1")
+			rcuCPUs := CPUSet("1")
+			profile.Spec.GloballyDisableIrqLoadBalancing = pointer.BoolPtr(true)
+			profile.Spec.CPU.Housekeeping = &HousekeepingCPUs{
+				IRQ:           &irqCPUs,
+				KernelThreads: &kernelThreadsCPUs,
+				RCU:           &rcuCPUs,
+			}
+			errors := profile.validateCPUs()
+			Expect(errors).To(BeEmpty())
+		})
+
+		It("should reject housekeeping CPUs outside of the reserved CPUs", func() {
+			rcuCPUs := CPUSet("3-4")
+			profile.Spec.CPU.Housekeeping = &HousekeepingCPUs{
+				RCU: &rcuCPUs,
+			}
+			errors := profile.validateCPUs()
+			Expect(errors).NotTo(BeEmpty())
+			Expect(errors[0].Error()).To(ContainSubstring("housekeeping CPUs should be a subset of the reserved CPUs, found non reserved CPUs: [4]"))
+		})
+
+		It("should reject IRQ housekeeping CPUs without globally disabled IRQ load balancing", func() {
+			irqCPUs := CPUSet("0")
+			profile.Spec.CPU.Housekeeping = &HousekeepingCPUs{
+				IRQ: &irqCPUs,
+			}
+			errors := profile.validateCPUs()
+			Expect(errors).NotTo(BeEmpty())
+			Expect(errors[0].Error()).To(ContainSubstring("IRQ housekeeping CPUs require globallyDisableIrqLoadBalancing to be enabled"))
+		})
 	})
 
 	Describe("Label selectors validation", func() {
@@ -258,7 +322,7 @@ var _ = Describe("PerformanceProfile", func() {
 			incorrectDefaultSize := HugePageSize("!#@")
 			profile.Spec.HugePages.DefaultHugePagesSize = &incorrectDefaultSize
 
-			errors := profile.validateHugePages()
+			errors := profile.validateHugePages(nil)
 			Expect(errors).NotTo(BeEmpty(), "should have validation error when default huge pages size has invalid value")
 			Expect(errors[0].Error()).To(ContainSubstring("hugepages default size should be equal"))
 		})
@@ -269,11 +333,99 @@ var _ = Describe("PerformanceProfile", func() {
 				Node:  pointer.Int32Ptr(0),
 				Size:  "14M",
 			})
-			errors := profile.validateHugePages()
+			errors := profile.validateHugePages(nil)
 			Expect(errors).NotTo(BeEmpty(), "should have validation error when page with invalid format presents")
 			Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("the page size should be equal to %q or %q", hugepagesSize1G, hugepagesSize2M)))
 		})
 
+		It("should reject arm64 page sizes on amd64 nodes", func() {
+			profile.Spec.HugePages.Pages = append(profile.Spec.HugePages.Pages, HugePage{
+				Count: 4,
+				Size:  hugepagesSize512M,
+			})
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archAMD64)})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error when page size is not supported by the nodes architecture")
+			Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("the page size should be equal to %q or %q on amd64 architecture", hugepagesSize1G, hugepagesSize2M)))
+		})
+
+		It("should accept arm64 page sizes on arm64 nodes", func() {
+			size := HugePageSize(hugepagesSize64K)
+			profile.Spec.HugePages.DefaultHugePagesSize = &size
+			profile.Spec.HugePages.Pages = []HugePage{
+				{Count: 4, Size: hugepagesSize32M},
+				{Count: 1, Size: hugepagesSize1G, Node: pointer.Int32Ptr(0)},
+			}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archARM64)})
+			Expect(errors).To(BeEmpty(), "should not have validation errors with arm64 page sizes on arm64 nodes")
+		})
+
+		It("should reject the 64K kernel page size huge page sizes on arm64 nodes", func() {
+			profile.Spec.HugePages.Pages = []HugePage{{Count: 4, Size: hugepagesSize512M}}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archARM64)})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error when page size is supported only with the 64K kernel page size")
+			Expect(errors[0].Error()).To(ContainSubstring("on arm64 architecture with the 4K kernel page size"))
+		})
+
+		It("should accept the 64K kernel page size huge page sizes on arm64 nodes with the 64K kernel page size", func() {
+			size := HugePageSize(hugepagesSize512M)
+			profile.Spec.HugePages.KernelPageSize = pointer.String(kernelPageSize64K)
+			profile.Spec.HugePages.DefaultHugePagesSize = &size
+			profile.Spec.HugePages.Pages = []HugePage{
+				{Count: 4, Size: hugepagesSize2M},
+				{Count: 8, Size: hugepagesSize512M},
+				{Count: 1, Size: hugepagesSize16G, Node: pointer.Int32Ptr(0)},
+			}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archARM64)})
+			Expect(errors).To(BeEmpty(), "should not have validation errors with the 64K kernel page size huge page sizes")
+		})
+
+		It("should reject the 4K kernel page size huge page sizes on arm64 nodes with the 64K kernel page size", func() {
+			profile.Spec.HugePages.KernelPageSize = pointer.String(kernelPageSize64K)
+			profile.Spec.HugePages.Pages = []HugePage{{Count: 4, Size: hugepagesSize32M}}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archARM64)})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error when page size is supported only with the 4K kernel page size")
+			Expect(errors[len(errors)-1].Error()).To(ContainSubstring(fmt.Sprintf("the page size should be equal to %q, %q or %q on arm64 architecture with the 64K kernel page size", hugepagesSize2M, hugepagesSize512M, hugepagesSize16G)))
+		})
+
+		It("should reject the kernel page sizes not supported by the architecture", func() {
+			profile.Spec.HugePages.KernelPageSize = pointer.String(kernelPageSize64K)
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archAMD64)})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error when the 64K kernel page size is used on amd64")
+			Expect(errors[0].Error()).To(ContainSubstring(`spec.hugepages.kernelPageSize: Unsupported value: "64K": supported values: "4K"`))
+
+			profile.Spec.HugePages.KernelPageSize = pointer.String("16K")
+			errors = profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archARM64)})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error with an unknown kernel page size")
+			Expect(errors[0].Error()).To(ContainSubstring(`supported values: "4K", "64K"`))
+		})
+
+		It("should validate the amd64 page sizes when the profile selects no nodes", func() {
+			profile.Spec.HugePages.Pages = []HugePage{{Count: 4, Size: hugepagesSize32M}}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{})
+			Expect(errors).NotTo(BeEmpty(), "should have validation error when page size is not supported by amd64")
+			Expect(errors[0].Error()).To(ContainSubstring("on amd64 architecture"))
+
+			profile.Spec.HugePages.Architecture = pointer.String(archARM64)
+			errors = profile.validateHugePages([]metav1.PartialObjectMetadata{})
+			Expect(errors).To(BeEmpty(), "should not have validation errors with an explicit arm64 architecture")
+		})
+
+		It("should take the explicit architecture over the nodes one", func() {
+			profile.Spec.HugePages.Architecture = pointer.String(archARM64)
+			profile.Spec.HugePages.Pages = []HugePage{{Count: 4, Size: hugepagesSize32M}}
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{newNodeWithArch("node1", archAMD64)})
+			Expect(errors).To(BeEmpty(), "should not have validation errors with arm64 page sizes and explicit arm64 architecture")
+		})
+
+		It("should reject nodes with different architectures", func() {
+			errors := profile.validateHugePages([]metav1.PartialObjectMetadata{
+				newNodeWithArch("node1", archAMD64),
+				newNodeWithArch("node2", archARM64),
+			})
+			Expect(errors).NotTo(BeEmpty())
+			Expect(errors[0].Error()).To(ContainSubstring("the profile selects nodes with different architectures"))
+		})
+
 		When("pages have duplication", func() {
 			Context("with specified NUMA node", func() {
 				It("should raise the validation error", func() {
@@ -287,7 +439,7 @@ var _ = Describe("PerformanceProfile", func() {
 						Size:  hugepagesSize1G,
 						Node:  pointer.Int32Ptr(0),
 					})
-					errors := profile.validateHugePages()
+					errors := profile.validateHugePages(nil)
 					Expect(errors).NotTo(BeEmpty())
 					Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("the page with the size %q and with specified NUMA node 0, has duplication", hugepagesSize1G)))
 				})
@@ -299,7 +451,7 @@ var _ = Describe("PerformanceProfile", func() {
 						Count: 128,
 						Size:  hugepagesSize1G,
 					})
-					errors := profile.validateHugePages()
+					errors := profile.validateHugePages(nil)
 					Expect(errors).NotTo(BeEmpty())
 					Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("the page with the size %q and without the specified NUMA node, has duplication", hugepagesSize1G)))
 				})
@@ -315,7 +467,7 @@ var _ = Describe("PerformanceProfile", func() {
 						Count: 128,
 						Size:  hugepagesSize1G,
 					})
-					errors := profile.validateHugePages()
+					errors := profile.validateHugePages(nil)
 					Expect(errors).NotTo(BeEmpty())
 					Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("the page with the size %q and without the specified NUMA node, has duplication", hugepagesSize1G)))
 				})
@@ -323,6 +475,126 @@ var _ = Describe("PerformanceProfile", func() {
 		})
 	})
 
+	Describe("Memory validation", func() {
+		Context("with per NUMA node reservations matching the kubelet reservation", func() {
+			It("should not raise validation errors", func() {
+				kubeReserved := resource.MustParse("1Gi")
+				profile.Spec.Memory = &Memory{
+					KubeReserved: &kubeReserved,
+					ReservedMemory: []NUMAReservedMemory{
+						{NUMANode: 0, Limit: resource.MustParse("1124Mi")},
+						{NUMANode: 1, Limit: resource.MustParse("500Mi")},
+					},
+				}
+				errors := profile.validateMemory()
+				Expect(errors).To(BeEmpty(), "should not have validation errors when the reservations sum up to the kubelet reservation")
+			})
+		})
+
+		Context("with per NUMA node reservations not matching the kubelet reservation", func() {
+			It("should raise the validation error", func() {
+				profile.Spec.Memory = &Memory{
+					ReservedMemory: []NUMAReservedMemory{
+						{NUMANode: 0, Limit: resource.MustParse("500Mi")},
+						{NUMANode: 1, Limit: resource.MustParse("500Mi")},
+					},
+				}
+				errors := profile.validateMemory()
+				Expect(errors).NotTo(BeEmpty())
+				Expect(errors[0].Error()).To(ContainSubstring("the sum of the per NUMA node reserved memory must be equal to the sum of kube-reserved, system-reserved and the hard eviction threshold (1100Mi)"))
+			})
+		})
+
+		Context("with duplicated NUMA nodes", func() {
+			It("should raise the validation error", func() {
+				profile.Spec.Memory = &Memory{
+					ReservedMemory: []NUMAReservedMemory{
+						{NUMANode: 0, Limit: resource.MustParse("600Mi")},
+						{NUMANode: 0, Limit: resource.MustParse("500Mi")},
+					},
+				}
+				errors := profile.validateMemory()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("the NUMA node 0 has duplication"))
+			})
+		})
+	})
+
+	Describe("Power validation", func() {
+		Context("with valid power policies", func() {
+			It("should not raise validation errors", func() {
+				profile.Spec.Power = &PowerPolicies{
+					Reserved: &CPUPowerPolicy{
+						Governor:     pointer.StringPtr("powersave"),
+						MinFrequency: pointer.Int32Ptr(800000),
+						MaxFrequency: pointer.Int32Ptr(2000000),
+					},
+					Isolated: &CPUPowerPolicy{
+						Governor:  pointer.StringPtr("performance"),
+						MaxCState: pointer.Int32Ptr(1),
+					},
+				}
+				errors := profile.validatePower()
+				Expect(errors).To(BeEmpty(), "should not have validation errors with valid power policies")
+			})
+		})
+
+		Context("with invalid power policies", func() {
+			It("should raise the validation errors", func() {
+				profile.Spec.Power = &PowerPolicies{
+					Reserved: &CPUPowerPolicy{
+						Governor:     pointer.StringPtr("turbo"),
+						MinFrequency: pointer.Int32Ptr(2000000),
+						MaxFrequency: pointer.Int32Ptr(800000),
+					},
+					Isolated: &CPUPowerPolicy{
+						MaxCState: pointer.Int32Ptr(-1),
+					},
+				}
+				errors := profile.validatePower()
+				Expect(len(errors)).To(Equal(3))
+				Expect(errors[0].Error()).To(ContainSubstring("the governor should be one of"))
+				Expect(errors[1].Error()).To(ContainSubstring("the minimum frequency can not be greater than the maximum frequency 800000"))
+				Expect(errors[2].Error()).To(ContainSubstring("the C-state can not be negative"))
+			})
+		})
+
+		Context("with the shared CPUs power policy without shared CPUs", func() {
+			It("should raise the validation error", func() {
+				profile.Spec.Power = &PowerPolicies{
+					Shared: &CPUPowerPolicy{Governor: pointer.StringPtr("powersave")},
+				}
+				errors := profile.validatePower()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("the shared CPUs power policy requires the shared CPUs to be set"))
+			})
+		})
+
+		Context("with the high power consumption workload hint", func() {
+			It("should raise the validation error", func() {
+				profile.Spec.WorkloadHints = &WorkloadHints{HighPowerConsumption: pointer.BoolPtr(true)}
+				profile.Spec.Power = &PowerPolicies{
+					Reserved: &CPUPowerPolicy{Governor: pointer.StringPtr("powersave")},
+				}
+				errors := profile.validatePower()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("power policies can not be used together with the high power consumption workload hint"))
+			})
+		})
+
+		Context("with the per pod power management workload hint", func() {
+			It("should raise the validation error", func() {
+				profile.Spec.WorkloadHints = &WorkloadHints{PerPodPowerManagement: pointer.BoolPtr(true)}
+				profile.Spec.Power = &PowerPolicies{
+					Isolated: &CPUPowerPolicy{MaxCState: pointer.Int32Ptr(1)},
+				}
+				errors := profile.validatePower()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("power policies can not be used together with the per pod power management workload hint"))
+			})
+		})
+	})
+
 	Describe("Net validation", func() {
 		Context("with properly populated fields", func() {
 			It("should have net fields properly populated", func() {
@@ -344,6 +616,79 @@ var _ = Describe("PerformanceProfile", func() {
 				Expect(errors[2].Error()).To(ContainSubstring(fmt.Sprintf("device model ID %s has an invalid format. Model ID should be represented as 0x<4 hexadecimal digits> (16 bit representation)", invalidDevice)))
 
 			})
+			It("should raise the validation errors for invalid PCI address, driver and MAC address", func() {
+				invalidPCIAddress := "3b:00.0"
+				invalidDriver := "ice driver"
+				invalidMACAddress := "52:54:00:12:34"
+				profile.Spec.Net.Devices[0].PCIAddress = pointer.StringPtr(invalidPCIAddress)
+				profile.Spec.Net.Devices[0].Driver = pointer.StringPtr(invalidDriver)
+				profile.Spec.Net.Devices[0].MACAddress = pointer.StringPtr(invalidMACAddress)
+				errors := profile.validateNet()
+				Expect(len(errors)).To(Equal(3))
+				Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("device PCI address %s has an invalid format", invalidPCIAddress)))
+				Expect(errors[1].Error()).To(ContainSubstring(fmt.Sprintf("device driver %q has an invalid format", invalidDriver)))
+				Expect(errors[2].Error()).To(ContainSubstring(fmt.Sprintf("device MAC address %s has an invalid format", invalidMACAddress)))
+			})
+			It("should accept valid PCI address, driver and MAC address", func() {
+				profile.Spec.Net.Devices[0].PCIAddress = pointer.StringPtr("0000:3b:00.1")
+				profile.Spec.Net.Devices[0].Driver = pointer.StringPtr("mlx5_core")
+				profile.Spec.Net.Devices[0].MACAddress = pointer.StringPtr("52:54:00:AB:cd:56")
+				errors := profile.validateNet()
+				Expect(errors).To(BeEmpty())
+			})
+			It("should raise the validation errors for invalid channels, RPS, XPS and RFS settings", func() {
+				rpsCPUs := CPUSet("0-")
+				xpsCPUs := CPUSet("6-7")
+				profile.Spec.Net.Devices[0].Channels = pointer.Int32Ptr(0)
+				profile.Spec.Net.Devices[0].RFSFlowEntries = pointer.Int32Ptr(-1)
+				profile.Spec.Net.Devices[0].RPSCPUs = &rpsCPUs
+				profile.Spec.Net.Devices[0].XPSCPUs = &xpsCPUs
+				errors := profile.validateNet()
+				Expect(len(errors)).To(Equal(4))
+				Expect(errors[0].Error()).To(ContainSubstring("device channels 0 should be greater than 0"))
+				Expect(errors[1].Error()).To(ContainSubstring("device RFS flow entries -1 should be greater than 0"))
+				Expect(errors[2].Error()).To(ContainSubstring("device RPS CPUs have an invalid format"))
+				Expect(errors[3].Error()).To(ContainSubstring("device XPS CPUs and offlined cpus overlap: 7"))
+			})
+			It("should accept valid channels, RPS, XPS and RFS settings", func() {
+				rpsCPUs := CPUSet("0-1")
+				xpsCPUs := CPUSet("2-3")
+				profile.Spec.Net.Devices[0].Channels = pointer.Int32Ptr(8)
+				profile.Spec.Net.Devices[0].RFSFlowEntries = pointer.Int32Ptr(4096)
+				profile.Spec.Net.Devices[0].RPSCPUs = &rpsCPUs
+				profile.Spec.Net.Devices[0].XPSCPUs = &xpsCPUs
+				errors := profile.validateNet()
+				Expect(errors).To(BeEmpty())
+			})
+			It("should raise the validation error for too many RFS flow entries", func() {
+				profile.Spec.Net.Devices[0].RFSFlowEntries = pointer.Int32Ptr(maxRFSFlowEntries + 1)
+				errors := profile.validateNet()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring(fmt.Sprintf("device RFS flow entries %d should not be greater than %d", maxRFSFlowEntries+1, maxRFSFlowEntries)))
+			})
+			It("should raise the validation error for RPS, XPS and RFS settings of the same devices", func() {
+				rpsCPUs := CPUSet("0-1")
+				device := profile.Spec.Net.Devices[0]
+				device.RPSCPUs = &rpsCPUs
+				duplicate := device
+				duplicate.DeviceID = nil
+				duplicate.RFSFlowEntries = pointer.Int32Ptr(4096)
+				profile.Spec.Net.Devices = []Device{device, duplicate}
+				Expect(profile.validateNet()).To(BeEmpty())
+
+				duplicate.DeviceID = device.DeviceID
+				profile.Spec.Net.Devices = []Device{device, duplicate}
+				errors := profile.validateNet()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("Duplicate value"))
+			})
+			It("should forbid the channels, RPS, XPS and RFS settings without user level networking", func() {
+				profile.Spec.Net.UserLevelNetworking = pointer.BoolPtr(false)
+				profile.Spec.Net.Devices[0].Channels = pointer.Int32Ptr(8)
+				errors := profile.validateNet()
+				Expect(len(errors)).To(Equal(1))
+				Expect(errors[0].Error()).To(ContainSubstring("can not be used without enabling spec.net.userLevelNetworking"))
+			})
 			It("should raise the validation errors for missing fields", func() {
 				profile.Spec.Net.Devices[0].VendorID = nil
 				profile.Spec.Net.Devices[0].DeviceID = pointer.StringPtr("0x1")
@@ -362,15 +707,251 @@ var _ = Describe("PerformanceProfile", func() {
 					profile.Spec.RealTimeKernel = &RealTimeKernel{
 						Enabled: pointer.BoolPtr(true),
 					}
-					errors := profile.validateWorkloadHints()
+					errors := profile.validateRealTimeKernelWorkloadHint()
 					Expect(errors).NotTo(BeEmpty())
 					Expect(errors[0].Error()).To(ContainSubstring("realtime kernel is enabled, but realtime workload hint is explicitly disable"))
 				})
+
+				It("should not be rejected by the workload hints validation", func() {
+					profile.Spec.WorkloadHints = &WorkloadHints{
+						RealTime: pointer.BoolPtr(false),
+					}
+					profile.Spec.RealTimeKernel = &RealTimeKernel{
+						Enabled: pointer.BoolPtr(true),
+					}
+					Expect(profile.validateWorkloadHints()).To(BeEmpty())
+				})
 			})
+
+			When("per pod power management and high power consumption hints are enabled", func() {
+				It("should raise validation error", func() {
+					profile.Spec.WorkloadHints = &WorkloadHints{
+						RealTime:              pointer.BoolPtr(true),
+						HighPowerConsumption:  pointer.BoolPtr(true),
+						PerPodPowerManagement: pointer.BoolPtr(true),
+					}
+					errors := profile.validateWorkloadHints()
+					Expect(errors).NotTo(BeEmpty())
+					Expect(errors[0].Error()).To(ContainSubstring("the workload hints combination realTime=true, highPowerConsumption=true, perPodPowerManagement=true is not supported"))
+				})
+			})
+
+			When("the workload hints combination is not supported", func() {
+				It("should be rejected by the fields validation", func() {
+					profile.Spec.WorkloadHints = &WorkloadHints{
+						RealTime:              pointer.BoolPtr(false),
+						HighPowerConsumption:  pointer.BoolPtr(true),
+						PerPodPowerManagement: pointer.BoolPtr(true),
+					}
+					errors := profile.validateFields(nil)
+					Expect(errors).NotTo(BeEmpty())
+					Expect(errors.ToAggregate().Error()).To(ContainSubstring("the workload hints combination realTime=false, highPowerConsumption=true, perPodPowerManagement=true is not supported"))
+				})
+			})
+
+			When("the workload hints are resolved", func() {
+				It("should not enable the real time hint when the workload hints are not set", func() {
+					hints, err := ResolveWorkloadHints(nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*hints).To(Equal(WorkloadHintsStatus{
+						RPS:          true,
+						CStates:      CStatesLatencyLimited,
+						CPUFrequency: CPUFrequencyPerformance,
+					}))
+				})
+
+				It("should not enable the real time hint when it is not set", func() {
+					hints, err := ResolveWorkloadHints(&WorkloadHints{HighPowerConsumption: pointer.BoolPtr(false)})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hints.RealTime).To(BeFalse())
+					Expect(hints.Stalld).To(BeFalse())
+					Expect(hints.UnlimitedRTRuntime).To(BeFalse())
+					Expect(hints.RealTimeTuning).To(BeFalse())
+					Expect(hints.RPS).To(BeTrue())
+				})
+
+				It("should not set the RPS masks when the real time hint is explicitly disabled", func() {
+					for _, hints := range []*WorkloadHints{
+						{RealTime: pointer.BoolPtr(false)},
+						{RealTime: pointer.BoolPtr(false), HighPowerConsumption: pointer.BoolPtr(true)},
+						{RealTime: pointer.BoolPtr(false), PerPodPowerManagement: pointer.BoolPtr(true)},
+					} {
+						resolved, err := ResolveWorkloadHints(hints)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resolved.RealTime).To(BeFalse())
+						Expect(resolved.RealTimeTuning).To(BeFalse())
+						Expect(resolved.RPS).To(BeFalse())
+					}
+				})
+
+				It("should tune for the real time workload when the real time hint is enabled", func() {
+					hints, err := ResolveWorkloadHints(&WorkloadHints{RealTime: pointer.BoolPtr(true)})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*hints).To(Equal(WorkloadHintsStatus{
+						RealTime:           true,
+						Stalld:             true,
+						UnlimitedRTRuntime: true,
+						RealTimeTuning:     true,
+						RPS:                true,
+						CStates:            CStatesLatencyLimited,
+						CPUFrequency:       CPUFrequencyPerformance,
+					}))
+				})
+
+				It("should poll the idle CPUs with the real time and high power consumption hints", func() {
+					hints, err := ResolveWorkloadHints(&WorkloadHints{RealTime: pointer.BoolPtr(true), HighPowerConsumption: pointer.BoolPtr(true)})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hints.RealTime).To(BeTrue())
+					Expect(hints.CStates).To(Equal(CStatesPolling))
+					Expect(hints.CPUFrequency).To(Equal(CPUFrequencyPStatesDisabled))
+				})
+
+				It("should leave the power management to the pods with the per pod power management hint", func() {
+					hints, err := ResolveWorkloadHints(&WorkloadHints{
+						RealTime:              pointer.BoolPtr(false),
+						PerPodPowerManagement: pointer.BoolPtr(true),
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hints.Stalld).To(BeFalse())
+					Expect(hints.UnlimitedRTRuntime).To(BeFalse())
+					Expect(hints.RealTimeTuning).To(BeFalse())
+					Expect(hints.CStates).To(Equal(CStatesPerPod))
+					Expect(hints.CPUFrequency).To(Equal(CPUFrequencyPerPod))
+				})
+			})
+
+			When("only the per pod power management hint is enabled", func() {
+				It("should not raise validation error", func() {
+					profile.Spec.WorkloadHints = &WorkloadHints{
+						HighPowerConsumption:  pointer.BoolPtr(false),
+						PerPodPowerManagement: pointer.BoolPtr(true),
+					}
+					errors := profile.validateWorkloadHints()
+					Expect(errors).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("Sysctls validation", func() {
+		It("should accept the sysctls that are not managed by the performance profile", func() {
+			profile.Spec.Sysctls = []Sysctl{
+				{Name: "net.core.somaxconn", Value: "4096"},
+				{Name: "net.ipv4.conf.eno1.rp_filter", Value: "0"},
+			}
+			errors := profile.validateSysctls()
+			Expect(errors).To(BeEmpty())
+		})
+
+		It("should raise the validation errors for invalid sysctls", func() {
+			profile.Spec.Sysctls = []Sysctl{
+				{Name: "net/core/somaxconn", Value: "4096"},
+				{Name: "vm.swappiness", Value: "60"},
+				{Name: "net.core.somaxconn", Value: "4096\nkernel.panic=1"},
+				{Name: "net.core.somaxconn", Value: "4096"},
+			}
+			errors := profile.validateSysctls()
+			Expect(len(errors)).To(Equal(4))
+			Expect(errors[0].Error()).To(ContainSubstring("the sysctl name should consist of lower case alphanumeric characters"))
+			Expect(errors[1].Error()).To(ContainSubstring("the sysctl vm.swappiness is managed by the performance profile and can not be overridden"))
+			Expect(errors[2].Error()).To(ContainSubstring("the sysctl net.core.somaxconn value should be a non empty single line"))
+			Expect(errors[3].Error()).To(ContainSubstring("Duplicate value"))
+		})
+
+		It("should forbid all the sysctls set by the performance profile tuned profile", func() {
+			content, err := assets.Tuned.ReadFile("tuned/openshift-node-performance")
+			Expect(err).ToNot(HaveOccurred())
+
+			// collect the sysctls under the [sysctl] section of the tuned profile template
+			sysctlRegex := regexp.MustCompile(`^([a-z0-9_.]+)=`)
+			var sysctls []string
+			inSysctlSection := false
+			for _, line := range strings.Split(string(content), "\n") {
+				if strings.HasPrefix(line, "[") {
+					inSysctlSection = line == "[sysctl]"
+					continue
+				}
+				if match := sysctlRegex.FindStringSubmatch(line); inSysctlSection && match != nil {
+					sysctls = append(sysctls, match[1])
+				}
+			}
+			Expect(sysctls).ToNot(BeEmpty())
+			Expect(operatorManagedSysctls).To(ContainElements(sysctls))
+		})
+	})
+
+	Describe("Kernel modules validation", func() {
+		It("should accept valid kernel modules", func() {
+			profile.Spec.KernelModules = &KernelModules{
+				Load:      []string{"vfio-pci"},
+				Blacklist: []string{"nouveau"},
+				Options: []KernelModuleOptions{
+					{Name: "ixgbe", Parameters: "allow_unsupported_sfp=1 max_vfs=8"},
+				},
+			}
+			errors := profile.validateKernelModules()
+			Expect(errors).To(BeEmpty())
+		})
+
+		It("should raise the validation errors for invalid kernel modules", func() {
+			profile.Spec.KernelModules = &KernelModules{
+				Load:      []string{"nouveau", "vfio pci"},
+				Blacklist: []string{"nouveau"},
+				Options: []KernelModuleOptions{
+					{Name: "nf_conntrack", Parameters: "hashsize=1024"},
+					{Name: "ixgbe", Parameters: ""},
+				},
+			}
+			errors := profile.validateKernelModules()
+			Expect(len(errors)).To(Equal(4))
+			Expect(errors[0].Error()).To(ContainSubstring("the kernel module nouveau can not be loaded and blacklisted at the same time"))
+			Expect(errors[1].Error()).To(ContainSubstring("the kernel module name should contain only alphanumeric characters"))
+			Expect(errors[2].Error()).To(ContainSubstring("the kernel module nf_conntrack parameter hashsize is managed by the performance profile"))
+			Expect(errors[3].Error()).To(ContainSubstring("the kernel module ixgbe parameters should be a non empty single line"))
+		})
+
+		It("should forbid the blacklisted kernel modules in the loaded and configured modules", func() {
+			profile.Spec.KernelModules = &KernelModules{
+				Load:      []string{"ice", "vfio-pci"},
+				Blacklist: []string{"ice", "i40e"},
+				Options: []KernelModuleOptions{
+					{Name: "i40e", Parameters: "max_vfs=8"},
+					{Name: "ixgbe", Parameters: "allow_unsupported_sfp=1"},
+				},
+			}
+			errors := profile.validateKernelModules()
+			Expect(len(errors)).To(Equal(2))
+			Expect(errors[0].Error()).To(ContainSubstring("the kernel module ice can not be loaded and blacklisted at the same time"))
+			Expect(errors[1].Error()).To(ContainSubstring("the kernel module i40e can not be configured and blacklisted at the same time"))
+		})
+	})
+
+	Describe("Additional tuned profile validation", func() {
+		It("should accept the tuned profiles names", func() {
+			profile.Spec.AdditionalTunedProfile = []string{"openshift-node-custom", "network-throughput"}
+			errors := profile.validateAdditionalTunedProfile()
+			Expect(errors).To(BeEmpty())
+		})
+
+		It("should forbid including the generated tuned profile", func() {
+			profile.Spec.AdditionalTunedProfile = []string{"openshift-node-performance-" + profile.Name, "bad,name"}
+			errors := profile.validateAdditionalTunedProfile()
+			Expect(len(errors)).To(Equal(2))
+			Expect(errors[0].Error()).To(ContainSubstring("can not include itself"))
+			Expect(errors[1].Error()).To(ContainSubstring("the tuned profile name should contain only alphanumeric characters"))
 		})
 	})
 })
 
+func newNodeWithArch(name string, arch string) metav1.PartialObjectMetadata {
+	return metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{corev1.LabelArchStable: arch},
+		},
+	}
+}
+
 func setValidNodeSelector(profile *PerformanceProfile) {
 	selector := make(map[string]string)
 	selector["fooDomain/"+NodeSelectorRole] = ""
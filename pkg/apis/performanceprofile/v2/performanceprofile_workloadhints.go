@@ -0,0 +1,129 @@
+package v2
+
+import (
+	"fmt"
+)
+
+// realTimeHint defines the value of the real time workload hint. An unset hint is distinguished
+// from an explicitly disabled one, the profiles created before the workload hints keep the RPS
+// masks set for them.
+type realTimeHint string
+
+const (
+	realTimeHintUnset    realTimeHint = "unset"
+	realTimeHintDisabled realTimeHint = "false"
+	realTimeHintEnabled  realTimeHint = "true"
+)
+
+// workloadHintsCombination defines a combination of the workload hints values.
+type workloadHintsCombination struct {
+	realTime              realTimeHint
+	highPowerConsumption  bool
+	perPodPowerManagement bool
+}
+
+// workloadHintsEffects defines the tuning enabled by a workload hints combination.
+type workloadHintsEffects struct {
+	stalld             bool
+	unlimitedRTRuntime bool
+	realTimeTuning     bool
+	rps                bool
+	cStates            CStatesMode
+	cpuFrequency       CPUFrequencyMode
+}
+
+// workloadHintsMatrix contains all the supported workload hints combinations and the tuning
+// each of them enables, combinations missing from the matrix are rejected by the webhook.
+var workloadHintsMatrix = map[workloadHintsCombination]workloadHintsEffects{
+	{realTime: realTimeHintUnset, highPowerConsumption: false, perPodPowerManagement: false}: {
+		rps:          true,
+		cStates:      CStatesLatencyLimited,
+		cpuFrequency: CPUFrequencyPerformance,
+	},
+	{realTime: realTimeHintDisabled, highPowerConsumption: false, perPodPowerManagement: false}: {
+		cStates:      CStatesLatencyLimited,
+		cpuFrequency: CPUFrequencyPerformance,
+	},
+	{realTime: realTimeHintEnabled, highPowerConsumption: false, perPodPowerManagement: false}: {
+		stalld:             true,
+		unlimitedRTRuntime: true,
+		realTimeTuning:     true,
+		rps:                true,
+		cStates:            CStatesLatencyLimited,
+		cpuFrequency:       CPUFrequencyPerformance,
+	},
+	{realTime: realTimeHintUnset, highPowerConsumption: true, perPodPowerManagement: false}: {
+		rps:          true,
+		cStates:      CStatesDisabled,
+		cpuFrequency: CPUFrequencyPStatesDisabled,
+	},
+	{realTime: realTimeHintDisabled, highPowerConsumption: true, perPodPowerManagement: false}: {
+		cStates:      CStatesDisabled,
+		cpuFrequency: CPUFrequencyPStatesDisabled,
+	},
+	{realTime: realTimeHintEnabled, highPowerConsumption: true, perPodPowerManagement: false}: {
+		stalld:             true,
+		unlimitedRTRuntime: true,
+		realTimeTuning:     true,
+		rps:                true,
+		cStates:            CStatesPolling,
+		cpuFrequency:       CPUFrequencyPStatesDisabled,
+	},
+	{realTime: realTimeHintUnset, highPowerConsumption: false, perPodPowerManagement: true}: {
+		rps:          true,
+		cStates:      CStatesPerPod,
+		cpuFrequency: CPUFrequencyPerPod,
+	},
+	{realTime: realTimeHintDisabled, highPowerConsumption: false, perPodPowerManagement: true}: {
+		cStates:      CStatesPerPod,
+		cpuFrequency: CPUFrequencyPerPod,
+	},
+	{realTime: realTimeHintEnabled, highPowerConsumption: false, perPodPowerManagement: true}: {
+		stalld:             true,
+		unlimitedRTRuntime: true,
+		realTimeTuning:     true,
+		rps:                true,
+		cStates:            CStatesPerPod,
+		cpuFrequency:       CPUFrequencyPerPod,
+	},
+}
+
+// ResolveWorkloadHints returns the workload hints with the defaults applied and the tuning
+// they enable, it returns an error when the combination of the hints is not supported.
+// The real time tuning is enabled only when the real time hint is explicitly set, the profiles
+// without it were never tuned for the real time workload, but keep their RPS masks.
+func ResolveWorkloadHints(hints *WorkloadHints) (*WorkloadHintsStatus, error) {
+	combination := workloadHintsCombination{realTime: realTimeHintUnset}
+	if hints != nil {
+		if hints.RealTime != nil {
+			combination.realTime = realTimeHintDisabled
+			if *hints.RealTime {
+				combination.realTime = realTimeHintEnabled
+			}
+		}
+		if hints.HighPowerConsumption != nil {
+			combination.highPowerConsumption = *hints.HighPowerConsumption
+		}
+		if hints.PerPodPowerManagement != nil {
+			combination.perPodPowerManagement = *hints.PerPodPowerManagement
+		}
+	}
+
+	effects, ok := workloadHintsMatrix[combination]
+	if !ok {
+		return nil, fmt.Errorf("the workload hints combination realTime=%s, highPowerConsumption=%t, perPodPowerManagement=%t is not supported",
+			combination.realTime, combination.highPowerConsumption, combination.perPodPowerManagement)
+	}
+
+	return &WorkloadHintsStatus{
+		RealTime:              combination.realTime == realTimeHintEnabled,
+		HighPowerConsumption:  combination.highPowerConsumption,
+		PerPodPowerManagement: combination.perPodPowerManagement,
+		Stalld:                effects.stalld,
+		UnlimitedRTRuntime:    effects.unlimitedRTRuntime,
+		RealTimeTuning:        effects.realTimeTuning,
+		RPS:                   effects.rps,
+		CStates:               effects.cStates,
+		CPUFrequency:          effects.cpuFrequency,
+	}, nil
+}
@@ -33,6 +33,16 @@ func (in *CPU) DeepCopyInto(out *CPU) {
 		*out = new(CPUSet)
 		**out = **in
 	}
+	if in.Shared != nil {
+		in, out := &in.Shared, &out.Shared
+		*out = new(CPUSet)
+		**out = **in
+	}
+	if in.Housekeeping != nil {
+		in, out := &in.Housekeeping, &out.Housekeeping
+		*out = new(HousekeepingCPUs)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -46,6 +56,42 @@ func (in *CPU) DeepCopy() *CPU {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CPUPowerPolicy) DeepCopyInto(out *CPUPowerPolicy) {
+	*out = *in
+	if in.Governor != nil {
+		in, out := &in.Governor, &out.Governor
+		*out = new(string)
+		**out = **in
+	}
+	if in.MinFrequency != nil {
+		in, out := &in.MinFrequency, &out.MinFrequency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxFrequency != nil {
+		in, out := &in.MaxFrequency, &out.MaxFrequency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxCState != nil {
+		in, out := &in.MaxCState, &out.MaxCState
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CPUPowerPolicy.
+func (in *CPUPowerPolicy) DeepCopy() *CPUPowerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CPUPowerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Device) DeepCopyInto(out *Device) {
 	*out = *in
@@ -64,6 +110,41 @@ func (in *Device) DeepCopyInto(out *Device) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.PCIAddress != nil {
+		in, out := &in.PCIAddress, &out.PCIAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.Driver != nil {
+		in, out := &in.Driver, &out.Driver
+		*out = new(string)
+		**out = **in
+	}
+	if in.MACAddress != nil {
+		in, out := &in.MACAddress, &out.MACAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RPSCPUs != nil {
+		in, out := &in.RPSCPUs, &out.RPSCPUs
+		*out = new(CPUSet)
+		**out = **in
+	}
+	if in.XPSCPUs != nil {
+		in, out := &in.XPSCPUs, &out.XPSCPUs
+		*out = new(CPUSet)
+		**out = **in
+	}
+	if in.RFSFlowEntries != nil {
+		in, out := &in.RFSFlowEntries, &out.RFSFlowEntries
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -77,6 +158,37 @@ func (in *Device) DeepCopy() *Device {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HousekeepingCPUs) DeepCopyInto(out *HousekeepingCPUs) {
+	*out = *in
+	if in.IRQ != nil {
+		in, out := &in.IRQ, &out.IRQ
+		*out = new(CPUSet)
+		**out = **in
+	}
+	if in.KernelThreads != nil {
+		in, out := &in.KernelThreads, &out.KernelThreads
+		*out = new(CPUSet)
+		**out = **in
+	}
+	if in.RCU != nil {
+		in, out := &in.RCU, &out.RCU
+		*out = new(CPUSet)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HousekeepingCPUs.
+func (in *HousekeepingCPUs) DeepCopy() *HousekeepingCPUs {
+	if in == nil {
+		return nil
+	}
+	out := new(HousekeepingCPUs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HugePage) DeepCopyInto(out *HugePage) {
 	*out = *in
@@ -113,6 +225,16 @@ func (in *HugePages) DeepCopyInto(out *HugePages) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Architecture != nil {
+		in, out := &in.Architecture, &out.Architecture
+		*out = new(string)
+		**out = **in
+	}
+	if in.KernelPageSize != nil {
+		in, out := &in.KernelPageSize, &out.KernelPageSize
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -126,6 +248,91 @@ func (in *HugePages) DeepCopy() *HugePages {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelModuleOptions) DeepCopyInto(out *KernelModuleOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelModuleOptions.
+func (in *KernelModuleOptions) DeepCopy() *KernelModuleOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelModuleOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelModules) DeepCopyInto(out *KernelModules) {
+	*out = *in
+	if in.Load != nil {
+		in, out := &in.Load, &out.Load
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Blacklist != nil {
+		in, out := &in.Blacklist, &out.Blacklist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]KernelModuleOptions, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelModules.
+func (in *KernelModules) DeepCopy() *KernelModules {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelModules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Memory) DeepCopyInto(out *Memory) {
+	*out = *in
+	if in.KubeReserved != nil {
+		in, out := &in.KubeReserved, &out.KubeReserved
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.SystemReserved != nil {
+		in, out := &in.SystemReserved, &out.SystemReserved
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.EvictionHard != nil {
+		in, out := &in.EvictionHard, &out.EvictionHard
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ReservedMemory != nil {
+		in, out := &in.ReservedMemory, &out.ReservedMemory
+		*out = make([]NUMAReservedMemory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Memory.
+func (in *Memory) DeepCopy() *Memory {
+	if in == nil {
+		return nil
+	}
+	out := new(Memory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NUMA) DeepCopyInto(out *NUMA) {
 	*out = *in
@@ -147,6 +354,23 @@ func (in *NUMA) DeepCopy() *NUMA {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NUMAReservedMemory) DeepCopyInto(out *NUMAReservedMemory) {
+	*out = *in
+	out.Limit = in.Limit.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NUMAReservedMemory.
+func (in *NUMAReservedMemory) DeepCopy() *NUMAReservedMemory {
+	if in == nil {
+		return nil
+	}
+	out := new(NUMAReservedMemory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Net) DeepCopyInto(out *Net) {
 	*out = *in
@@ -300,6 +524,31 @@ func (in *PerformanceProfileSpec) DeepCopyInto(out *PerformanceProfileSpec) {
 		*out = new(WorkloadHints)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(Memory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Power != nil {
+		in, out := &in.Power, &out.Power
+		*out = new(PowerPolicies)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make([]Sysctl, len(*in))
+		copy(*out, *in)
+	}
+	if in.KernelModules != nil {
+		in, out := &in.KernelModules, &out.KernelModules
+		*out = new(KernelModules)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdditionalTunedProfile != nil {
+		in, out := &in.AdditionalTunedProfile, &out.AdditionalTunedProfile
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -333,6 +582,11 @@ func (in *PerformanceProfileStatus) DeepCopyInto(out *PerformanceProfileStatus)
 		*out = new(string)
 		**out = **in
 	}
+	if in.WorkloadHints != nil {
+		in, out := &in.WorkloadHints, &out.WorkloadHints
+		*out = new(WorkloadHintsStatus)
+		**out = **in
+	}
 	return
 }
 
@@ -346,6 +600,37 @@ func (in *PerformanceProfileStatus) DeepCopy() *PerformanceProfileStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerPolicies) DeepCopyInto(out *PowerPolicies) {
+	*out = *in
+	if in.Reserved != nil {
+		in, out := &in.Reserved, &out.Reserved
+		*out = new(CPUPowerPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Isolated != nil {
+		in, out := &in.Isolated, &out.Isolated
+		*out = new(CPUPowerPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Shared != nil {
+		in, out := &in.Shared, &out.Shared
+		*out = new(CPUPowerPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerPolicies.
+func (in *PowerPolicies) DeepCopy() *PowerPolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerPolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RealTimeKernel) DeepCopyInto(out *RealTimeKernel) {
 	*out = *in
@@ -367,6 +652,22 @@ func (in *RealTimeKernel) DeepCopy() *RealTimeKernel {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Sysctl) DeepCopyInto(out *Sysctl) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sysctl.
+func (in *Sysctl) DeepCopy() *Sysctl {
+	if in == nil {
+		return nil
+	}
+	out := new(Sysctl)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadHints) DeepCopyInto(out *WorkloadHints) {
 	*out = *in
@@ -380,6 +681,11 @@ func (in *WorkloadHints) DeepCopyInto(out *WorkloadHints) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.PerPodPowerManagement != nil {
+		in, out := &in.PerPodPowerManagement, &out.PerPodPowerManagement
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -392,3 +698,19 @@ func (in *WorkloadHints) DeepCopy() *WorkloadHints {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadHintsStatus) DeepCopyInto(out *WorkloadHintsStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadHintsStatus.
+func (in *WorkloadHintsStatus) DeepCopy() *WorkloadHintsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadHintsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
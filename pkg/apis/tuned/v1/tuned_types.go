@@ -22,6 +22,35 @@ const (
 	// Annotation on Profiles to denote the operand version responsible for calculating and reporting
 	// the Profile status.
 	GeneratedByOperandVersionAnnotationKey string = "tuned.openshift.io/generated-by-operand-version"
+
+	// Annotation on Profiles to denote the namespace/name of the Pod whose label or annotation
+	// caused the selection of the Profile's TuneD profile.
+	MatchedPodAnnotationKey string = "tuned.openshift.io/matched-pod"
+
+	// Annotation on the rendered Tuned and Profiles to denote the revision of the rendered content
+	// (the name of its ControllerRevision) the operator calculated them from.
+	RenderedRevisionAnnotationKey string = "tuned.openshift.io/rendered-revision"
+
+	// Annotation on Profiles to denote the revision of the rendered content the operand last
+	// applied the Profile with successfully, i.e. without TuneD reporting errors.
+	AppliedRevisionAnnotationKey string = "tuned.openshift.io/applied-revision"
+
+	// Annotation on the default Tuned to request a rollback of the rendered content to a previous
+	// revision, identified by its ControllerRevision name or revision number.  The rollback lasts
+	// until the annotation is removed.
+	RollbackToRevisionAnnotationKey string = "tuned.openshift.io/rollback-to-revision"
+
+	// Annotation on Namespaces holding a label selector of Nodes the tenant Tuned CRs in the
+	// Namespace are entitled to tune.  Tuned CRs in Namespaces without this annotation are rejected.
+	TenantNodeSelectorAnnotationKey string = "tuned.openshift.io/tenant-node-selector"
+
+	// Annotation on Namespaces holding a comma-separated list of TuneD plugins the tenant Tuned CRs
+	// in the Namespace are allowed to use.
+	TenantAllowedPluginsAnnotationKey string = "tuned.openshift.io/tenant-allowed-plugins"
+
+	// Annotation on Namespaces holding a comma-separated list of sysctls the tenant Tuned CRs in the
+	// Namespace are allowed to set.  A trailing '*' matches any sysctl with the given prefix.
+	TenantAllowedSysctlsAnnotationKey string = "tuned.openshift.io/tenant-allowed-sysctls"
 )
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -57,6 +86,23 @@ type TunedSpec struct {
 	// Selection logic for all Tuned profiles.
 	// +optional
 	Recommend []TunedRecommend `json:"recommend"`
+	// Optional policy to automatically revert changes of this Tuned that degrade too many Nodes.
+	// +optional
+	AutoRevert *TunedAutoRevert `json:"autoRevert,omitempty"`
+}
+
+// TunedAutoRevert is a policy to automatically revert the profiles and recommend rules of a
+// Tuned to their previous revision.
+type TunedAutoRevert struct {
+	// Maximum percentage of Nodes selecting any of the Tuned's profiles that may report the
+	// Degraded Profile condition within windowSeconds after a change of the Tuned.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxDegradedPercent int32 `json:"maxDegradedPercent"`
+	// Number of seconds after a change of the Tuned within which Degraded Profiles cause the
+	// change to be reverted.
+	// +kubebuilder:validation:Minimum=1
+	WindowSeconds int32 `json:"windowSeconds"`
 }
 
 // A Tuned profile.
@@ -65,6 +111,26 @@ type TunedProfile struct {
 	Name *string `json:"name"`
 	// Specification of the Tuned profile to be consumed by the Tuned daemon.
 	Data *string `json:"data"`
+	// Optional requirements of the Tuned profile on the Nodes it is applied to.
+	// +optional
+	Requirements *TunedProfileRequirements `json:"requirements,omitempty"`
+}
+
+// TunedProfileRequirements are the dependencies of a Tuned profile on the Nodes
+// it is applied to.  The Tuned daemon checks them before applying the profile and
+// reports the result in the RequirementsMet Profile condition.
+type TunedProfileRequirements struct {
+	// Kernel modules which need to be available (loaded, built-in or loadable) on the Node.
+	// +optional
+	Modules []string `json:"modules,omitempty"`
+	// systemd services which need to be installed on the Node.
+	// +optional
+	Services []string `json:"services,omitempty"`
+	// Kernel boot parameters the Node needs to be booted with.  They are reported along
+	// with the kernel parameters calculated by the Tuned daemon and therefore added to the
+	// MachineConfig created for profiles selected by rules with machineConfigLabels.
+	// +optional
+	BootParameters []string `json:"bootParameters,omitempty"`
 }
 
 // Selection logic for a single Tuned profile.
@@ -84,6 +150,11 @@ type TunedRecommend struct {
 	// profile 'Profile' on all nodes that match the MachineConfigPools' nodeSelectors.
 	MachineConfigLabels map[string]string `json:"machineConfigLabels,omitempty"`
 
+	// Time windows connected by logical OR operator during which the rule applies. If omitted,
+	// the rule applies at all times. Rules with both MachineConfigLabels and a schedule are ignored.
+	// +optional
+	Schedule []TunedScheduleWindow `json:"schedule,omitempty"`
+
 	// Optional operand configuration.
 	// +optional
 	Operand OperandConfig `json:"operand,omitempty"`
@@ -91,18 +162,40 @@ type TunedRecommend struct {
 
 // Rules governing application of a Tuned profile.
 type TunedMatch struct {
-	// Node or Pod label name.
+	// Node or Pod label name, or Pod annotation name.
 	Label *string `json:"label"`
-	// Node or Pod label value. If omitted, the presence of label name is enough to match.
+	// Node or Pod label value, or Pod annotation value. If omitted, the presence of label/annotation
+	// name is enough to match.
 	Value *string `json:"value,omitempty"`
-	// Match type: [node/pod]. If omitted, "node" is assumed.
-	// +kubebuilder:validation:Enum={"node","pod"}
+	// Match type: [node/pod/podAnnotation]. If omitted, "node" is assumed.
+	// +kubebuilder:validation:Enum={"node","pod","podAnnotation"}
 	Type *string `json:"type,omitempty"`
 
 	// Additional rules governing application of the tuned profile connected by logical AND operator.
 	Match []TunedMatch `json:"match,omitempty"`
 }
 
+// A time window during which a Tuned profile selection rule applies.
+type TunedScheduleWindow struct {
+	// Days of the week the window starts on. If omitted, the window starts every day.
+	// +optional
+	Days []TunedScheduleDay `json:"days,omitempty"`
+	// Start of the window in the 24-hour HH:MM format.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End of the window in the 24-hour HH:MM format. The window ends on the following day
+	// if the end is not after the start.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// IANA time zone name of the start and end times. If omitted, "UTC" is assumed.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// A day of the week.
+// +kubebuilder:validation:Enum={"Mon","Tue","Wed","Thu","Fri","Sat","Sun"}
+type TunedScheduleDay string
+
 type OperandConfig struct {
 	// turn debugging on/off for the TuneD daemon: true/false (default is false)
 	// +optional
@@ -121,8 +214,66 @@ type TuneDConfig struct {
 
 // TunedStatus is the status for a Tuned resource.
 type TunedStatus struct {
+	// conditions represents the state of the Tuned resource; currently only reported
+	// for tenant Tuned resources outside of the operator's namespace.  The Accepted
+	// condition is False if the whole resource was rejected and True with reason
+	// PartiallyAccepted if only the fragments the namespace is not entitled to were
+	// dropped.
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +optional
+	Conditions []TunedStatusCondition `json:"conditions,omitempty"  patchStrategy:"merge" patchMergeKey:"type"`
+
+	// name of the revision (ControllerRevision) of the Tuned's profiles and recommend rules
+	// reverted by the autoRevert policy; the previous revision is used while the Tuned's content
+	// matches this revision
+	// +optional
+	RevertedRevision string `json:"revertedRevision,omitempty"`
+}
+
+// TunedStatusCondition represents a partial state of the Tuned resource.
+// +k8s:deepcopy-gen=true
+type TunedStatusCondition struct {
+	// type specifies the aspect reported by this condition.
+	// +kubebuilder:validation:Required
+	// +required
+	Type TunedConditionType `json:"type"`
+
+	// status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Required
+	// +required
+	Status corev1.ConditionStatus `json:"status"`
+
+	// lastTransitionTime is the time of the last update to the current status property.
+	// +kubebuilder:validation:Required
+	// +required
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// reason is the CamelCase reason for the condition's current status.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// message provides additional information about the current condition.
+	// This is only to be consumed by humans.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
+// TunedConditionType is an aspect of Tuned resource state.
+type TunedConditionType string
+
+const (
+	// TunedAccepted indicates whether the profiles and recommend rules of a tenant
+	// Tuned resource were accepted by the operator and merged into Node profiles.
+	// Fragments of an accepted tenant Tuned the Namespace is not entitled to are
+	// dropped and listed in the condition message with reason PartiallyAccepted.
+	TunedAccepted TunedConditionType = "Accepted"
+
+	// TunedReverted indicates whether the last change of the Tuned resource was reverted
+	// by its autoRevert policy.
+	TunedReverted TunedConditionType = "Reverted"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // TunedList is a list of Tuned resources.
@@ -160,6 +311,10 @@ type ProfileConfig struct {
 	// Name of the cloud provider as taken from the Node providerID: <ProviderName>://<ProviderSpecificNodeID>
 	// +optional
 	ProviderName string `json:"providerName,omitempty"`
+	// option to stop TuneD daemon, roll back the node-level tuning and remove the TuneD profiles
+	// written by the operand; set by the operator when it is being removed
+	// +optional
+	Rollback bool `json:"rollback,omitempty"`
 }
 
 // ProfileStatus is the status for a Profile resource; the status is for internal use only
@@ -177,6 +332,63 @@ type ProfileStatus struct {
 	// +patchStrategy=merge
 	// +optional
 	Conditions []ProfileStatusCondition `json:"conditions,omitempty"  patchStrategy:"merge" patchMergeKey:"type"`
+
+	// the active schedule window of the rule that selected the TuneD profile
+	// +optional
+	ActiveWindow string `json:"activeWindow,omitempty"`
+
+	// the next time the TuneD profile selection can change due to a schedule window start or end
+	// +optional
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+
+	// capabilities of the Node discovered by the Tuned daemon at startup
+	// +optional
+	Capabilities *NodeCapabilities `json:"capabilities,omitempty"`
+}
+
+// NodeCapabilities are the capabilities of a Node discovered by the Tuned daemon.
+type NodeCapabilities struct {
+	// virtualization technologies detected by virt-what; empty on bare metal
+	// +optional
+	Virtualization []string `json:"virtualization,omitempty"`
+
+	// TuneD plug-ins available to the Tuned daemon
+	// +optional
+	TunedPlugins []string `json:"tunedPlugins,omitempty"`
+
+	// CPU frequency scaling driver
+	// +optional
+	CPUFreqDriver string `json:"cpufreqDriver,omitempty"`
+
+	// CPU idle driver
+	// +optional
+	CPUIdleDriver string `json:"cpuidleDriver,omitempty"`
+
+	// NUMA nodes of the Node
+	// +optional
+	NUMANodes []NUMANodeCapabilities `json:"numaNodes,omitempty"`
+
+	// network interfaces backed by a device and their drivers
+	// +optional
+	NetworkInterfaces []NetworkInterfaceCapabilities `json:"networkInterfaces,omitempty"`
+}
+
+// NUMANodeCapabilities describes a NUMA node of a Node.
+type NUMANodeCapabilities struct {
+	// NUMA node ID
+	ID int `json:"id"`
+
+	// CPUs of the NUMA node in the Linux CPU list format
+	CPUs string `json:"cpus"`
+}
+
+// NetworkInterfaceCapabilities describes a network interface of a Node.
+type NetworkInterfaceCapabilities struct {
+	// interface name
+	Name string `json:"name"`
+
+	// kernel driver of the interface's device
+	Driver string `json:"driver"`
 }
 
 // ProfileStatusCondition represents a partial state of the per-node Profile application.
@@ -219,6 +431,14 @@ const (
 	// application.  To conclude the profile application was successful,
 	// both TunedProfileApplied and TunedDegraded need to be queried.
 	TunedDegraded ProfileConditionType = "Degraded"
+
+	// TunedRolledBack indicates the Tuned daemon was stopped, the node-level
+	// tuning rolled back and the TuneD profiles removed on the operator's request.
+	TunedRolledBack ProfileConditionType = "RolledBack"
+
+	// TunedRequirementsMet indicates whether the Node meets the requirements of
+	// the Tuned profile.  Only reported for profiles with requirements.
+	TunedRequirementsMet ProfileConditionType = "RequirementsMet"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -26,6 +26,74 @@ func (in *OperandConfig) DeepCopy() *OperandConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NUMANodeCapabilities) DeepCopyInto(out *NUMANodeCapabilities) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NUMANodeCapabilities.
+func (in *NUMANodeCapabilities) DeepCopy() *NUMANodeCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(NUMANodeCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterfaceCapabilities) DeepCopyInto(out *NetworkInterfaceCapabilities) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterfaceCapabilities.
+func (in *NetworkInterfaceCapabilities) DeepCopy() *NetworkInterfaceCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterfaceCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCapabilities) DeepCopyInto(out *NodeCapabilities) {
+	*out = *in
+	if in.Virtualization != nil {
+		in, out := &in.Virtualization, &out.Virtualization
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TunedPlugins != nil {
+		in, out := &in.TunedPlugins, &out.TunedPlugins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NUMANodes != nil {
+		in, out := &in.NUMANodes, &out.NUMANodes
+		*out = make([]NUMANodeCapabilities, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkInterfaceCapabilities, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCapabilities.
+func (in *NodeCapabilities) DeepCopy() *NodeCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Profile) DeepCopyInto(out *Profile) {
 	*out = *in
@@ -131,6 +199,15 @@ func (in *ProfileStatus) DeepCopyInto(out *ProfileStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NextTransition != nil {
+		in, out := &in.NextTransition, &out.NextTransition
+		*out = (*in).DeepCopy()
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = new(NodeCapabilities)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -188,7 +265,7 @@ func (in *Tuned) DeepCopyInto(out *Tuned) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -210,6 +287,22 @@ func (in *Tuned) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunedAutoRevert) DeepCopyInto(out *TunedAutoRevert) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunedAutoRevert.
+func (in *TunedAutoRevert) DeepCopy() *TunedAutoRevert {
+	if in == nil {
+		return nil
+	}
+	out := new(TunedAutoRevert)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunedList) DeepCopyInto(out *TunedList) {
 	*out = *in
@@ -294,6 +387,11 @@ func (in *TunedProfile) DeepCopyInto(out *TunedProfile) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Requirements != nil {
+		in, out := &in.Requirements, &out.Requirements
+		*out = new(TunedProfileRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -307,6 +405,37 @@ func (in *TunedProfile) DeepCopy() *TunedProfile {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunedProfileRequirements) DeepCopyInto(out *TunedProfileRequirements) {
+	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BootParameters != nil {
+		in, out := &in.BootParameters, &out.BootParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunedProfileRequirements.
+func (in *TunedProfileRequirements) DeepCopy() *TunedProfileRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(TunedProfileRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunedRecommend) DeepCopyInto(out *TunedRecommend) {
 	*out = *in
@@ -334,6 +463,13 @@ func (in *TunedRecommend) DeepCopyInto(out *TunedRecommend) {
 			(*out)[key] = val
 		}
 	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = make([]TunedScheduleWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Operand.DeepCopyInto(&out.Operand)
 	return
 }
@@ -348,6 +484,27 @@ func (in *TunedRecommend) DeepCopy() *TunedRecommend {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunedScheduleWindow) DeepCopyInto(out *TunedScheduleWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]TunedScheduleDay, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunedScheduleWindow.
+func (in *TunedScheduleWindow) DeepCopy() *TunedScheduleWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(TunedScheduleWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunedSpec) DeepCopyInto(out *TunedSpec) {
 	*out = *in
@@ -365,6 +522,11 @@ func (in *TunedSpec) DeepCopyInto(out *TunedSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AutoRevert != nil {
+		in, out := &in.AutoRevert, &out.AutoRevert
+		*out = new(TunedAutoRevert)
+		**out = **in
+	}
 	return
 }
 
@@ -381,6 +543,13 @@ func (in *TunedSpec) DeepCopy() *TunedSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunedStatus) DeepCopyInto(out *TunedStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]TunedStatusCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -393,3 +562,20 @@ func (in *TunedStatus) DeepCopy() *TunedStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunedStatusCondition) DeepCopyInto(out *TunedStatusCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunedStatusCondition.
+func (in *TunedStatusCondition) DeepCopy() *TunedStatusCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(TunedStatusCondition)
+	in.DeepCopyInto(out)
+	return out
+}
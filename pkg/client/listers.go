@@ -3,6 +3,7 @@ package client
 import (
 	kappslisters "k8s.io/client-go/listers/apps/v1"
 	kcorelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 
 	configlisters "github.com/openshift/client-go/config/listers/config/v1"
 
@@ -11,13 +12,20 @@ import (
 )
 
 type Listers struct {
-	DaemonSets         kappslisters.DaemonSetNamespaceLister
-	ConfigMaps         kcorelisters.ConfigMapNamespaceLister
-	Pods               kcorelisters.PodLister
-	Nodes              kcorelisters.NodeLister
-	ClusterOperators   configlisters.ClusterOperatorLister
-	TunedResources     ntolisters.TunedNamespaceLister
-	TunedProfiles      ntolisters.ProfileNamespaceLister
-	MachineConfigs     mcfglisters.MachineConfigLister
-	MachineConfigPools mcfglisters.MachineConfigPoolLister
+	DaemonSets          kappslisters.DaemonSetNamespaceLister
+	ControllerRevisions kappslisters.ControllerRevisionNamespaceLister
+	ConfigMaps          kcorelisters.ConfigMapNamespaceLister
+	MachineConfigMaps   kcorelisters.ConfigMapNamespaceLister
+	StatusConfigMaps    kcorelisters.ConfigMapNamespaceLister
+	Pods                kcorelisters.PodLister
+	PodIndexer          cache.Indexer
+	Nodes               kcorelisters.NodeLister
+	Namespaces          kcorelisters.NamespaceLister
+	ClusterOperators    configlisters.ClusterOperatorLister
+	TunedResources      ntolisters.TunedNamespaceLister
+	TenantTuneds        ntolisters.TunedLister
+	TunedProfiles       ntolisters.ProfileNamespaceLister
+	MachineConfigs      mcfglisters.MachineConfigLister
+	MachineConfigPools  mcfglisters.MachineConfigPoolLister
+	KubeletConfigs      mcfglisters.KubeletConfigLister
 }
@@ -4,10 +4,11 @@ import "github.com/prometheus/client_golang/prometheus"
 
 // When adding metric names, see https://prometheus.io/docs/practices/naming/#metric-names
 const (
-	podLabelsUsedQuery     = "nto_pod_labels_used_info"
-	profileCalculatedQuery = "nto_profile_calculated_total"
-	buildInfoQuery         = "nto_build_info"
-	degradedInfoQuery      = "nto_degraded_info"
+	podLabelsUsedQuery      = "nto_pod_labels_used_info"
+	podAnnotationsUsedQuery = "nto_pod_annotations_used_info"
+	profileCalculatedQuery  = "nto_profile_calculated_total"
+	buildInfoQuery          = "nto_build_info"
+	degradedInfoQuery       = "nto_degraded_info"
 
 	// MetricsPort is the IP port supplied to the HTTP server used for Prometheus,
 	// and matches what is specified in the corresponding Service and ServiceMonitor.
@@ -22,6 +23,12 @@ var (
 			Help: "Is the Pod label functionality turned on (1) or off (0)?",
 		},
 	)
+	podAnnotationsUsed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: podAnnotationsUsedQuery,
+			Help: "Is the Pod annotation functionality turned on (1) or off (0)?",
+		},
+	)
 	profileCalculated = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: profileCalculatedQuery,
@@ -47,6 +54,7 @@ var (
 func init() {
 	registry.MustRegister(
 		podLabelsUsed,
+		podAnnotationsUsed,
 		profileCalculated,
 		buildInfo,
 		degradedState,
@@ -63,6 +71,16 @@ func PodLabelsUsed(enable bool) {
 	podLabelsUsed.Set(0)
 }
 
+// PodAnnotationsUsed indicates whether the Pod annotation matching functionality
+// is turned on.
+func PodAnnotationsUsed(enable bool) {
+	if enable {
+		podAnnotationsUsed.Set(1)
+		return
+	}
+	podAnnotationsUsed.Set(0)
+}
+
 // ProfileCalculated keeps track of the number of times a given Tuned profile
 // resource was calculated for node 'nodeName'.
 func ProfileCalculated(nodeName, profileName string) {
@@ -0,0 +1,253 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+)
+
+// tunedContent returns the content of Tuned 'tuned' the autoRevert policy reverts.
+func tunedContent(tuned *tunedv1.Tuned) tunedv1.TunedSpec {
+	return tunedv1.TunedSpec{
+		Profile:   tuned.Spec.Profile,
+		Recommend: tuned.Spec.Recommend,
+	}
+}
+
+// tunedAutoRevert returns true if Tuned 'tuned' has an autoRevert policy.
+func tunedAutoRevert(tuned *tunedv1.Tuned) bool {
+	return tuned.Spec.AutoRevert != nil && tuned.Name != tunedv1.TunedRenderedResourceName
+}
+
+// revisionPrevious returns the revision of Tuned 'tuned' preceding revision 'name'.
+func (c *Controller) revisionPrevious(tuned *tunedv1.Tuned, name string) (*appsv1.ControllerRevision, error) {
+	revisions, err := c.revisionsList(revisionHistoryTuned, tuned)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Name == name {
+			if i == 0 {
+				break
+			}
+			return revisions[i-1], nil
+		}
+	}
+	return nil, fmt.Errorf("no revision of Tuned %s precedes revision %s", tuned.Name, name)
+}
+
+// syncTunedsReverted records the revisions of Tuned CRs 'tunedList' with an
+// autoRevert policy.
+// Returns a map of Tuned names to Tuned CRs with their content reverted to the
+// previous revision and an error if any.
+func (c *Controller) syncTunedsReverted(tunedList []*tunedv1.Tuned) (map[string]*tunedv1.Tuned, error) {
+	reverted := map[string]*tunedv1.Tuned{}
+
+	for _, tuned := range tunedList {
+		if !tunedAutoRevert(tuned) {
+			continue
+		}
+		name, err := c.syncRevision(tuned, revisionHistoryTuned, tunedContent(tuned))
+		if err != nil {
+			return nil, err
+		}
+		if tuned.Status.RevertedRevision != name {
+			continue
+		}
+		prev, err := c.revisionPrevious(tuned, name)
+		if err != nil {
+			klog.Errorf("unable to revert Tuned %s: %v", tuned.Name, err)
+			continue
+		}
+		prevTuned, err := revisionTuned(prev)
+		if err != nil {
+			return nil, err
+		}
+		tuned = tuned.DeepCopy() // never update the objects from cache
+		tuned.Spec.Profile = prevTuned.Spec.Profile
+		tuned.Spec.Recommend = prevTuned.Spec.Recommend
+		reverted[tuned.Name] = tuned
+		klog.V(2).Infof("syncTunedsReverted(): using revision %s of Tuned %s", prev.Name, tuned.Name)
+	}
+
+	return reverted, nil
+}
+
+// tunedsEffective returns 'tunedList' with the Tuned CRs reverted by their
+// autoRevert policy replaced by their previous revision.
+func (pc *ProfileCalculator) tunedsEffective(tunedList []*tunedv1.Tuned) []*tunedv1.Tuned {
+	if len(pc.reverted) == 0 {
+		return tunedList
+	}
+
+	ret := make([]*tunedv1.Tuned, 0, len(tunedList))
+	for _, tuned := range tunedList {
+		if reverted, ok := pc.reverted[tuned.Name]; ok {
+			tuned = reverted
+		}
+		ret = append(ret, tuned)
+	}
+	return ret
+}
+
+// tunedProfilesDegraded counts Profiles from 'profileList' that select any of
+// the TuneD profiles of Tuned 'tuned' and how many of those turned Degraded
+// since 'since'.
+func tunedProfilesDegraded(tuned *tunedv1.Tuned, profileList []*tunedv1.Profile, since time.Time) (int, int) {
+	var targeted, degraded int
+
+	names := map[string]bool{}
+	for _, profile := range tuned.Spec.Profile {
+		if profile.Name != nil {
+			names[*profile.Name] = true
+		}
+	}
+
+	for _, profile := range profileList {
+		selected := false
+		for _, name := range strings.Fields(profile.Spec.Config.TunedProfile) {
+			if names[name] {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			continue
+		}
+		targeted++
+		for _, sc := range profile.Status.Conditions {
+			if sc.Type == tunedv1.TunedDegraded && sc.Status == corev1.ConditionTrue && !sc.LastTransitionTime.Time.Before(since) {
+				degraded++
+				break
+			}
+		}
+	}
+
+	return targeted, degraded
+}
+
+// autoRevertWindowExpired returns true if more than the window of autoRevert
+// policy 'policy' elapsed between the change at 'since' and 'now'.  Nodes
+// turning Degraded after the window expired no longer revert the change.
+func autoRevertWindowExpired(policy *tunedv1.TunedAutoRevert, since time.Time, now time.Time) bool {
+	return now.Sub(since) > time.Duration(policy.WindowSeconds)*time.Second
+}
+
+// autoRevertTriggered returns true if 'degraded' out of 'targeted' Nodes exceed
+// the maximum percentage of Degraded Nodes of autoRevert policy 'policy'.
+func autoRevertTriggered(policy *tunedv1.TunedAutoRevert, targeted int, degraded int) bool {
+	return targeted > 0 && degraded*100 > targeted*int(policy.MaxDegradedPercent)
+}
+
+// setTunedStatusCondition sets condition 'condition' in 'conditions' and keeps
+// the last transition time of the condition if its status did not change.
+func setTunedStatusCondition(conditions []tunedv1.TunedStatusCondition, condition tunedv1.TunedStatusCondition) []tunedv1.TunedStatusCondition {
+	condition.LastTransitionTime = metav1.Now()
+	ret := []tunedv1.TunedStatusCondition{}
+	for _, c := range conditions {
+		if c.Type != condition.Type {
+			ret = append(ret, c)
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+	}
+	return append(ret, condition)
+}
+
+// syncAutoRevert reverts the last change of Tuned CRs with an autoRevert policy
+// if more than the policy's maximum percentage of Nodes selecting the Tuned's
+// profiles turned Degraded within the policy's window after the change.
+func (c *Controller) syncAutoRevert() error {
+	tunedList, err := c.listers.TunedResources.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list Tuned: %v", err)
+	}
+
+	var profileList []*tunedv1.Profile
+	for _, tuned := range tunedList {
+		if !tunedAutoRevert(tuned) {
+			continue
+		}
+		data, err := json.Marshal(tunedContent(tuned))
+		if err != nil {
+			return fmt.Errorf("failed to encode Tuned content: %v", err)
+		}
+		name := revisionName(revisionHistoryTuned, tuned, data)
+		cr, err := c.listers.ControllerRevisions.Get(name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// The change was not recorded yet.
+				continue
+			}
+			return fmt.Errorf("failed to get ControllerRevision %s: %v", name, err)
+		}
+
+		condition := tunedv1.TunedStatusCondition{Type: tunedv1.TunedReverted}
+		switch {
+		case tuned.Status.RevertedRevision == name:
+			// Already reverted.
+			continue
+
+		case tuned.Status.RevertedRevision != "":
+			// The Tuned changed since it was reverted.
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = "Changed"
+			condition.Message = fmt.Sprintf("Tuned changed since revision %s was reverted", tuned.Status.RevertedRevision)
+
+		default:
+			since := revisionTimestamp(cr)
+			window := time.Duration(tuned.Spec.AutoRevert.WindowSeconds) * time.Second
+			if autoRevertWindowExpired(tuned.Spec.AutoRevert, since, time.Now()) {
+				continue
+			}
+			if profileList == nil {
+				profileList, err = c.listers.TunedProfiles.List(labels.Everything())
+				if err != nil {
+					return fmt.Errorf("failed to list Tuned Profiles: %v", err)
+				}
+			}
+			targeted, degraded := tunedProfilesDegraded(tuned, profileList, since)
+			if !autoRevertTriggered(tuned.Spec.AutoRevert, targeted, degraded) {
+				continue
+			}
+			prev, err := c.revisionPrevious(tuned, name)
+			if err != nil {
+				klog.Errorf("%d/%d Nodes selecting profiles of Tuned %s are Degraded, unable to revert: %v", degraded, targeted, tuned.Name, err)
+				continue
+			}
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = "ProfilesDegraded"
+			condition.Message = fmt.Sprintf("%d/%d Nodes selecting the Tuned's profiles became Degraded within %v of the change; reverted revision %s to revision %s",
+				degraded, targeted, window, name, prev.Name)
+		}
+
+		tuned = tuned.DeepCopy() // never update the objects from cache
+		if condition.Status == corev1.ConditionTrue {
+			tuned.Status.RevertedRevision = cr.Name
+		} else {
+			tuned.Status.RevertedRevision = ""
+		}
+		tuned.Status.Conditions = setTunedStatusCondition(tuned.Status.Conditions, condition)
+		_, err = c.clients.Tuned.TunedV1().Tuneds(ntoconfig.WatchNamespace()).Update(context.TODO(), tuned, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update Tuned %s: %v", tuned.Name, err)
+		}
+		klog.Infof("Tuned %s: %s", tuned.Name, condition.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package operator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kappslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+)
+
+func TestAutoRevertWindowExpired(t *testing.T) {
+	policy := &tunedv1.TunedAutoRevert{MaxDegradedPercent: 10, WindowSeconds: 600}
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		now      time.Time
+		expected bool
+	}{
+		{now: since, expected: false},
+		{now: since.Add(10 * time.Minute), expected: false},
+		{now: since.Add(10*time.Minute + time.Second), expected: true},
+		{now: since.Add(24 * time.Hour), expected: true},
+	}
+
+	for i, tc := range tests {
+		if expired := autoRevertWindowExpired(policy, since, tc.now); expired != tc.expected {
+			t.Errorf("failed test case %d:\n\twant: %v\n\thave: %v", i+1, tc.expected, expired)
+		}
+	}
+}
+
+func TestAutoRevertTriggered(t *testing.T) {
+	var tests = []struct {
+		maxDegradedPercent int32
+		targeted           int
+		degraded           int
+		expected           bool
+	}{
+		{maxDegradedPercent: 10, targeted: 0, degraded: 0, expected: false},
+		{maxDegradedPercent: 10, targeted: 10, degraded: 0, expected: false},
+		{maxDegradedPercent: 10, targeted: 10, degraded: 1, expected: false},
+		{maxDegradedPercent: 10, targeted: 10, degraded: 2, expected: true},
+		{maxDegradedPercent: 0, targeted: 100, degraded: 1, expected: true},
+		{maxDegradedPercent: 100, targeted: 3, degraded: 3, expected: false},
+		{maxDegradedPercent: 50, targeted: 3, degraded: 2, expected: true},
+	}
+
+	for i, tc := range tests {
+		policy := &tunedv1.TunedAutoRevert{MaxDegradedPercent: tc.maxDegradedPercent, WindowSeconds: 600}
+		if triggered := autoRevertTriggered(policy, tc.targeted, tc.degraded); triggered != tc.expected {
+			t.Errorf("failed test case %d:\n\twant: %v\n\thave: %v", i+1, tc.expected, triggered)
+		}
+	}
+}
+
+func TestTunedProfilesDegraded(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	name := "tenant"
+	tuned := &tunedv1.Tuned{Spec: tunedv1.TunedSpec{Profile: []tunedv1.TunedProfile{{Name: &name}}}}
+
+	profile := func(tunedProfile string, degraded corev1.ConditionStatus, transition time.Time) *tunedv1.Profile {
+		p := &tunedv1.Profile{}
+		p.Spec.Config.TunedProfile = tunedProfile
+		p.Status.Conditions = []tunedv1.ProfileStatusCondition{{
+			Type:               tunedv1.TunedDegraded,
+			Status:             degraded,
+			LastTransitionTime: metav1.NewTime(transition),
+		}}
+		return p
+	}
+	profileList := []*tunedv1.Profile{
+		profile("openshift-node", corev1.ConditionTrue, since.Add(time.Minute)),        // not targeted
+		profile("tenant", corev1.ConditionFalse, since.Add(time.Minute)),               // targeted, healthy
+		profile("tenant", corev1.ConditionTrue, since.Add(time.Minute)),                // targeted, degraded by the change
+		profile("openshift-node tenant", corev1.ConditionTrue, since.Add(time.Second)), // merged profile, degraded by the change
+		profile("tenant", corev1.ConditionTrue, since.Add(-time.Minute)),               // degraded before the change
+	}
+
+	targeted, degraded := tunedProfilesDegraded(tuned, profileList, since)
+	if targeted != 4 || degraded != 2 {
+		t.Errorf("want 4 targeted and 2 degraded Profiles, have %d and %d", targeted, degraded)
+	}
+}
+
+func TestSetTunedStatusCondition(t *testing.T) {
+	past := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	conditions := []tunedv1.TunedStatusCondition{
+		{Type: tunedv1.TunedAccepted, Status: corev1.ConditionTrue, LastTransitionTime: past},
+		{Type: tunedv1.TunedReverted, Status: corev1.ConditionTrue, LastTransitionTime: past},
+	}
+
+	// Same status keeps the last transition time.
+	ret := setTunedStatusCondition(conditions, tunedv1.TunedStatusCondition{Type: tunedv1.TunedReverted, Status: corev1.ConditionTrue, Reason: "ProfilesDegraded"})
+	if len(ret) != 2 || ret[1].Reason != "ProfilesDegraded" || !ret[1].LastTransitionTime.Equal(&past) {
+		t.Errorf("unexpected conditions: %+v", ret)
+	}
+
+	// Status change updates the last transition time.
+	ret = setTunedStatusCondition(conditions, tunedv1.TunedStatusCondition{Type: tunedv1.TunedReverted, Status: corev1.ConditionFalse, Reason: "Changed"})
+	if len(ret) != 2 || ret[1].Status != corev1.ConditionFalse || ret[1].LastTransitionTime.Equal(&past) {
+		t.Errorf("unexpected conditions: %+v", ret)
+	}
+}
+
+func TestSyncTunedsReverted(t *testing.T) {
+	prevData, newData := "[main]\nsummary=previous", "[main]\nsummary=new"
+	name := "tenant"
+	tuned := &tunedv1.Tuned{
+		ObjectMeta: metav1.ObjectMeta{Name: "tuned", Namespace: ntoconfig.WatchNamespace(), UID: "uid"},
+		Spec: tunedv1.TunedSpec{
+			Profile:    []tunedv1.TunedProfile{{Name: &name, Data: &prevData}},
+			AutoRevert: &tunedv1.TunedAutoRevert{MaxDegradedPercent: 10, WindowSeconds: 600},
+		},
+	}
+	prevRevision := autoRevertTestRevision(t, tuned, 1)
+	tuned.Spec.Profile[0].Data = &newData
+	newRevision := autoRevertTestRevision(t, tuned, 2)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(prevRevision)
+	indexer.Add(newRevision)
+	c := &Controller{listers: &ntoclient.Listers{
+		ControllerRevisions: kappslisters.NewControllerRevisionLister(indexer).ControllerRevisions(ntoconfig.WatchNamespace()),
+	}}
+
+	// The newest revision is in effect until it is reverted.
+	reverted, err := c.syncTunedsReverted([]*tunedv1.Tuned{tuned})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reverted) != 0 {
+		t.Errorf("want no reverted Tuned, have %v", reverted)
+	}
+
+	// The reverted revision is replaced by the previous one.
+	tuned.Status.RevertedRevision = newRevision.Name
+	reverted, err = c.syncTunedsReverted([]*tunedv1.Tuned{tuned})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, ok := reverted[tuned.Name]; !ok || *r.Spec.Profile[0].Data != prevData {
+		t.Errorf("want Tuned %s reverted to the previous revision, have %v", tuned.Name, reverted)
+	}
+	if *tuned.Spec.Profile[0].Data != newData {
+		t.Errorf("input Tuned modified")
+	}
+
+	// The first revision cannot be reverted.
+	prev, err := c.revisionPrevious(tuned, prevRevision.Name)
+	if err == nil {
+		t.Errorf("want an error for the revision preceding the first revision, have %s", prev.Name)
+	}
+}
+
+func autoRevertTestRevision(t *testing.T, tuned *tunedv1.Tuned, revision int64) *appsv1.ControllerRevision {
+	data, err := json.Marshal(tunedContent(tuned))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            revisionName(revisionHistoryTuned, tuned, data),
+			Namespace:       ntoconfig.WatchNamespace(),
+			Labels:          map[string]string{revisionHistoryLabel: revisionHistoryTuned},
+			OwnerReferences: getDefaultTunedRefs(tuned),
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revision,
+	}
+}
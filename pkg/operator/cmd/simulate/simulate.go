@@ -0,0 +1,275 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/operator"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+)
+
+const (
+	outputTable = "table"
+	outputYAML  = "yaml"
+)
+
+type simulateOpts struct {
+	inputFiles            inputFiles
+	tunedInputFiles       inputFiles
+	compareTunedInputFile inputFiles
+	output                string
+}
+
+type inputFiles []string
+
+func (f *inputFiles) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *inputFiles) Type() string {
+	return "inputFiles"
+}
+
+// Set parses a comma-separated list of files and directories and stores it in f.
+func (f *inputFiles) Set(value string) error {
+	if len(*f) > 0 {
+		return errors.New("input files flag already set")
+	}
+
+	for _, s := range strings.Split(value, ",") {
+		*f = append(*f, s)
+	}
+	return nil
+}
+
+// simulationDiff is the difference of the profiles calculated for a Node from
+// two sets of Tuned CRs.
+type simulationDiff struct {
+	Node    string                     `json:"node"`
+	Current *operator.SimulatedProfile `json:"current"`
+	Compare *operator.SimulatedProfile `json:"compare"`
+}
+
+// NewSimulateCommand creates a simulate command.
+func NewSimulateCommand() *cobra.Command {
+	simulateOpts := simulateOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate the TuneD profile selection for Nodes offline",
+		Long: `Calculate the TuneD profile, operand configuration and MachineConfig labels each Node
+would receive from Tuned, Node, MachineConfigPool, Pod and Namespace manifests (or a must-gather)
+without an API server.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := simulateOpts.Validate(); err != nil {
+				klog.Fatal(err)
+			}
+
+			if err := simulateOpts.Run(os.Stdout); err != nil {
+				klog.Fatal(err)
+			}
+		},
+	}
+
+	simulateOpts.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (s *simulateOpts) AddFlags(fs *pflag.FlagSet) {
+	fs.Var(&s.inputFiles, "input-files", "A comma-separated list of manifest files or directories (e.g. a must-gather) with Tuned, Node, MachineConfigPool, Pod and Namespace objects.")
+	fs.Var(&s.tunedInputFiles, "tuned-input-files", "A comma-separated list of Tuned manifest files or directories to use instead of the Tuned objects from --input-files.")
+	fs.Var(&s.compareTunedInputFile, "compare-tuned-input-files", "A comma-separated list of Tuned manifest files or directories to compare with; only Nodes with differing results are printed.")
+	fs.StringVarP(&s.output, "output", "o", outputTable, "Output format: table or yaml.")
+}
+
+func (s *simulateOpts) Validate() error {
+	if len(s.inputFiles) == 0 {
+		return fmt.Errorf("input-files must be specified")
+	}
+
+	if s.output != outputTable && s.output != outputYAML {
+		return fmt.Errorf("unsupported output format %q", s.output)
+	}
+
+	return nil
+}
+
+func (s *simulateOpts) Run(w io.Writer) error {
+	decoder, err := newDecoder()
+	if err != nil {
+		return err
+	}
+
+	objs, err := util.ReadManifests(s.inputFiles, decoder)
+	if err != nil {
+		return err
+	}
+	in := simulationInput(objs)
+	if len(in.Nodes) == 0 {
+		return fmt.Errorf("no Nodes found in %s", strings.Join(s.inputFiles, ","))
+	}
+
+	if len(s.tunedInputFiles) > 0 {
+		objs, err := util.ReadManifests(s.tunedInputFiles, decoder)
+		if err != nil {
+			return err
+		}
+		in.Tuneds = simulationInput(objs).Tuneds
+	}
+
+	profiles, err := operator.SimulateProfiles(in)
+	if err != nil {
+		return err
+	}
+
+	if len(s.compareTunedInputFile) == 0 {
+		return s.printProfiles(w, profiles)
+	}
+
+	objs, err = util.ReadManifests(s.compareTunedInputFile, decoder)
+	if err != nil {
+		return err
+	}
+	in.Tuneds = simulationInput(objs).Tuneds
+	compareProfiles, err := operator.SimulateProfiles(in)
+	if err != nil {
+		return err
+	}
+
+	return s.printDiffs(w, diffProfiles(profiles, compareProfiles))
+}
+
+// newDecoder returns a decoder for the kinds of objects the simulation uses.
+func newDecoder() (runtime.Decoder, error) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	if err := tunedv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := mcfgv1.Install(scheme); err != nil {
+		return nil, err
+	}
+	return serializer.NewCodecFactory(scheme).UniversalDeserializer(), nil
+}
+
+// simulationInput sorts objects 'objs' into operator.SimulationInput.
+// Objects of kinds the simulation does not use are ignored.
+func simulationInput(objs []runtime.Object) operator.SimulationInput {
+	in := operator.SimulationInput{}
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *tunedv1.Tuned:
+			in.Tuneds = append(in.Tuneds, o)
+		case *corev1.Node:
+			in.Nodes = append(in.Nodes, o)
+		case *mcfgv1.MachineConfigPool:
+			in.MachineConfigPools = append(in.MachineConfigPools, o)
+		case *corev1.Pod:
+			in.Pods = append(in.Pods, o)
+		case *corev1.Namespace:
+			in.Namespaces = append(in.Namespaces, o)
+		}
+	}
+
+	return in
+}
+
+// diffProfiles returns the Nodes for which 'profiles' and 'compareProfiles'
+// differ.
+func diffProfiles(profiles, compareProfiles []operator.SimulatedProfile) []simulationDiff {
+	byNode := map[string]*operator.SimulatedProfile{}
+	for i := range compareProfiles {
+		byNode[compareProfiles[i].Node] = &compareProfiles[i]
+	}
+
+	var diffs []simulationDiff
+	for i := range profiles {
+		compare := byNode[profiles[i].Node]
+		if reflect.DeepEqual(&profiles[i], compare) {
+			continue
+		}
+		diffs = append(diffs, simulationDiff{Node: profiles[i].Node, Current: &profiles[i], Compare: compare})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Node < diffs[j].Node })
+
+	return diffs
+}
+
+func (s *simulateOpts) printProfiles(w io.Writer, profiles []operator.SimulatedProfile) error {
+	if s.output == outputYAML {
+		return printYAML(w, profiles)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPROFILE\tMACHINECONFIG\tOPERAND\tMATCHED POD\tERROR")
+	for _, p := range profiles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Node, valueOrNone(p.TunedProfile), valueOrNone(p.MachineConfig),
+			operandString(p.Operand), valueOrNone(p.MatchedPod), valueOrNone(p.Error))
+	}
+	return tw.Flush()
+}
+
+func (s *simulateOpts) printDiffs(w io.Writer, diffs []simulationDiff) error {
+	if s.output == outputYAML {
+		return printYAML(w, diffs)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPROFILE\tCOMPARE PROFILE\tMACHINECONFIG\tCOMPARE MACHINECONFIG\tOPERAND\tCOMPARE OPERAND")
+	for _, d := range diffs {
+		compare := d.Compare
+		if compare == nil {
+			compare = &operator.SimulatedProfile{}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Node,
+			valueOrNone(d.Current.TunedProfile), valueOrNone(compare.TunedProfile),
+			valueOrNone(d.Current.MachineConfig), valueOrNone(compare.MachineConfig),
+			operandString(d.Current.Operand), operandString(compare.Operand))
+	}
+	return tw.Flush()
+}
+
+func printYAML(w io.Writer, v interface{}) error {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func operandString(operand tunedv1.OperandConfig) string {
+	s := fmt.Sprintf("debug=%t", operand.Debug)
+	if operand.TuneDConfig.ReapplySysctl != nil {
+		s += fmt.Sprintf(",reapply_sysctl=%t", *operand.TuneDConfig.ReapplySysctl)
+	}
+	return s
+}
+
+func valueOrNone(s string) string {
+	if len(s) == 0 {
+		return "<none>"
+	}
+	return s
+}
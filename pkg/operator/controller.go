@@ -57,12 +57,29 @@ const (
 	wqKindClusterOperator   = "clusteroperator"
 	wqKindDaemonSet         = "daemonset"
 	wqKindTuned             = "tuned"
+	wqKindTenantTuned       = "tenanttuned"
+	wqKindNamespace         = "namespace"
 	wqKindProfile           = "profile"
 	wqKindConfigMap         = "configmap"
 	wqKindMachineConfigPool = "machineconfigpool"
+	wqKindHostedStatus      = "hostedstatus"
 
-	tunedConfigMapLabel     = "hypershift.openshift.io/tuned-config"
-	tunedConfigMapConfigKey = "tuned"
+	tunedConfigMapLabel     = util.HyperShiftTunedConfigMapLabel
+	tunedConfigMapConfigKey = util.HyperShiftTunedConfigMapConfigKey
+
+	// Profile updates caused by Pod annotation changes on a Node are coalesced
+	// within this period to prevent Pod churn from causing repeated profile
+	// calculations and TuneD reloads.
+	podChurnDebounce = 5 * time.Second
+
+	// Reports of the hosted cluster tuning status to the management cluster are
+	// coalesced within this period to prevent bursts of Profile changes, such as
+	// during NodePool rollouts, from causing repeated management cluster updates.
+	hostedStatusDebounce = 10 * time.Second
+
+	// Maximum time to wait for the operands to confirm the rollback of the
+	// node-level tuning in Profile status when the operator is being removed.
+	removalRollbackTimeout = 5 * time.Minute
 )
 
 // Controller is the controller implementation for Tuned resources
@@ -76,12 +93,29 @@ type Controller struct {
 	listers *ntoclient.Listers
 	clients *ntoclient.Clients
 
-	pod, node struct {
+	pod, podAnnotation, node struct {
 		informerEnabled bool
 		stopCh          chan struct{}
 	}
 
 	pc *ProfileCalculator
+
+	// state of the operator removal (ManagementState: Removed)
+	removal struct {
+		// time the operands were first requested to roll back the node-level tuning
+		started time.Time
+		// names of the Profiles with the rollback not confirmed yet
+		pending []string
+		// all traces of the operand were removed
+		completed bool
+	}
+
+	// time the Profiles generated by an old operand version were first observed
+	// after the operand DaemonSet rolled out; zero if there are none
+	operandVersionSkewSince time.Time
+
+	// sysctl configuration files of the MachineConfigs checked for sysctl conflicts
+	mcSysctlFiles machineConfigSysctlFilesCache
 }
 
 type wqKey struct {
@@ -238,6 +272,10 @@ func (c *Controller) sync(key wqKey) error {
 	}
 	// We have the default Tuned custom resource (cr)
 
+	if cr.Spec.ManagementState != operatorv1.Removed {
+		c.removalReset()
+	}
+
 	switch cr.Spec.ManagementState {
 	case operatorv1.Force:
 		// Use the same logic as Managed.
@@ -300,11 +338,44 @@ func (c *Controller) sync(key wqKey) error {
 		}
 		return nil
 
+	case key.kind == wqKindNamespace:
+		klog.V(2).Infof("sync(): Namespace %s", key.name)
+
+		// Namespace annotations grant entitlements to tenant Tuned CRs in the Namespace.
+		return c.enqueueTenantTuneds(key.name)
+
+	case key.kind == wqKindTenantTuned:
+		klog.V(2).Infof("sync(): tenant Tuned %s/%s", key.namespace, key.name)
+
+		err = c.syncTenantTuned(key.namespace, key.name)
+		if err != nil {
+			return fmt.Errorf("failed to sync tenant Tuned %s/%s: %v", key.namespace, key.name, err)
+		}
+		err = c.syncTunedRendered(cr)
+		if err != nil {
+			return fmt.Errorf("failed to sync Tuned %s: %v", tunedv1.TunedRenderedResourceName, err)
+		}
+		// Tenant Tuned CRs can change profiles of all Nodes their Namespace is entitled to tune.
+		return c.enqueueProfileUpdates()
+
 	case key.kind == wqKindConfigMap:
 		// This should only happen in HyperShift
 		klog.V(2).Infof("sync(): wqKindConfigMap %s", key.name)
 		err = c.syncHostedClusterTuneds()
-		return err
+		if err != nil {
+			return err
+		}
+		c.enqueueHostedClusterStatus()
+		return nil
+
+	case key.kind == wqKindHostedStatus:
+		// This should only happen in HyperShift
+		klog.V(2).Infof("sync(): hosted cluster status")
+		err = c.syncHostedClusterStatus()
+		if err != nil {
+			return fmt.Errorf("failed to sync hosted cluster status: %v", err)
+		}
+		return nil
 
 	case key.kind == wqKindMachineConfigPool:
 		klog.V(2).Infof("sync(): MachineConfigPool %s", key.name)
@@ -348,6 +419,15 @@ func (c *Controller) sync(key wqKey) error {
 		if err != nil {
 			return fmt.Errorf("failed to sync Profile %s: %v", key.name, err)
 		}
+		// Profile status changes can trigger the autoRevert policy of Tuned CRs.
+		err = c.syncAutoRevert()
+		if err != nil {
+			return fmt.Errorf("failed to sync autoRevert policies: %v", err)
+		}
+		if ntoconfig.InHyperShift() {
+			// Report the Profile status changes back to the management cluster.
+			c.enqueueHostedClusterStatus()
+		}
 		return nil
 
 	default:
@@ -402,11 +482,13 @@ func (c *Controller) sync(key wqKey) error {
 
 	// Tuned CR change can also mean some MachineConfigs the operator created are no longer needed;
 	// removal of these will also rollback host settings such as kernel boot parameters.
-	if !ntoconfig.InHyperShift() {
+	if ntoconfig.InHyperShift() {
+		err = c.pruneMachineConfigsHyperShift()
+	} else {
 		err = c.pruneMachineConfigs()
-		if err != nil {
-			return err
-		}
+	}
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -420,6 +502,10 @@ out:
 	if err != nil {
 		lastErr = fmt.Errorf("failed to disable Pod informer: %v", err)
 	}
+	err = c.enablePodAnnotationInformer(false)
+	if err != nil {
+		lastErr = fmt.Errorf("failed to disable Pod annotation informer: %v", err)
+	}
 	err = c.syncOperatorStatus(cr)
 	if err != nil {
 		lastErr = fmt.Errorf("failed to synchronize Operator status: %v", err)
@@ -481,12 +567,55 @@ func (c *Controller) syncTunedRendered(tuned *tunedv1.Tuned) error {
 	if err != nil {
 		return fmt.Errorf("failed to list Tuned: %v", err)
 	}
+	// Tuned CRs with an autoRevert policy may be reverted to their previous revision.
+	c.pc.reverted, err = c.syncTunedsReverted(tunedList)
+	if err != nil {
+		return err
+	}
+	tunedList = c.pc.tunedsEffective(tunedList)
+	// Revisions record the recommend rules of the Tuned CRs in the operator's namespace.
+	recommend := tunedRecommend(tunedList)
+
+	// Accepted tenant Tuned CRs are rendered together with the Tuned CRs in the
+	// operator's namespace; their profiles are renamed not to collide with others.
+	tenants, err := c.pc.tenantTuneds()
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		tunedList = append(tunedList, tenant.tuned)
+	}
 
 	crMf := ntomf.TunedRenderedResource(tunedList)
 	crMf.ObjectMeta.OwnerReferences = getDefaultTunedRefs(tuned)
 	crMf.Name = tunedv1.TunedRenderedResourceName
 
-	nodeLabelsUsed := c.pc.tunedsUseNodeLabels(tunedList)
+	// Cluster administrators can roll the rendered content back to a previous
+	// revision by annotating the default Tuned CR.
+	c.pc.rollback = nil
+	if revision, ok := tuned.Annotations[tunedv1.RollbackToRevisionAnnotationKey]; ok {
+		rollback, err := c.rollbackTuned(tuned, revision)
+		if err != nil {
+			klog.Errorf("ignoring request to roll back to revision %s: %v", revision, err)
+		} else {
+			klog.V(2).Infof("syncTunedRendered(): rolling back to revision %s", rollback.Name)
+			c.pc.rollback = rollback
+			crMf.Spec.Profile = rollback.Spec.Profile
+			tunedList = []*tunedv1.Tuned{rollback}
+		}
+	}
+	if c.pc.rollback != nil {
+		c.pc.revision = c.pc.rollback.Name
+	} else {
+		c.pc.revision, err = c.syncRevision(tuned, revisionHistoryRendered, tunedv1.TunedSpec{Profile: crMf.Spec.Profile, Recommend: recommend})
+		if err != nil {
+			return err
+		}
+	}
+	crMf.ObjectMeta.Annotations = map[string]string{tunedv1.RenderedRevisionAnnotationKey: c.pc.revision}
+
+	// Tenant Namespaces are entitled to tune Nodes based on Node labels.
+	nodeLabelsUsed := c.pc.tunedsUseNodeLabels(tunedList) || len(tenants) > 0
 	c.enableNodeInformer(nodeLabelsUsed)
 
 	// Enable/Disable Pod events based on tuned CRs using this functionality.
@@ -494,6 +623,14 @@ func (c *Controller) syncTunedRendered(tuned *tunedv1.Tuned) error {
 	podLabelsUsed := c.pc.tunedsUsePodLabels(tunedList)
 	c.enablePodInformer(podLabelsUsed)
 
+	// Pod annotation based matching does not track Pod labels cluster-wide and only
+	// Pods annotated by the annotations the Tuned CRs use trigger profile calculations.
+	podAnnotations := c.pc.tunedsPodAnnotations(tunedList)
+	if c.pc.podAnnotationsSet(podAnnotations) {
+		klog.V(2).Infof("syncTunedRendered(): Pod annotations used for profile matching: %v", podAnnotations)
+	}
+	c.enablePodAnnotationInformer(len(podAnnotations) > 0)
+
 	cr, err := c.listers.TunedResources.Get(tunedv1.TunedRenderedResourceName)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -509,12 +646,14 @@ func (c *Controller) syncTunedRendered(tuned *tunedv1.Tuned) error {
 		return fmt.Errorf("failed to get Tuned %s: %v", tunedv1.TunedRenderedResourceName, err)
 	}
 
-	if reflect.DeepEqual(crMf.Spec.Profile, cr.Spec.Profile) {
+	if reflect.DeepEqual(crMf.Spec.Profile, cr.Spec.Profile) &&
+		cr.Annotations[tunedv1.RenderedRevisionAnnotationKey] == c.pc.revision {
 		klog.V(2).Infof("syncTunedRendered(): Tuned %s doesn't need updating", crMf.Name)
 		return nil
 	}
 	cr = cr.DeepCopy() // never update the objects from cache
 	cr.Spec = crMf.Spec
+	setAnnotation(&cr.ObjectMeta, tunedv1.RenderedRevisionAnnotationKey, c.pc.revision)
 
 	klog.V(2).Infof("syncTunedRendered(): updating Tuned %s", cr.Name)
 	_, err = c.clients.Tuned.TunedV1().Tuneds(ntoconfig.WatchNamespace()).Update(context.TODO(), cr, metav1.UpdateOptions{})
@@ -623,6 +762,14 @@ func (c *Controller) syncProfile(tuned *tunedv1.Tuned, nodeName string) error {
 
 	metrics.ProfileCalculated(profileMf.Name, tunedProfileName)
 
+	activeWindow := c.pc.state.scheduleWindows[nodeName]
+	var nextTransition *metav1.Time
+	if next, ok := c.pc.state.scheduleNext[nodeName]; ok {
+		nextTransition = &metav1.Time{Time: next}
+		// Recalculate the profile once a schedule window starts or ends.
+		c.workqueue.AddAfter(wqKey{kind: wqKindProfile, namespace: ntoconfig.WatchNamespace(), name: nodeName}, time.Until(next))
+	}
+
 	profile, err := c.listers.TunedProfiles.Get(profileMf.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -640,6 +787,10 @@ func (c *Controller) syncProfile(tuned *tunedv1.Tuned, nodeName string) error {
 			profileMf.Spec.Config.Debug = operand.Debug
 			profileMf.Spec.Config.TuneDConfig = operand.TuneDConfig
 			profileMf.Status.Conditions = tunedpkg.InitializeStatusConditions()
+			profileMf.Status.ActiveWindow = activeWindow
+			profileMf.Status.NextTransition = nextTransition
+			setAnnotation(&profileMf.ObjectMeta, tunedv1.MatchedPodAnnotationKey, c.pc.state.podMatches[nodeName])
+			setAnnotation(&profileMf.ObjectMeta, tunedv1.RenderedRevisionAnnotationKey, c.pc.revision)
 			_, err = c.clients.Tuned.TunedV1().Profiles(ntoconfig.WatchNamespace()).Create(context.TODO(), profileMf, metav1.CreateOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to create Profile %s: %v", profileMf.Name, err)
@@ -666,8 +817,14 @@ func (c *Controller) syncProfile(tuned *tunedv1.Tuned, nodeName string) error {
 
 	if ntoconfig.InHyperShift() {
 		// In HyperShift
-		if profile.Status.TunedProfile == tunedProfileName && profileApplied(profile) {
-			klog.V(2).Infof("MachineConfigs not yet supported in HyperShift. Skipping for profile %s on node %s for NodePool %s", tunedProfileName, nodeName, nodePoolName)
+		if nodePoolName != "" && profile.Status.TunedProfile == tunedProfileName && profileApplied(profile) {
+			// The Tuned daemon profile 'tunedProfileName' for nodeName was selected for the whole
+			// NodePool.  Synchronize the operator-generated MachineConfig for the NodePool once
+			// the profile has been successfully applied.
+			err := c.syncMachineConfigHyperShift(nodePoolName, profile)
+			if err != nil {
+				return fmt.Errorf("failed to update Profile %s: %v", profile.Name, err)
+			}
 		}
 	} else {
 		if mcLabels != nil {
@@ -685,19 +842,37 @@ func (c *Controller) syncProfile(tuned *tunedv1.Tuned, nodeName string) error {
 		}
 	}
 
+	matchedPod := c.pc.state.podMatches[nodeName]
 	if profile.Spec.Config.TunedProfile == tunedProfileName &&
+		!profile.Spec.Config.Rollback &&
 		profile.Spec.Config.Debug == operand.Debug &&
 		reflect.DeepEqual(profile.Spec.Config.TuneDConfig, operand.TuneDConfig) &&
-		profile.Spec.Config.ProviderName == providerName {
+		profile.Spec.Config.ProviderName == providerName &&
+		profile.ObjectMeta.Annotations[tunedv1.MatchedPodAnnotationKey] == matchedPod &&
+		profile.ObjectMeta.Annotations[tunedv1.RenderedRevisionAnnotationKey] == c.pc.revision &&
+		profile.Status.ActiveWindow == activeWindow &&
+		timeEqual(profile.Status.NextTransition, nextTransition) {
 		klog.V(2).Infof("syncProfile(): no need to update Profile %s", nodeName)
 		return nil
 	}
 	profile = profile.DeepCopy() // never update the objects from cache
+	if profile.Spec.Config.TunedProfile != tunedProfileName ||
+		profile.Spec.Config.Rollback ||
+		profile.Spec.Config.Debug != operand.Debug ||
+		!reflect.DeepEqual(profile.Spec.Config.TuneDConfig, operand.TuneDConfig) ||
+		profile.Spec.Config.ProviderName != providerName {
+		// Only reset the status conditions when the operand needs to act on the change.
+		profile.Status.Conditions = tunedpkg.InitializeStatusConditions()
+	}
 	profile.Spec.Config.TunedProfile = tunedProfileName
+	profile.Spec.Config.Rollback = false
 	profile.Spec.Config.Debug = operand.Debug
 	profile.Spec.Config.TuneDConfig = operand.TuneDConfig
 	profile.Spec.Config.ProviderName = providerName
-	profile.Status.Conditions = tunedpkg.InitializeStatusConditions()
+	profile.Status.ActiveWindow = activeWindow
+	profile.Status.NextTransition = nextTransition
+	setAnnotation(&profile.ObjectMeta, tunedv1.MatchedPodAnnotationKey, matchedPod)
+	setAnnotation(&profile.ObjectMeta, tunedv1.RenderedRevisionAnnotationKey, c.pc.revision)
 
 	klog.V(2).Infof("syncProfile(): updating Profile %s [%s]", profile.Name, tunedProfileName)
 	_, err = c.clients.Tuned.TunedV1().Profiles(ntoconfig.WatchNamespace()).Update(context.TODO(), profile, metav1.UpdateOptions{})
@@ -709,6 +884,28 @@ func (c *Controller) syncProfile(tuned *tunedv1.Tuned, nodeName string) error {
 	return nil
 }
 
+// setAnnotation sets annotation 'key' of object metadata 'meta' to 'value'.
+// An empty 'value' removes the annotation.
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if value == "" {
+		delete(meta.Annotations, key)
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
+// timeEqual returns true if times 'a' and 'b' are equal with the (second)
+// precision they are serialized with.
+func timeEqual(a, b *metav1.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Unix() == b.Unix()
+}
+
 func (c *Controller) getProviderName(nodeName string) (string, error) {
 	node, err := c.listers.Nodes.Get(nodeName)
 	if err != nil {
@@ -986,12 +1183,142 @@ func (c *Controller) enablePodInformer(enable bool) error {
 	return nil
 }
 
+// enablePodAnnotationInformer enables/disables event handling for Pods used by
+// the Pod annotation based profile matching.
+func (c *Controller) enablePodAnnotationInformer(enable bool) error {
+	if (enable && c.podAnnotation.informerEnabled) || (!enable && !c.podAnnotation.informerEnabled) {
+		return nil
+	}
+
+	if enable {
+		var (
+			informerFactory kubeinformers.SharedInformerFactory
+			informer        corev1informers.PodInformer
+		)
+		c.podAnnotation.stopCh = make(chan struct{})
+		informerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(c.clients.Kube, ntoconfig.ResyncPeriod(), kubeinformers.WithNamespace(corev1.NamespaceAll))
+
+		informer = informerFactory.Core().V1().Pods()
+		if err := informer.Informer().SetTransform(podAnnotationTransform); err != nil {
+			return err
+		}
+		if err := informer.Informer().AddIndexers(cache.Indexers{podNodeNameIndex: podNodeNameIndexFunc}); err != nil {
+			return err
+		}
+		c.listers.PodIndexer = informer.Informer().GetIndexer()
+		informer.Informer().AddEventHandler(c.podAnnotationEventHandler())
+
+		informerFactory.Start(c.podAnnotation.stopCh)
+	} else {
+		defer close(c.podAnnotation.stopCh)
+		c.podAnnotation.stopCh <- struct{}{}
+		c.listers.PodIndexer = nil
+	}
+
+	c.podAnnotation.informerEnabled = enable
+	metrics.PodAnnotationsUsed(enable)
+	return nil
+}
+
+// podAnnotationEventHandler returns Pod event handlers for the Pod annotation
+// based profile matching.  Unlike informerEventHandler, the events are filtered
+// before reaching the workqueue: only changes to the Pod annotations used by
+// the Tuned CRs and (re)scheduling/termination of such annotated Pods result
+// in (debounced) Profile updates of the affected Nodes.
+func (c *Controller) podAnnotationEventHandler() cache.ResourceEventHandlerFuncs {
+	enqueue := func(pod *corev1.Pod) {
+		if pod.Spec.NodeName == "" {
+			// Pod not scheduled (yet)
+			return
+		}
+		klog.V(2).Infof("add Profile %s to workqueue due to Pod %s/%s", pod.Spec.NodeName, pod.Namespace, pod.Name)
+		c.workqueue.AddAfter(wqKey{kind: wqKindProfile, namespace: ntoconfig.WatchNamespace(), name: pod.Spec.NodeName}, podChurnDebounce)
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(o interface{}) {
+			pod, ok := o.(*corev1.Pod)
+			if !ok || !c.pc.podAnnotated(pod) {
+				return
+			}
+			enqueue(pod)
+		},
+		UpdateFunc: func(o, n interface{}) {
+			podOld, ok := o.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			podNew, ok := n.(*corev1.Pod)
+			if !ok || !c.pc.podAnnotationsChange(podOld, podNew) {
+				return
+			}
+			if podOld.Spec.NodeName != podNew.Spec.NodeName {
+				enqueue(podOld)
+			}
+			enqueue(podNew)
+		},
+		DeleteFunc: func(o interface{}) {
+			pod, ok := o.(*corev1.Pod)
+			if !ok {
+				tombstone, ok := o.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					klog.Errorf("error decoding object, invalid type")
+					return
+				}
+				pod, ok = tombstone.Obj.(*corev1.Pod)
+				if !ok {
+					klog.Errorf("error decoding object tombstone, invalid type")
+					return
+				}
+				klog.V(4).Infof("recovered deleted object %s from tombstone", pod.GetName())
+			}
+			if !c.pc.podAnnotated(pod) {
+				return
+			}
+			enqueue(pod)
+		},
+	}
+}
+
+// removeResources removes all traces of the operand in a deterministic order.
+// The operands are first requested to roll back the node-level tuning; once
+// the rollback is confirmed in Profile status (or removalRollbackTimeout
+// passes), the operator-created MachineConfigs are pruned and the operand
+// objects removed.
 func (c *Controller) removeResources() error {
 	var lastErr error
 	dsMf := ntomf.TunedDaemonSet()
 	ctx := context.TODO()
 
-	_, err := c.listers.DaemonSets.Get(dsMf.Name)
+	if c.removal.started.IsZero() {
+		c.removal.started = time.Now()
+	}
+	c.removal.completed = false
+
+	pending, err := c.rollbackProfiles()
+	if err != nil {
+		return err
+	}
+	c.removal.pending = pending
+	if len(pending) > 0 {
+		remaining := removalRollbackTimeout - time.Since(c.removal.started)
+		if remaining > 0 {
+			klog.Infof("waiting for %d Profile(s) to roll back: %s", len(pending), boundedList(pending))
+			// Profile status updates trigger the removal as well, this only guards the timeout.
+			c.workqueue.AddAfter(wqKey{kind: wqKindTuned, name: tunedv1.TunedDefaultResourceName}, remaining)
+			return nil
+		}
+		klog.Warningf("timeout waiting for %d Profile(s) to roll back, continuing the removal: %s", len(pending), boundedList(pending))
+	}
+
+	if !ntoconfig.InHyperShift() {
+		err = c.removeMachineConfigs()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to remove operator-created MachineConfigs: %v", err)
+		}
+	}
+
+	_, err = c.listers.DaemonSets.Get(dsMf.Name)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			lastErr = fmt.Errorf("failed to get DaemonSet %s: %v", dsMf.Name, err)
@@ -1032,14 +1359,93 @@ func (c *Controller) removeResources() error {
 		}
 	}
 
-	if !ntoconfig.InHyperShift() {
-		err = c.pruneMachineConfigs()
+	c.removal.completed = lastErr == nil
+	if c.removal.completed {
+		klog.Infof("operand removed")
+	}
+
+	return lastErr
+}
+
+// rollbackProfiles requests the operands to roll back the node-level tuning
+// of their active TuneD profile.  Rollback is not waited for if the operand
+// DaemonSet no longer exists as there is no operand to confirm it.
+//
+// Returns
+// * names of the Profiles with the rollback not confirmed yet
+// * an error if any
+func (c *Controller) rollbackProfiles() ([]string, error) {
+	var pending []string
+	dsMf := ntomf.TunedDaemonSet()
+
+	_, err := c.listers.DaemonSets.Get(dsMf.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get DaemonSet %s: %v", dsMf.Name, err)
+	}
+
+	profileList, err := c.listers.TunedProfiles.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Tuned Profiles: %v", err)
+	}
+	for _, profile := range profileList {
+		if profile.Spec.Config.Rollback {
+			if !profileRolledBack(profile) {
+				pending = append(pending, profile.Name)
+			}
+			continue
+		}
+		profile = profile.DeepCopy() // never update the objects from cache
+		profile.Spec.Config.Rollback = true
+		_, err = c.clients.Tuned.TunedV1().Profiles(ntoconfig.WatchNamespace()).Update(context.TODO(), profile, metav1.UpdateOptions{})
 		if err != nil {
-			lastErr = fmt.Errorf("failed to prune operator-created MachineConfigs: %v", err)
+			return nil, fmt.Errorf("failed to update Profile %s: %v", profile.Name, err)
 		}
+		klog.Infof("requested rollback of Profile %s", profile.Name)
+		pending = append(pending, profile.Name)
 	}
 
-	return lastErr
+	return pending, nil
+}
+
+// removeMachineConfigs removes the MachineConfigs selected by the Tuned CRs
+// and any other MachineConfigs created by the operator.
+func (c *Controller) removeMachineConfigs() error {
+	mcNames, err := c.getMachineConfigNamesForTuned()
+	if err != nil {
+		return err
+	}
+
+	for mcName := range mcNames {
+		mc, err := c.listers.MachineConfigs.Get(mcName)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if _, ok := mc.ObjectMeta.Annotations[GeneratedByControllerVersionAnnotationKey]; !ok {
+			// Not created by the operator.
+			continue
+		}
+		klog.V(2).Infof("removeMachineConfigs(): deleting MachineConfig %s", mcName)
+		err = c.clients.MC.MachineconfigurationV1().MachineConfigs().Delete(context.TODO(), mcName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("deleted MachineConfig %s", mcName)
+	}
+
+	return c.pruneMachineConfigs()
+}
+
+// removalReset resets the state of the operator removal.
+func (c *Controller) removalReset() {
+	c.removal.started = time.Time{}
+	c.removal.pending = nil
+	c.removal.completed = false
 }
 
 // run will set up the event handlers for types we are interested in, as well
@@ -1054,7 +1460,9 @@ func (c *Controller) run(ctx context.Context) {
 
 	configInformerFactory := configinformers.NewSharedInformerFactory(c.clients.ConfigClientSet, ntoconfig.ResyncPeriod())
 	kubeNTOInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(c.clients.Kube, ntoconfig.ResyncPeriod(), kubeinformers.WithNamespace(ntoconfig.WatchNamespace()))
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(c.clients.Kube, ntoconfig.ResyncPeriod())
 	tunedInformerFactory := tunedinformers.NewSharedInformerFactoryWithOptions(c.clients.Tuned, ntoconfig.ResyncPeriod(), tunedinformers.WithNamespace(ntoconfig.WatchNamespace()))
+	tenantInformerFactory := tunedinformers.NewSharedInformerFactory(c.clients.Tuned, ntoconfig.ResyncPeriod())
 
 	coInformer := configInformerFactory.Config().V1().ClusterOperators()
 	c.listers.ClusterOperators = coInformer.Lister()
@@ -1064,6 +1472,9 @@ func (c *Controller) run(ctx context.Context) {
 	c.listers.DaemonSets = dsInformer.Lister().DaemonSets(ntoconfig.WatchNamespace())
 	dsInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindDaemonSet}))
 
+	crInformer := kubeNTOInformerFactory.Apps().V1().ControllerRevisions()
+	c.listers.ControllerRevisions = crInformer.Lister().ControllerRevisions(ntoconfig.WatchNamespace())
+
 	trInformer := tunedInformerFactory.Tuned().V1().Tuneds()
 	c.listers.TunedResources = trInformer.Lister().Tuneds(ntoconfig.WatchNamespace())
 	trInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindTuned}))
@@ -1072,14 +1483,35 @@ func (c *Controller) run(ctx context.Context) {
 	c.listers.TunedProfiles = tpInformer.Lister().Profiles(ntoconfig.WatchNamespace())
 	tpInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindProfile}))
 
+	// Tenant Tuned CRs live outside of the operator's namespace.
+	ttInformer := tenantInformerFactory.Tuned().V1().Tuneds()
+	c.listers.TenantTuneds = ttInformer.Lister()
+	ttInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(o interface{}) bool {
+			if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+				o = tombstone.Obj
+			}
+			tuned, ok := o.(*tunedv1.Tuned)
+			return ok && isTenantTuned(tuned)
+		},
+		Handler: c.informerEventHandler(wqKey{kind: wqKindTenantTuned}),
+	})
+
+	nsInformer := kubeInformerFactory.Core().V1().Namespaces()
+	c.listers.Namespaces = nsInformer.Lister()
+	nsInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindNamespace}))
+
 	InformerFuncs := []cache.InformerSynced{
 		coInformer.Informer().HasSynced,
 		dsInformer.Informer().HasSynced,
+		crInformer.Informer().HasSynced,
 		trInformer.Informer().HasSynced,
 		tpInformer.Informer().HasSynced,
+		ttInformer.Informer().HasSynced,
+		nsInformer.Informer().HasSynced,
 	}
 
-	var configMapInformerFactory kubeinformers.SharedInformerFactory
+	var configMapInformerFactory, mcConfigMapInformerFactory, statusConfigMapInformerFactory kubeinformers.SharedInformerFactory
 	var mcfgInformerFactory mcfginformers.SharedInformerFactory
 	if ntoconfig.InHyperShift() {
 		labelOptions := kubeinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
@@ -1092,26 +1524,57 @@ func (c *Controller) run(ctx context.Context) {
 		configMapInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindConfigMap}))
 		InformerFuncs = append(InformerFuncs, configMapInformer.Informer().HasSynced)
 
+		// ConfigMaps with the tuning status of the NodePools reported by the operator.
+		statusLabelOptions := kubeinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = operatorGeneratedStatus + "=true"
+		})
+		statusConfigMapInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(c.clients.ManagementKube, ntoconfig.ResyncPeriod(), kubeinformers.WithNamespace(ntoconfig.OperatorNamespace()), statusLabelOptions)
+
+		statusConfigMapInformer := statusConfigMapInformerFactory.Core().V1().ConfigMaps()
+		c.listers.StatusConfigMaps = statusConfigMapInformer.Lister().ConfigMaps(ntoconfig.OperatorNamespace())
+		InformerFuncs = append(InformerFuncs, statusConfigMapInformer.Informer().HasSynced)
+
+		// ConfigMaps with the MachineConfigs for kernel parameters calculated by the operands.
+		mcLabelOptions := kubeinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = tunedMachineConfigLabel + "=true"
+		})
+		mcConfigMapInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(c.clients.ManagementKube, ntoconfig.ResyncPeriod(), kubeinformers.WithNamespace(ntoconfig.OperatorNamespace()), mcLabelOptions)
+
+		mcConfigMapInformer := mcConfigMapInformerFactory.Core().V1().ConfigMaps()
+		c.listers.MachineConfigMaps = mcConfigMapInformer.Lister().ConfigMaps(ntoconfig.OperatorNamespace())
+		InformerFuncs = append(InformerFuncs, mcConfigMapInformer.Informer().HasSynced)
+
 	} else {
 		mcfgInformerFactory = mcfginformers.NewSharedInformerFactory(c.clients.MC, ntoconfig.ResyncPeriod())
 		mcInformer := mcfgInformerFactory.Machineconfiguration().V1().MachineConfigs()
 
 		c.listers.MachineConfigs = mcInformer.Lister()
+		// sysctl.d files of MachineConfigs may conflict with the TuneD sysctls.
+		mcInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindClusterOperator}))
 
 		mcpInformer := mcfgInformerFactory.Machineconfiguration().V1().MachineConfigPools()
 		c.listers.MachineConfigPools = mcpInformer.Lister()
 		mcpInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindMachineConfigPool}))
-		InformerFuncs = append(InformerFuncs, mcInformer.Informer().HasSynced, mcInformer.Informer().HasSynced)
+
+		// allowedUnsafeSysctls of KubeletConfigs may conflict with the TuneD sysctls.
+		kcInformer := mcfgInformerFactory.Machineconfiguration().V1().KubeletConfigs()
+		c.listers.KubeletConfigs = kcInformer.Lister()
+		kcInformer.Informer().AddEventHandler(c.informerEventHandler(wqKey{kind: wqKindClusterOperator}))
+		InformerFuncs = append(InformerFuncs, mcInformer.Informer().HasSynced, mcInformer.Informer().HasSynced, kcInformer.Informer().HasSynced)
 	}
 
 	configInformerFactory.Start(ctx.Done())  // ClusterOperator
-	kubeNTOInformerFactory.Start(ctx.Done()) // DaemonSet
+	kubeNTOInformerFactory.Start(ctx.Done()) // DaemonSet/ControllerRevision
+	kubeInformerFactory.Start(ctx.Done())    // Namespace
 	tunedInformerFactory.Start(ctx.Done())   // Tuned/Profile
+	tenantInformerFactory.Start(ctx.Done())  // tenant Tuned
 
 	if ntoconfig.InHyperShift() {
 		configMapInformerFactory.Start(ctx.Done())
+		mcConfigMapInformerFactory.Start(ctx.Done())
+		statusConfigMapInformerFactory.Start(ctx.Done())
 	} else {
-		mcfgInformerFactory.Start(ctx.Done()) // MachineConfig/MachineConfigPool
+		mcfgInformerFactory.Start(ctx.Done()) // MachineConfig/MachineConfigPool/KubeletConfig
 	}
 
 	// Wait for the caches to be synced before starting worker(s)
@@ -1129,6 +1592,7 @@ func (c *Controller) run(ctx context.Context) {
 	<-ctx.Done()
 	c.enableNodeInformer(false)
 	c.enablePodInformer(false)
+	c.enablePodAnnotationInformer(false)
 	klog.Info("shutting down events processor/controller")
 }
 
@@ -0,0 +1,239 @@
+package operator
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsset "k8s.io/client-go/kubernetes/typed/apps/v1"
+	kappslisters "k8s.io/client-go/listers/apps/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+	tunedset "github.com/openshift/cluster-node-tuning-operator/pkg/generated/clientset/versioned"
+	ntolisters "github.com/openshift/cluster-node-tuning-operator/pkg/generated/listers/tuned/v1"
+	ntomf "github.com/openshift/cluster-node-tuning-operator/pkg/manifests"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	mcfgclientset "github.com/openshift/machine-config-operator/pkg/generated/clientset/versioned"
+	mcfglisters "github.com/openshift/machine-config-operator/pkg/generated/listers/machineconfiguration.openshift.io/v1"
+)
+
+// removalTestAPIServer records the requests sent to the API server.  Updates
+// are echoed back, deletes succeed.
+type removalTestAPIServer struct {
+	sync.Mutex
+	requests []string
+}
+
+func (s *removalTestAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodDelete {
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Success"}`))
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Write(body)
+}
+
+func (s *removalTestAPIServer) flush() []string {
+	s.Lock()
+	defer s.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+type removalTestController struct {
+	*Controller
+	server   *removalTestAPIServer
+	queue    *fakeDelayingQueue
+	profiles cache.Indexer
+}
+
+// newRemovalTestController returns a Controller with the operand DaemonSet, the
+// rendered Tuned, a Profile of Node 'node1' and an operator-created MachineConfig
+// 'mc1' not selected by any Tuned.
+func newRemovalTestController(t *testing.T) *removalTestController {
+	server := &removalTestAPIServer{}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	cfg := &restclient.Config{Host: ts.URL}
+
+	appsClient, err := appsset.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunedClient, err := tunedset.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcClient, err := mcfgclientset.NewForConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := ntoconfig.WatchNamespace()
+	daemonSets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	daemonSets.Add(&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: ntomf.TunedDaemonSet().Name}})
+	tuneds := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	tuneds.Add(&tunedv1.Tuned{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: tunedv1.TunedRenderedResourceName}})
+	profiles := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	profiles.Add(&tunedv1.Profile{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "node1"}})
+	mcs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	mcs.Add(&mcfgv1.MachineConfig{ObjectMeta: metav1.ObjectMeta{
+		Name:        "mc1",
+		Annotations: map[string]string{GeneratedByControllerVersionAnnotationKey: "4.12.0"},
+	}})
+
+	queue := &fakeDelayingQueue{}
+	c := &Controller{
+		listers: &ntoclient.Listers{
+			DaemonSets:     kappslisters.NewDaemonSetLister(daemonSets).DaemonSets(ns),
+			TunedResources: ntolisters.NewTunedLister(tuneds).Tuneds(ns),
+			TunedProfiles:  ntolisters.NewProfileLister(profiles).Profiles(ns),
+			MachineConfigs: mcfglisters.NewMachineConfigLister(mcs),
+		},
+		clients: &ntoclient.Clients{
+			Apps:  appsClient,
+			Tuned: tunedClient,
+			MC:    mcClient,
+		},
+		pc:        NewProfileCalculator(nil, nil),
+		workqueue: queue,
+	}
+
+	return &removalTestController{Controller: c, server: server, queue: queue, profiles: profiles}
+}
+
+// rollBack confirms the rollback of the Profile of Node 'node1'.
+func (c *removalTestController) rollBack() {
+	c.profiles.Update(&tunedv1.Profile{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ntoconfig.WatchNamespace(), Name: "node1"},
+		Spec:       tunedv1.ProfileSpec{Config: tunedv1.ProfileConfig{Rollback: true}},
+		Status: tunedv1.ProfileStatus{Conditions: []tunedv1.ProfileStatusCondition{
+			{Type: tunedv1.TunedRolledBack, Status: corev1.ConditionTrue},
+		}},
+	})
+}
+
+// requestRollback marks the Profile of Node 'node1' as requested to roll back.
+func (c *removalTestController) requestRollback() {
+	c.profiles.Update(&tunedv1.Profile{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ntoconfig.WatchNamespace(), Name: "node1"},
+		Spec:       tunedv1.ProfileSpec{Config: tunedv1.ProfileConfig{Rollback: true}},
+	})
+}
+
+func TestRemoveResourcesRollbackBeforePrune(t *testing.T) {
+	ns := ntoconfig.WatchNamespace()
+	c := newRemovalTestController(t)
+
+	// The rollback is requested, nothing is removed until it is confirmed.
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"PUT /apis/tuned.openshift.io/v1/namespaces/" + ns + "/profiles/node1"}
+	if have := c.server.flush(); !reflect.DeepEqual(have, want) {
+		t.Errorf("rollback requested: want requests %v, have %v", want, have)
+	}
+	if !reflect.DeepEqual(c.removal.pending, []string{"node1"}) || c.removal.completed {
+		t.Errorf("rollback requested: want Profile node1 pending, have %v, completed %t", c.removal.pending, c.removal.completed)
+	}
+	if !reflect.DeepEqual(c.queue.added, []string{tunedv1.TunedDefaultResourceName}) {
+		t.Errorf("rollback requested: want the removal timeout queued, have %v", c.queue.added)
+	}
+
+	// The rollback is not confirmed yet, the rollback is not requested again.
+	c.requestRollback()
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if have := c.server.flush(); len(have) != 0 {
+		t.Errorf("rollback pending: want no requests, have %v", have)
+	}
+
+	// The MachineConfigs are pruned only after the rollback, then the operand is removed.
+	c.rollBack()
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{
+		"DELETE /apis/machineconfiguration.openshift.io/v1/machineconfigs/mc1",
+		"DELETE /apis/apps/v1/namespaces/" + ns + "/daemonsets/" + ntomf.TunedDaemonSet().Name,
+		"DELETE /apis/tuned.openshift.io/v1/namespaces/" + ns + "/tuneds/" + tunedv1.TunedRenderedResourceName,
+		"DELETE /apis/tuned.openshift.io/v1/namespaces/" + ns + "/profiles/node1",
+	}
+	if have := c.server.flush(); !reflect.DeepEqual(have, want) {
+		t.Errorf("rolled back: want requests %v, have %v", want, have)
+	}
+	if len(c.removal.pending) != 0 || !c.removal.completed {
+		t.Errorf("rolled back: want removal completed, have pending %v, completed %t", c.removal.pending, c.removal.completed)
+	}
+}
+
+func TestRemoveResourcesRollbackTimeout(t *testing.T) {
+	ns := ntoconfig.WatchNamespace()
+	c := newRemovalTestController(t)
+	c.requestRollback()
+
+	// Within the timeout the removal waits for the rollback.
+	c.removal.started = time.Now().Add(-removalRollbackTimeout + time.Minute)
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if have := c.server.flush(); len(have) != 0 {
+		t.Errorf("within the timeout: want no requests, have %v", have)
+	}
+	if c.removal.completed {
+		t.Errorf("within the timeout: want removal not completed")
+	}
+
+	// Past the timeout the removal continues without the rollback confirmed.
+	c.queue.added = nil
+	c.removal.started = time.Now().Add(-removalRollbackTimeout - time.Minute)
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"DELETE /apis/machineconfiguration.openshift.io/v1/machineconfigs/mc1",
+		"DELETE /apis/apps/v1/namespaces/" + ns + "/daemonsets/" + ntomf.TunedDaemonSet().Name,
+		"DELETE /apis/tuned.openshift.io/v1/namespaces/" + ns + "/tuneds/" + tunedv1.TunedRenderedResourceName,
+		"DELETE /apis/tuned.openshift.io/v1/namespaces/" + ns + "/profiles/node1",
+	}
+	if have := c.server.flush(); !reflect.DeepEqual(have, want) {
+		t.Errorf("past the timeout: want requests %v, have %v", want, have)
+	}
+	if len(c.queue.added) != 0 {
+		t.Errorf("past the timeout: want nothing queued, have %v", c.queue.added)
+	}
+	if !reflect.DeepEqual(c.removal.pending, []string{"node1"}) || !c.removal.completed {
+		t.Errorf("past the timeout: want removal completed with Profile node1 pending, have %v, completed %t", c.removal.pending, c.removal.completed)
+	}
+
+	// Leaving the Removed state resets the removal, a new removal waits for the rollback again.
+	c.removalReset()
+	if !c.removal.started.IsZero() || c.removal.pending != nil || c.removal.completed {
+		t.Errorf("removal not reset: %+v", c.removal)
+	}
+	if err := c.removeResources(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if have := c.server.flush(); len(have) != 0 {
+		t.Errorf("after reset: want no requests, have %v", have)
+	}
+}
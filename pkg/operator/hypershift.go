@@ -6,23 +6,37 @@ import (
 	"fmt"
 	"hash/fnv"
 	"io"
+	"os"
 	"reflect"
+	"sort"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 
 	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
 	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
+	"github.com/openshift/cluster-node-tuning-operator/version"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 )
 
 const (
 	hypershiftNodeOwnerNameLabel = "cluster.x-k8s.io/owner-name"
 	hypershiftNodeOwnerKindLabel = "cluster.x-k8s.io/owner-kind"
-	hypershiftNodePoolLabel      = "hypershift.openshift.io/nodePool"
+	hypershiftNodePoolLabel      = util.HyperShiftNodePoolLabel
 	hypershiftNodePoolNameLabel  = "hypershift.openshift.io/nodePoolName"
+
+	operatorGeneratedMachineConfig = util.HyperShiftGeneratedMachineConfigLabel
+	tunedMachineConfigLabel        = util.HyperShiftTunedMachineConfigLabel
+	mcConfigMapDataKey             = util.HyperShiftMachineConfigConfigMapKey
+	mcConfigMapPrefix              = "nto-mc-"
 )
 
 // syncHostedClusterTuneds synchronizes Tuned objects embedded in ConfigMaps
@@ -105,22 +119,39 @@ func (c *Controller) syncHostedClusterTuneds() error {
 // retrieved Tuned objects.  Duplicate Tuned objects are ignored.  Returns non-nil
 // error only when retry is needed.
 func (c *Controller) getObjFromTunedConfigMap() ([]tunedv1.Tuned, error) {
-	var cmTuneds []tunedv1.Tuned
-
 	cmListOptions := metav1.ListOptions{
 		LabelSelector: tunedConfigMapLabel + "=true",
 	}
 
 	cmList, err := c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).List(context.TODO(), cmListOptions)
 	if err != nil {
-		return cmTuneds, fmt.Errorf("error listing ConfigMaps in namespace %s: %v", ntoconfig.OperatorNamespace(), err)
+		return nil, fmt.Errorf("error listing ConfigMaps in namespace %s: %v", ntoconfig.OperatorNamespace(), err)
 	}
 
+	cms := make([]*corev1.ConfigMap, 0, len(cmList.Items))
+	for i := range cmList.Items {
+		cms = append(cms, &cmList.Items[i])
+	}
+	cmTuneds, _ := parseTunedConfigMaps(cms)
+
+	return cmTuneds, nil
+}
+
+// parseTunedConfigMaps parses the Tuned objects embedded in ConfigMaps 'cms'.
+// Duplicate Tuned objects are ignored.
+//
+// Returns
+// * a slice of the parsed Tuned objects
+// * a map of NodePool names to the status of the ConfigMaps referenced by the NodePools
+func parseTunedConfigMaps(cms []*corev1.ConfigMap) ([]tunedv1.Tuned, map[string][]tunedConfigMapStatus) {
+	var cmTuneds []tunedv1.Tuned
+
+	cmStatus := map[string][]tunedConfigMapStatus{}
 	seenTunedObject := map[string]bool{}
-	for _, cm := range cmList.Items {
+	for _, cm := range cms {
 		tunedConfig, ok := cm.Data[tunedConfigMapConfigKey]
 		if !ok {
-			klog.Warning("Tuned in ConfigMap %s has no data for field %s", cm.ObjectMeta.Name, tunedConfigMapConfigKey)
+			klog.Warningf("Tuned in ConfigMap %s has no data for field %s", cm.ObjectMeta.Name, tunedConfigMapConfigKey)
 			continue
 		}
 
@@ -129,11 +160,14 @@ func (c *Controller) getObjFromTunedConfigMap() ([]tunedv1.Tuned, error) {
 			klog.Warningf("failed to parseTunedManifests in ConfigMap %s, no label %s", cm.ObjectMeta.Name, hypershiftNodePoolLabel)
 			continue
 		}
-		nodePoolName := parseNamespacedName(cmNodePoolNamespacedName)
+		nodePoolName := util.ParseNamespacedName(cmNodePoolNamespacedName)
+		status := tunedConfigMapStatus{Name: cm.ObjectMeta.Name}
 
 		tunedsFromConfigMap, err := parseTunedManifests([]byte(tunedConfig), nodePoolName)
 		if err != nil {
 			klog.Warningf("failed to parseTunedManifests in ConfigMap %s: %v", cm.ObjectMeta.Name, err)
+			status.Error = err.Error()
+			cmStatus[nodePoolName] = append(cmStatus[nodePoolName], status)
 			continue
 		}
 
@@ -142,16 +176,19 @@ func (c *Controller) getObjFromTunedConfigMap() ([]tunedv1.Tuned, error) {
 			tunedObjectName := tunedsFromConfigMap[j].ObjectMeta.Name
 			if seenTunedObject[tunedObjectName] {
 				klog.Warningf("ignoring duplicate Tuned Profile %s in ConfigMap %s", tunedObjectName, cm.ObjectMeta.Name)
+				status.Error = fmt.Sprintf("ignoring duplicate Tuned %s", tunedObjectName)
 				continue
 			}
 			seenTunedObject[tunedObjectName] = true
 			tunedsFromConfigMapUnique = append(tunedsFromConfigMapUnique, t)
+			status.Tuneds = append(status.Tuneds, tunedObjectName)
 		}
 
 		cmTuneds = append(cmTuneds, tunedsFromConfigMapUnique...)
+		cmStatus[nodePoolName] = append(cmStatus[nodePoolName], status)
 	}
 
-	return cmTuneds, nil
+	return cmTuneds, cmStatus
 }
 
 // parseManifests parses a YAML or JSON document that may contain one or more
@@ -189,13 +226,170 @@ func hashStruct(o interface{}) string {
 	return fmt.Sprintf("%08x", intHash)
 }
 
-// parseNamespacedName expects a string with the format "namespace/name"
-// and returns the name only.
-// If given a string in the format "name" returns "name".
-func parseNamespacedName(namespacedName string) string {
-	parts := strings.SplitN(namespacedName, "/", 2)
-	if len(parts) > 1 {
-		return parts[1]
+// mcConfigMapName returns the name of the ConfigMap in management's cluster
+// hosted namespace with the operator-generated MachineConfig for NodePool
+// 'nodePoolName'.
+func mcConfigMapName(nodePoolName string) string {
+	return mcConfigMapPrefix + nodePoolName
+}
+
+// newConfigMapForMachineConfig returns a ConfigMap named 'name' embedding
+// MachineConfig 'mc' for the NodePool controller to roll out to NodePool
+// 'nodePoolName'.
+func newConfigMapForMachineConfig(name string, nodePoolName string, mc *mcfgv1.MachineConfig) (*corev1.ConfigMap, error) {
+	mc.Kind = "MachineConfig"
+	data, err := yaml.Marshal(mc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode MachineConfig %s: %v", mc.Name, err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ntoconfig.OperatorNamespace(),
+			Labels: map[string]string{
+				operatorGeneratedMachineConfig: "true",
+				tunedMachineConfigLabel:        "true",
+				hypershiftNodePoolLabel:        nodePoolName,
+			},
+			Annotations: map[string]string{GeneratedByControllerVersionAnnotationKey: version.Version},
+		},
+		Data: map[string]string{
+			mcConfigMapDataKey: string(data),
+		},
+	}, nil
+}
+
+// syncMachineConfigHyperShift synchronizes the MachineConfig with the kernel
+// parameters calculated for Profile 'profile' embedded in a ConfigMap in
+// management's cluster hosted namespace.  Changes of the ConfigMap trigger a
+// config rollout of NodePool 'nodePoolName'.
+func (c *Controller) syncMachineConfigHyperShift(nodePoolName string, profile *tunedv1.Profile) error {
+	if v := profile.ObjectMeta.Annotations[tunedv1.GeneratedByOperandVersionAnnotationKey]; v != os.Getenv("RELEASE_VERSION") {
+		// This looks like an update triggered by an old (not-yet-upgraded) operand.  Ignore it.
+		klog.Infof("refusing to sync MachineConfig for NodePool %q due to Profile %q change generated by operand version %q", nodePoolName, profile.Name, v)
+		return nil
+	}
+
+	mcName := MachineConfigPrefix + "-" + nodePoolName
+	cmName := mcConfigMapName(nodePoolName)
+	bootcmdline := profile.Status.Bootcmdline
+	kernelArguments := util.SplitKernelArguments(bootcmdline)
+	annotations := map[string]string{GeneratedByControllerVersionAnnotationKey: version.Version}
+
+	mcNew := newMachineConfig(mcName, annotations, nil, kernelArguments, nil, nil)
+	cmNew, err := newConfigMapForMachineConfig(cmName, nodePoolName, mcNew)
+	if err != nil {
+		return err
+	}
+
+	cm, err := c.listers.MachineConfigMaps.Get(cmName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get ConfigMap %s: %v", cmName, err)
+		}
+		klog.V(2).Infof("syncMachineConfigHyperShift(): ConfigMap %s not found, creating one", cmName)
+		if len(bootcmdline) == 0 {
+			// Creating a new MachineConfig with empty kernelArguments only causes unnecessary node
+			// reboots.
+			klog.V(2).Infof("not creating a MachineConfig with empty kernelArguments")
+			return nil
+		}
+		_, err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Create(context.TODO(), cmNew, metav1.CreateOptions{})
+		if err == nil {
+			klog.Infof("created ConfigMap %s for NodePool %s with kernel parameters: [%s]", cmName, nodePoolName, bootcmdline)
+			return nil
+		}
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create ConfigMap %s: %v", cmName, err)
+		}
+		// Created by an operator version not setting the tunedMachineConfigLabel label
+		// yet or the informer cache is not up-to-date yet.  Update it.
+		cm, err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Get(context.TODO(), cmName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get ConfigMap %s: %v", cmName, err)
+		}
 	}
-	return parts[0]
+
+	if !mcConfigMapNeedsUpdate(cm, cmNew, kernelArguments) {
+		klog.V(2).Infof("syncMachineConfigHyperShift(): ConfigMap %s doesn't need updating", cmName)
+		return nil
+	}
+	cm = cm.DeepCopy() // never update the objects from cache
+	cm.ObjectMeta.Labels = cmNew.ObjectMeta.Labels
+	cm.ObjectMeta.Annotations = cmNew.ObjectMeta.Annotations
+	cm.Data = cmNew.Data
+
+	klog.V(2).Infof("syncMachineConfigHyperShift(): updating ConfigMap %s with kernel parameters: [%s]", cmName, bootcmdline)
+	_, err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", cmName, err)
+	}
+	klog.Infof("updated ConfigMap %s for NodePool %s with kernel parameters: [%s]", cmName, nodePoolName, bootcmdline)
+
+	return nil
+}
+
+// mcConfigMapNeedsUpdate returns true if ConfigMap 'cm' with an embedded
+// MachineConfig differs from ConfigMap 'cmNew' with the MachineConfig carrying
+// kernel parameters 'kernelArguments' in its labels or kernel parameters.
+func mcConfigMapNeedsUpdate(cm *corev1.ConfigMap, cmNew *corev1.ConfigMap, kernelArguments []string) bool {
+	mc := &mcfgv1.MachineConfig{}
+	if err := yaml.Unmarshal([]byte(cm.Data[mcConfigMapDataKey]), mc); err != nil {
+		klog.Warningf("failed to decode MachineConfig in ConfigMap %s, overwriting it: %v", cm.Name, err)
+		return true
+	}
+	return !util.StringSlicesEqual(mc.Spec.KernelArguments, kernelArguments) ||
+		!reflect.DeepEqual(cm.ObjectMeta.Labels, cmNew.ObjectMeta.Labels)
+}
+
+// mcConfigMapsStale returns the names of ConfigMaps from 'cms' with the
+// MachineConfigs generated for NodePools none of the Tuned CRs from 'tunedList'
+// are propagated to anymore.  Only the ConfigMaps named by mcConfigMapName()
+// are considered.
+func mcConfigMapsStale(cms []*corev1.ConfigMap, tunedList []*tunedv1.Tuned) []string {
+	var stale []string
+
+	cmNames := map[string]bool{}
+	for _, tuned := range tunedList {
+		if nodePoolName, ok := tuned.Labels[hypershiftNodePoolNameLabel]; ok {
+			cmNames[mcConfigMapName(nodePoolName)] = true
+		}
+	}
+
+	for _, cm := range cms {
+		if cm.Labels[tunedMachineConfigLabel] != "true" || !strings.HasPrefix(cm.Name, mcConfigMapPrefix) || cmNames[cm.Name] {
+			continue
+		}
+		stale = append(stale, cm.Name)
+	}
+	sort.Strings(stale)
+
+	return stale
+}
+
+// pruneMachineConfigsHyperShift removes the ConfigMaps with operator-generated
+// MachineConfigs in management's cluster hosted namespace for NodePools none of
+// the Tuned CRs are propagated to anymore.
+func (c *Controller) pruneMachineConfigsHyperShift() error {
+	cms, err := c.listers.MachineConfigMaps.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list ConfigMaps in namespace %s: %v", ntoconfig.OperatorNamespace(), err)
+	}
+
+	tunedList, err := c.listers.TunedResources.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list Tuned: %v", err)
+	}
+
+	for _, cmName := range mcConfigMapsStale(cms, tunedList) {
+		klog.V(2).Infof("pruneMachineConfigsHyperShift(): deleting ConfigMap %s", cmName)
+		err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Delete(context.TODO(), cmName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ConfigMap %s: %v", cmName, err)
+		}
+		klog.Infof("deleted ConfigMap %s", cmName)
+	}
+
+	return nil
 }
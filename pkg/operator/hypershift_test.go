@@ -0,0 +1,174 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestNewConfigMapForMachineConfig(t *testing.T) {
+	mc := newMachineConfig(MachineConfigPrefix+"-pool", nil, nil, []string{"nosmt"}, nil, nil)
+	cm, err := newConfigMapForMachineConfig(mcConfigMapName("pool"), "pool", mc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cm.Name != "nto-mc-pool" {
+		t.Errorf("unexpected ConfigMap name %s", cm.Name)
+	}
+	expectedLabels := map[string]string{
+		operatorGeneratedMachineConfig: "true",
+		tunedMachineConfigLabel:        "true",
+		hypershiftNodePoolLabel:        "pool",
+	}
+	if !reflect.DeepEqual(cm.Labels, expectedLabels) {
+		t.Errorf("unexpected ConfigMap labels:\n\twant: %v\n\thave: %v", expectedLabels, cm.Labels)
+	}
+	if _, ok := cm.Data[mcConfigMapDataKey]; !ok {
+		t.Errorf("ConfigMap has no data for key %s", mcConfigMapDataKey)
+	}
+}
+
+func TestMcConfigMapNeedsUpdate(t *testing.T) {
+	newCM := func(kernelArguments []string, legacy bool) *corev1.ConfigMap {
+		mc := newMachineConfig(MachineConfigPrefix+"-pool", nil, nil, kernelArguments, nil, nil)
+		cm, err := newConfigMapForMachineConfig(mcConfigMapName("pool"), "pool", mc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if legacy {
+			delete(cm.Labels, tunedMachineConfigLabel)
+		}
+		return cm
+	}
+
+	var tests = []struct {
+		name            string
+		cm              *corev1.ConfigMap
+		kernelArguments []string
+		expected        bool
+	}{
+		{
+			name:            "kernel arguments unchanged",
+			cm:              newCM([]string{"nosmt", "skew_tick=1"}, false),
+			kernelArguments: []string{"nosmt", "skew_tick=1"},
+			expected:        false,
+		},
+		{
+			name:            "kernel arguments changed",
+			cm:              newCM([]string{"nosmt"}, false),
+			kernelArguments: []string{"nosmt", "skew_tick=1"},
+			expected:        true,
+		},
+		{
+			name:            "kernel arguments removed",
+			cm:              newCM([]string{"nosmt"}, false),
+			kernelArguments: nil,
+			expected:        true,
+		},
+		{
+			name:            "ConfigMap created without the Tuned label",
+			cm:              newCM([]string{"nosmt"}, true),
+			kernelArguments: []string{"nosmt"},
+			expected:        true,
+		},
+		{
+			name: "undecodable MachineConfig",
+			cm: func() *corev1.ConfigMap {
+				cm := newCM([]string{"nosmt"}, false)
+				cm.Data[mcConfigMapDataKey] = "{"
+				return cm
+			}(),
+			kernelArguments: []string{"nosmt"},
+			expected:        true,
+		},
+	}
+
+	for _, tc := range tests {
+		cmNew := newCM(tc.kernelArguments, false)
+		if needsUpdate := mcConfigMapNeedsUpdate(tc.cm, cmNew, tc.kernelArguments); needsUpdate != tc.expected {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.expected, needsUpdate)
+		}
+	}
+}
+
+func TestMcConfigMapsStale(t *testing.T) {
+	tunedCM := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				operatorGeneratedMachineConfig: "true",
+				tunedMachineConfigLabel:        "true",
+			},
+		}}
+	}
+	// ConfigMaps rendered by the PerformanceProfile controller for NodePool 'nodePoolName'
+	ppCM := func(name, nodePoolName string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				operatorGeneratedMachineConfig:                             "true",
+				hypershiftNodePoolLabel:                                    nodePoolName,
+				"hypershift.openshift.io/performanceprofile-config-source": "pp-" + nodePoolName,
+			},
+		}}
+	}
+	tunedFor := func(nodePoolName string) *tunedv1.Tuned {
+		return &tunedv1.Tuned{ObjectMeta: metav1.ObjectMeta{
+			Name:   "tuned-" + nodePoolName,
+			Labels: map[string]string{hypershiftNodePoolNameLabel: nodePoolName},
+		}}
+	}
+
+	var tests = []struct {
+		name      string
+		cms       []*corev1.ConfigMap
+		tunedList []*tunedv1.Tuned
+		expected  []string
+	}{
+		{
+			name:      "ConfigMaps of NodePools with Tuned CRs are kept",
+			cms:       []*corev1.ConfigMap{tunedCM("nto-mc-a"), tunedCM("nto-mc-b")},
+			tunedList: []*tunedv1.Tuned{tunedFor("a"), tunedFor("b")},
+		},
+		{
+			name:      "ConfigMaps of NodePools without Tuned CRs are stale",
+			cms:       []*corev1.ConfigMap{tunedCM("nto-mc-b"), tunedCM("nto-mc-a"), tunedCM("nto-mc-c")},
+			tunedList: []*tunedv1.Tuned{tunedFor("b"), {ObjectMeta: metav1.ObjectMeta{Name: "default"}}},
+			expected:  []string{"nto-mc-a", "nto-mc-c"},
+		},
+		{
+			name: "ConfigMaps without the Tuned label are kept",
+			cms: []*corev1.ConfigMap{
+				{ObjectMeta: metav1.ObjectMeta{Name: "nto-mc-a", Labels: map[string]string{operatorGeneratedMachineConfig: "true"}}},
+			},
+		},
+		{
+			name: "ConfigMaps not named by the Tuned controller are kept",
+			cms:  []*corev1.ConfigMap{tunedCM("pp-test-machineconfig")},
+		},
+		{
+			name: "PerformanceProfile ConfigMaps of the same NodePool are kept",
+			cms: []*corev1.ConfigMap{
+				tunedCM("nto-mc-a"),
+				tunedCM("nto-mc-b"),
+				ppCM("pp-a-machineconfig", "a"),
+				ppCM("pp-a-kubeletconfig", "a"),
+				ppCM("pp-b-machineconfig", "b"),
+			},
+			tunedList: []*tunedv1.Tuned{tunedFor("a")},
+			expected:  []string{"nto-mc-b"},
+		},
+	}
+
+	for _, tc := range tests {
+		stale := mcConfigMapsStale(tc.cms, tc.tunedList)
+		if !reflect.DeepEqual(stale, tc.expected) {
+			t.Errorf("%s:\n\twant: %v\n\thave: %v", tc.name, tc.expected, stale)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+	"github.com/openshift/cluster-node-tuning-operator/version"
+)
+
+const (
+	// label on management cluster ConfigMaps with the tuning status of the NodePool
+	// named by the hypershiftNodePoolLabel label
+	operatorGeneratedStatus = "hypershift.openshift.io/nto-status"
+	// ConfigMap data key holding the tuning status of the NodePool
+	statusConfigMapDataKey = "status"
+)
+
+// tunedConfigMapStatus is the status of a management cluster ConfigMap with
+// embedded Tuned objects.
+type tunedConfigMapStatus struct {
+	// name of the ConfigMap
+	Name string `json:"name"`
+	// names of the Tuned objects synchronized to the hosted cluster
+	Tuneds []string `json:"tuneds,omitempty"`
+	// error parsing the embedded Tuned objects
+	Error string `json:"error,omitempty"`
+}
+
+// nodePoolTunedStatus is the tuning status of a NodePool reported back to the
+// management cluster.
+type nodePoolTunedStatus struct {
+	// ConfigMaps with Tuned objects referenced by the NodePool
+	ConfigMaps []tunedConfigMapStatus `json:"configMaps,omitempty"`
+	// number of Nodes in the NodePool
+	Nodes int `json:"nodes"`
+	// number of Nodes with their TuneD profile applied
+	Applied int `json:"applied"`
+	// number of Nodes reporting TuneD errors or timeouts applying their profile
+	Degraded int `json:"degraded"`
+	// TuneD profiles selected for the Nodes and the number of Nodes they are selected for
+	Profiles map[string]int `json:"profiles,omitempty"`
+}
+
+// statusConfigMapName returns the name of the ConfigMap in management's cluster
+// hosted namespace with the tuning status of NodePool 'nodePoolName'.
+func statusConfigMapName(nodePoolName string) string {
+	return "nto-status-" + nodePoolName
+}
+
+// enqueueHostedClusterStatus schedules a report of the hosted cluster tuning
+// status to the management cluster.  Requests within hostedStatusDebounce are
+// coalesced into a single report.
+func (c *Controller) enqueueHostedClusterStatus() {
+	c.workqueue.AddAfter(wqKey{kind: wqKindHostedStatus}, hostedStatusDebounce)
+}
+
+// syncHostedClusterStatus reports the status of the Tuned objects embedded in
+// ConfigMaps and of the Profiles of the hosted cluster's Nodes per NodePool into
+// ConfigMaps in management's cluster hosted namespace.
+func (c *Controller) syncHostedClusterStatus() error {
+	cmList, err := c.listers.ConfigMaps.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list ConfigMaps: %v", err)
+	}
+
+	profileList, err := c.listers.TunedProfiles.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list Tuned Profiles: %v", err)
+	}
+
+	nodePools := map[string]string{}
+	for _, profile := range profileList {
+		node, err := c.listers.Nodes.Get(profile.Name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get Node %s: %v", profile.Name, err)
+		}
+		nodePools[node.Name] = node.Labels[hypershiftNodePoolLabel]
+	}
+
+	statuses := nodePoolTunedStatuses(cmList, profileList, nodePools)
+	for nodePoolName, status := range statuses {
+		err = c.syncNodePoolStatusConfigMap(nodePoolName, status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.pruneNodePoolStatusConfigMaps(statuses)
+}
+
+// nodePoolTunedStatuses aggregates the status of the Tuned objects embedded in
+// ConfigMaps 'cms' and of Profiles 'profileList' per NodePool.  'nodePools' maps
+// the names of the Nodes to the names of their NodePools; Profiles of Nodes
+// without a NodePool are not reported.
+func nodePoolTunedStatuses(cms []*corev1.ConfigMap, profileList []*tunedv1.Profile, nodePools map[string]string) map[string]*nodePoolTunedStatus {
+	_, cmStatus := parseTunedConfigMaps(cms)
+
+	statuses := map[string]*nodePoolTunedStatus{}
+	for nodePoolName, cms := range cmStatus {
+		sort.Slice(cms, func(i, j int) bool { return cms[i].Name < cms[j].Name })
+		statuses[nodePoolName] = &nodePoolTunedStatus{ConfigMaps: cms}
+	}
+
+	for _, profile := range profileList {
+		nodePoolName := nodePools[profile.Name]
+		if nodePoolName == "" {
+			continue
+		}
+		status, ok := statuses[nodePoolName]
+		if !ok {
+			status = &nodePoolTunedStatus{}
+			statuses[nodePoolName] = status
+		}
+		status.Nodes++
+		if profileApplied(profile) {
+			status.Applied++
+		}
+		if profileDegraded(profile) {
+			status.Degraded++
+		}
+		if profile.Spec.Config.TunedProfile != "" {
+			if status.Profiles == nil {
+				status.Profiles = map[string]int{}
+			}
+			status.Profiles[profile.Spec.Config.TunedProfile]++
+		}
+	}
+
+	return statuses
+}
+
+// syncNodePoolStatusConfigMap writes tuning status 'status' of NodePool
+// 'nodePoolName' into its status ConfigMap in management's cluster hosted
+// namespace.
+func (c *Controller) syncNodePoolStatusConfigMap(nodePoolName string, status *nodePoolTunedStatus) error {
+	data, err := yaml.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode status of NodePool %s: %v", nodePoolName, err)
+	}
+	cmName := statusConfigMapName(nodePoolName)
+	cmLabels := map[string]string{
+		operatorGeneratedStatus: "true",
+		hypershiftNodePoolLabel: nodePoolName,
+	}
+
+	cm, err := c.listers.StatusConfigMaps.Get(cmName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get ConfigMap %s: %v", cmName, err)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        cmName,
+				Namespace:   ntoconfig.OperatorNamespace(),
+				Labels:      cmLabels,
+				Annotations: map[string]string{GeneratedByControllerVersionAnnotationKey: version.Version},
+			},
+			Data: map[string]string{statusConfigMapDataKey: string(data)},
+		}
+		_, err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Create(context.TODO(), cm, metav1.CreateOptions{})
+		if err != nil {
+			// The informer cache might not be up-to-date yet, retry later.
+			return fmt.Errorf("failed to create ConfigMap %s: %v", cmName, err)
+		}
+		klog.Infof("created ConfigMap %s", cmName)
+		return nil
+	}
+
+	if cm.Data[statusConfigMapDataKey] == string(data) {
+		klog.V(2).Infof("syncNodePoolStatusConfigMap(): ConfigMap %s doesn't need updating", cmName)
+		return nil
+	}
+	cm = cm.DeepCopy() // never update the objects from cache
+	cm.ObjectMeta.Labels = cmLabels
+	cm.Data = map[string]string{statusConfigMapDataKey: string(data)}
+	_, err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %v", cmName, err)
+	}
+	klog.V(2).Infof("updated ConfigMap %s", cmName)
+
+	return nil
+}
+
+// pruneNodePoolStatusConfigMaps removes the status ConfigMaps in management's
+// cluster hosted namespace of NodePools other than those in 'statuses'.
+func (c *Controller) pruneNodePoolStatusConfigMaps(statuses map[string]*nodePoolTunedStatus) error {
+	cms, err := c.listers.StatusConfigMaps.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list ConfigMaps in namespace %s: %v", ntoconfig.OperatorNamespace(), err)
+	}
+
+	for _, cm := range cms {
+		if _, ok := statuses[cm.Labels[hypershiftNodePoolLabel]]; ok {
+			continue
+		}
+		err = c.clients.ManagementKube.CoreV1().ConfigMaps(ntoconfig.OperatorNamespace()).Delete(context.TODO(), cm.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ConfigMap %s: %v", cm.Name, err)
+		}
+		klog.Infof("deleted ConfigMap %s", cm.Name)
+	}
+
+	return nil
+}
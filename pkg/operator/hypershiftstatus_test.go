@@ -0,0 +1,93 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestNodePoolTunedStatuses(t *testing.T) {
+	tunedCM := func(name, nodePool, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{hypershiftNodePoolLabel: "clusters/" + nodePool},
+			},
+			Data: map[string]string{tunedConfigMapConfigKey: data},
+		}
+	}
+	profile := func(name, tunedProfile, activeProfile string, applied, degraded corev1.ConditionStatus) *tunedv1.Profile {
+		p := &tunedv1.Profile{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		p.Spec.Config.TunedProfile = tunedProfile
+		p.Status.TunedProfile = activeProfile
+		p.Status.Conditions = []tunedv1.ProfileStatusCondition{
+			{Type: tunedv1.TunedProfileApplied, Status: applied},
+			{Type: tunedv1.TunedDegraded, Status: degraded},
+		}
+		return p
+	}
+
+	cms := []*corev1.ConfigMap{
+		tunedCM("tuned-b", "pool-a", "apiVersion: tuned.openshift.io/v1\nkind: Tuned\nmetadata:\n  name: b\n"),
+		tunedCM("tuned-a", "pool-a", "apiVersion: tuned.openshift.io/v1\nkind: Tuned\nmetadata:\n  name: a\n"),
+		tunedCM("tuned-invalid", "pool-b", "apiVersion: [\n"),
+	}
+	profileList := []*tunedv1.Profile{
+		profile("node-1", "profile-a", "profile-a", corev1.ConditionTrue, corev1.ConditionFalse),
+		profile("node-2", "profile-a", "profile-a", corev1.ConditionFalse, corev1.ConditionTrue),
+		profile("node-3", "profile-b", "profile-a", corev1.ConditionTrue, corev1.ConditionFalse), // not applied yet
+		profile("node-4", "profile-c", "profile-c", corev1.ConditionTrue, corev1.ConditionFalse),
+		profile("node-5", "profile-c", "profile-c", corev1.ConditionTrue, corev1.ConditionFalse), // no NodePool
+		profile("node-6", "profile-c", "profile-c", corev1.ConditionTrue, corev1.ConditionFalse), // Node not found
+	}
+	nodePools := map[string]string{
+		"node-1": "pool-a",
+		"node-2": "pool-a",
+		"node-3": "pool-a",
+		"node-4": "pool-c",
+		"node-5": "",
+	}
+
+	statuses := nodePoolTunedStatuses(cms, profileList, nodePools)
+
+	if len(statuses) != 3 {
+		t.Fatalf("want statuses of 3 NodePools, have %d: %v", len(statuses), statuses)
+	}
+
+	poolA := statuses["pool-a"]
+	if poolA == nil {
+		t.Fatalf("no status of NodePool pool-a")
+	}
+	if len(poolA.ConfigMaps) != 2 || poolA.ConfigMaps[0].Name != "tuned-a" || poolA.ConfigMaps[1].Name != "tuned-b" {
+		t.Errorf("want sorted ConfigMaps tuned-a, tuned-b of NodePool pool-a, have %+v", poolA.ConfigMaps)
+	}
+	if poolA.Nodes != 3 || poolA.Applied != 1 || poolA.Degraded != 1 {
+		t.Errorf("want 3 Nodes, 1 applied and 1 degraded in NodePool pool-a, have %d, %d and %d", poolA.Nodes, poolA.Applied, poolA.Degraded)
+	}
+	if expected := map[string]int{"profile-a": 2, "profile-b": 1}; !reflect.DeepEqual(poolA.Profiles, expected) {
+		t.Errorf("unexpected profiles of NodePool pool-a:\n\twant: %v\n\thave: %v", expected, poolA.Profiles)
+	}
+
+	poolB := statuses["pool-b"]
+	if poolB == nil {
+		t.Fatalf("no status of NodePool pool-b")
+	}
+	if len(poolB.ConfigMaps) != 1 || poolB.ConfigMaps[0].Error == "" {
+		t.Errorf("want a parse error reported for the ConfigMap of NodePool pool-b, have %+v", poolB.ConfigMaps)
+	}
+	if poolB.Nodes != 0 {
+		t.Errorf("want no Nodes in NodePool pool-b, have %d", poolB.Nodes)
+	}
+
+	poolC := statuses["pool-c"]
+	if poolC == nil {
+		t.Fatalf("no status of NodePool pool-c")
+	}
+	if poolC.Nodes != 1 || poolC.Applied != 1 || len(poolC.ConfigMaps) != 0 {
+		t.Errorf("unexpected status of NodePool pool-c: %+v", poolC)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -21,6 +23,8 @@ import (
 const (
 	// default Profile just in case default Tuned CR is inaccessible or incorrectly defined
 	defaultProfile = "openshift-node"
+	// name of the Pod informer index keyed by the name of the Node the Pod is scheduled on
+	podNodeNameIndex = "nodeName"
 )
 
 type tunedState struct {
@@ -34,12 +38,34 @@ type tunedState struct {
 	providerIDs map[string]string
 	// Node name:   ^^^^^^
 	// provider-id         ^^^^^^
+	podMatches map[string]string
+	// Node name:  ^^^^^^
+	// Namespace/podname of the Pod that selected the profile: ^^^^^^
+	scheduleWindows map[string]string
+	// Node name:       ^^^^^^
+	// active schedule window of the rule that selected the profile: ^^^^^^
+	scheduleNext map[string]time.Time
+	// Node name:    ^^^^^^
+	// next time the profile selection can change due to a schedule window: ^^^^^^
 }
 
 type ProfileCalculator struct {
 	listers *ntoclient.Listers
 	clients *ntoclient.Clients
 	state   tunedState
+
+	// Pod annotation names used by Tuned CRs.  Accessed from the Pod informer
+	// event handlers, hence the lock.
+	podAnnotationsLock sync.RWMutex
+	podAnnotations     map[string]bool
+
+	// Name of the rendered content revision the profiles are calculated from.
+	revision string
+	// Previous rendered content revision the profiles are calculated from while
+	// a rollback is requested; nil otherwise.
+	rollback *tunedv1.Tuned
+	// Tuned CRs reverted to their previous revision by their autoRevert policy.
+	reverted map[string]*tunedv1.Tuned
 }
 
 func NewProfileCalculator(listers *ntoclient.Listers, clients *ntoclient.Clients) *ProfileCalculator {
@@ -50,6 +76,10 @@ func NewProfileCalculator(listers *ntoclient.Listers, clients *ntoclient.Clients
 	pc.state.nodeLabels = map[string]map[string]string{}
 	pc.state.podLabels = map[string]map[string]map[string]string{}
 	pc.state.providerIDs = map[string]string{}
+	pc.state.podMatches = map[string]string{}
+	pc.state.scheduleWindows = map[string]string{}
+	pc.state.scheduleNext = map[string]time.Time{}
+	pc.podAnnotations = map[string]bool{}
 	return pc
 }
 
@@ -151,11 +181,18 @@ func (pc *ProfileCalculator) calculateProfile(nodeName string) (string, map[stri
 	var operand tunedv1.OperandConfig
 
 	klog.V(3).Infof("calculateProfile(%s)", nodeName)
+	delete(pc.state.podMatches, nodeName)
+	pc.scheduleReset(nodeName)
 	tunedList, err := pc.listers.TunedResources.List(labels.Everything())
 
 	if err != nil {
 		return "", nil, nil, operand, fmt.Errorf("failed to list Tuned: %v", err)
 	}
+	tunedList = pc.tunedsEffective(tunedList)
+	if pc.rollback != nil {
+		// Use the recommend rules of the revision we roll back to.
+		tunedList = []*tunedv1.Tuned{pc.rollback}
+	}
 
 	for _, recommend := range tunedRecommend(tunedList) {
 		var (
@@ -163,13 +200,27 @@ func (pc *ProfileCalculator) calculateProfile(nodeName string) (string, map[stri
 			node  *corev1.Node
 		)
 
+		if recommend.MachineConfigLabels != nil && len(recommend.Schedule) > 0 {
+			// Scheduled MachineConfigs would reboot the nodes at every window start and end.
+			klog.Errorf("ignoring scheduled recommend rule for profile %s with machineConfigLabels", *recommend.Profile)
+			continue
+		}
+
 		// Start with node/pod label based matching to MachineConfig matching when
 		// both the match section and MachineConfigLabels are specified.
 		// Also note the catch-all functionality when "recommend.Match == nil",
 		// we do not want to call profileMatches() in that case unless machineConfigLabels
 		// is undefined.
-		if (recommend.Match != nil || recommend.MachineConfigLabels == nil) && pc.profileMatches(recommend.Match, nodeName) {
-			return *recommend.Profile, nil, nil, recommend.Operand, nil
+		if recommend.Match != nil || recommend.MachineConfigLabels == nil {
+			if matches, podNsName := pc.profileMatches(recommend.Match, nodeName); matches {
+				active, window := pc.scheduleActive(nodeName, recommend.Schedule)
+				if !active {
+					continue
+				}
+				pc.podMatchesSet(nodeName, podNsName)
+				pc.scheduleWindowSet(nodeName, window)
+				return pc.tenantProfilesMerge(nodeName, *recommend.Profile), nil, nil, recommend.Operand, nil
+			}
 		}
 
 		if recommend.MachineConfigLabels == nil {
@@ -195,7 +246,7 @@ func (pc *ProfileCalculator) calculateProfile(nodeName string) (string, map[stri
 
 		// MachineConfigLabels based matching
 		if pc.machineConfigLabelsMatch(recommend.MachineConfigLabels, pools) {
-			return *recommend.Profile, recommend.MachineConfigLabels, pools, recommend.Operand, nil
+			return pc.tenantProfilesMerge(nodeName, *recommend.Profile), recommend.MachineConfigLabels, pools, recommend.Operand, nil
 		}
 	}
 
@@ -220,6 +271,8 @@ func (pc *ProfileCalculator) calculateProfileHyperShift(nodeName string) (string
 	var operand tunedv1.OperandConfig
 
 	klog.V(3).Infof("calculateProfileHyperShift(%s)", nodeName)
+	delete(pc.state.podMatches, nodeName)
+	pc.scheduleReset(nodeName)
 
 	node, err := pc.listers.Nodes.Get(nodeName)
 	if err != nil {
@@ -244,20 +297,33 @@ func (pc *ProfileCalculator) calculateProfileHyperShift(nodeName string) (string
 	if err != nil {
 		return defaultProfile, "", operand, fmt.Errorf("failed to get Tuned %s: %v", tunedv1.TunedDefaultResourceName, err)
 	}
-	tunedList = append(tunedList, defaultTuned)
+	tunedList = pc.tunedsEffective(append(tunedList, defaultTuned))
 
 	for _, recommend := range tunedRecommend(tunedList) {
 		// Start with node/pod label based matching
-		if recommend.Match != nil && pc.profileMatches(recommend.Match, nodeName) {
-			klog.V(2).Infof("calculateProfileHyperShift: node / pod label matching used. node: %s, tunedProfileName: %s, nodePoolName: %s, operand: %v", nodeName, *recommend.Profile, "", recommend.Operand)
-			return *recommend.Profile, "", recommend.Operand, nil
+		if recommend.Match != nil {
+			if matches, podNsName := pc.profileMatches(recommend.Match, nodeName); matches {
+				active, window := pc.scheduleActive(nodeName, recommend.Schedule)
+				if !active {
+					continue
+				}
+				pc.scheduleWindowSet(nodeName, window)
+				klog.V(2).Infof("calculateProfileHyperShift: node / pod label matching used. node: %s, tunedProfileName: %s, nodePoolName: %s, operand: %v", nodeName, *recommend.Profile, "", recommend.Operand)
+				pc.podMatchesSet(nodeName, podNsName)
+				return pc.tenantProfilesMerge(nodeName, *recommend.Profile), "", recommend.Operand, nil
+			}
 		}
 
 		// If recommend.Match is empty, NodePool based matching is assumed
 		// or this is the default profile
 		if recommend.Match == nil {
+			active, window := pc.scheduleActive(nodeName, recommend.Schedule)
+			if !active {
+				continue
+			}
+			pc.scheduleWindowSet(nodeName, window)
 			klog.V(2).Infof("calculateProfileHyperShift: NodePool based matching used. node: %s, tunedProfileName:  %s, nodePoolName: %s", nodeName, *recommend.Profile, nodePoolName)
-			return *recommend.Profile, nodePoolName, recommend.Operand, nil
+			return pc.tenantProfilesMerge(nodeName, *recommend.Profile), nodePoolName, recommend.Operand, nil
 		}
 	}
 
@@ -266,32 +332,42 @@ func (pc *ProfileCalculator) calculateProfileHyperShift(nodeName string) (string
 
 // profileMatches returns true, if Node 'nodeName' fulfills all the necessary
 // requirements of TunedMatch's tree-like definition of profile matching
-// rules 'match'.
-func (pc *ProfileCalculator) profileMatches(match []tunedv1.TunedMatch, nodeName string) bool {
+// rules 'match'.  When a Pod label or Pod annotation rule took part in the
+// match, the namespace/name of the (first) matching Pod is returned as well.
+func (pc *ProfileCalculator) profileMatches(match []tunedv1.TunedMatch, nodeName string) (bool, string) {
 	if len(match) == 0 {
 		// Empty catch-all profile with no Node/Pod labels
-		return true
+		return true, ""
 	}
 
 	for _, m := range match {
-		var labelMatches bool
+		var (
+			labelMatches bool
+			podNsName    string
+		)
 
-		if m.Type != nil && *m.Type == "pod" { // note the (lower-)case from the API
-			labelMatches = pc.podLabelMatches(m.Label, m.Value, nodeName)
-		} else {
-			// Assume "node" type match; no types other than "node"/"pod" are allowed.
+		switch {
+		case m.Type != nil && *m.Type == "pod": // note the (lower-)case from the API
+			labelMatches, podNsName = pc.podLabelMatches(m.Label, m.Value, nodeName)
+		case m.Type != nil && *m.Type == "podAnnotation":
+			labelMatches, podNsName = pc.podAnnotationMatches(m.Label, m.Value, nodeName)
+		default:
+			// Assume "node" type match; no types other than "node"/"pod"/"podAnnotation" are allowed.
 			// Unspecified m.Type means "node" type match.
 			labelMatches = pc.nodeLabelMatches(m.Label, m.Value, nodeName)
 		}
 		if labelMatches {
 			// AND condition, check if subtree matches too
-			if pc.profileMatches(m.Match, nodeName) {
-				return true
+			if matches, subtreePodNsName := pc.profileMatches(m.Match, nodeName); matches {
+				if podNsName == "" {
+					podNsName = subtreePodNsName
+				}
+				return true, podNsName
 			}
 		}
 	}
 
-	return false
+	return false, ""
 }
 
 // nodeLabelMatches returns true if Node label's 'mNodeLabel' value 'mNodeLabelValue'
@@ -319,28 +395,74 @@ func (pc *ProfileCalculator) nodeLabelMatches(mNodeLabel *string, mNodeLabelValu
 
 // podLabelMatches returns true if Pod label's 'mPodLabel' value 'mPodLabelValue'
 // matches any of the Pod labels in the ProfileCalculator internal data structures
-// for any Pod associated with Node of the name 'mNodeName'.
-func (pc *ProfileCalculator) podLabelMatches(mPodLabel *string, mPodLabelValue *string, mNodeName string) bool {
+// for any Pod associated with Node of the name 'mNodeName'.  The namespace/name
+// of the matching Pod is returned as well.
+func (pc *ProfileCalculator) podLabelMatches(mPodLabel *string, mPodLabelValue *string, mNodeName string) (bool, string) {
 	if mPodLabel == nil {
 		// Undefined Pod label matches
-		return true
+		return true, ""
 	}
 
 	podsPerNode := pc.state.podLabels[mNodeName]
 
-	for _, podLabels := range podsPerNode {
+	for podNsName, podLabels := range podsPerNode {
 		for podLabel, podLabelValue := range podLabels {
 			if podLabel == *mPodLabel {
 				if mPodLabelValue == nil || (podLabelValue == *mPodLabelValue) {
 					// Undefined Pod label value matches
-					return true
+					return true, podNsName
 				}
 				// Pod label value did not match, check the remaining pods on mNodeName
 			}
 		}
 	}
 
-	return false
+	return false, ""
+}
+
+// podAnnotationMatches returns true if Pod annotation's 'mPodAnnotation' value
+// 'mPodAnnotationValue' matches any of the annotations of the Pods scheduled on
+// Node of the name 'mNodeName'.  Unlike podLabelMatches, no per-Pod state is
+// kept by the ProfileCalculator; the Pods are looked up by the Pod informer's
+// Node name index.  The namespace/name of the matching Pod is returned as well.
+func (pc *ProfileCalculator) podAnnotationMatches(mPodAnnotation *string, mPodAnnotationValue *string, mNodeName string) (bool, string) {
+	if mPodAnnotation == nil {
+		// Undefined Pod annotation matches
+		return true, ""
+	}
+
+	if pc.listers.PodIndexer == nil {
+		// Pod informer not running (yet)
+		return false, ""
+	}
+
+	objs, err := pc.listers.PodIndexer.ByIndex(podNodeNameIndex, mNodeName)
+	if err != nil {
+		klog.Errorf("failed to list Pods on Node %s: %v", mNodeName, err)
+		return false, ""
+	}
+
+	var matches []string
+	for _, obj := range objs {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || podTerminated(pod) {
+			continue
+		}
+		v, ok := pod.Annotations[*mPodAnnotation]
+		if !ok {
+			continue
+		}
+		if mPodAnnotationValue == nil || v == *mPodAnnotationValue {
+			matches = append(matches, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	if len(matches) == 0 {
+		return false, ""
+	}
+	// Report the same Pod for repeated calculations regardless of the indexer ordering.
+	sort.Strings(matches)
+
+	return true, matches[0]
 }
 
 // machineConfigLabelsMatch returns true if any of the MachineConfigPools 'pools' select 'machineConfigLabels' labels.
@@ -415,6 +537,10 @@ func (pc *ProfileCalculator) nodeRemove(nodeName string) {
 
 	// Delete all data structures related to nodeName in podLabels
 	delete(pc.state.podLabels, nodeName)
+
+	delete(pc.state.podMatches, nodeName)
+
+	pc.scheduleReset(nodeName)
 }
 
 // podRemove removes the reference of a Pod identified by namespace/name
@@ -454,6 +580,16 @@ func (pc *ProfileCalculator) nodeLabelsDelete() {
 	pc.state.nodeLabels = map[string]map[string]string{}
 }
 
+// podMatchesSet records Pod 'podNsName' as the Pod that selected the profile
+// for Node 'nodeName'.
+func (pc *ProfileCalculator) podMatchesSet(nodeName string, podNsName string) {
+	if podNsName == "" {
+		delete(pc.state.podMatches, nodeName)
+		return
+	}
+	pc.state.podMatches[nodeName] = podNsName
+}
+
 // tunedUsesNodeLabels returns true if any of the TunedMatch's tree-like definition
 // of profile matching rules 'match' uses Node labels.
 func (pc *ProfileCalculator) tunedUsesNodeLabels(match []tunedv1.TunedMatch) bool {
@@ -516,6 +652,95 @@ func (pc *ProfileCalculator) tunedsUsePodLabels(tunedSlice []*tunedv1.Tuned) boo
 	return false
 }
 
+// tunedPodAnnotations adds names of all Pod annotations used by TunedMatch's
+// tree-like definition of profile matching rules 'match' to 'annotations'.
+func (pc *ProfileCalculator) tunedPodAnnotations(match []tunedv1.TunedMatch, annotations map[string]bool) {
+	for _, m := range match {
+		if m.Type != nil && *m.Type == "podAnnotation" && m.Label != nil {
+			annotations[*m.Label] = true
+		}
+		pc.tunedPodAnnotations(m.Match, annotations)
+	}
+}
+
+// tunedsPodAnnotations returns a set of Pod annotation names used by the Tuned CRs.
+func (pc *ProfileCalculator) tunedsPodAnnotations(tunedSlice []*tunedv1.Tuned) map[string]bool {
+	annotations := map[string]bool{}
+	for _, recommend := range tunedRecommend(tunedSlice) {
+		pc.tunedPodAnnotations(recommend.Match, annotations)
+	}
+	return annotations
+}
+
+// podAnnotationsSet stores the Pod annotation names used by the Tuned CRs.
+// Returns true if the set of Pod annotation names changed.
+func (pc *ProfileCalculator) podAnnotationsSet(annotations map[string]bool) bool {
+	pc.podAnnotationsLock.Lock()
+	defer pc.podAnnotationsLock.Unlock()
+
+	if len(annotations) == len(pc.podAnnotations) {
+		change := false
+		for a := range annotations {
+			if !pc.podAnnotations[a] {
+				change = true
+				break
+			}
+		}
+		if !change {
+			return false
+		}
+	}
+	pc.podAnnotations = annotations
+
+	return true
+}
+
+// podAnnotated returns true if Pod 'pod' carries any of the Pod annotations
+// used by the Tuned CRs.
+func (pc *ProfileCalculator) podAnnotated(pod *corev1.Pod) bool {
+	pc.podAnnotationsLock.RLock()
+	defer pc.podAnnotationsLock.RUnlock()
+
+	for a := range pc.podAnnotations {
+		if _, ok := pod.Annotations[a]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// podAnnotationsChange returns true if the change from Pod 'podOld' to Pod
+// 'podNew' can affect the Pod annotation based profile matching.  Only the
+// annotations used by the Tuned CRs, Pod (re)scheduling and termination are
+// considered.
+func (pc *ProfileCalculator) podAnnotationsChange(podOld, podNew *corev1.Pod) bool {
+	annotatedOld := pc.podAnnotated(podOld)
+	annotatedNew := pc.podAnnotated(podNew)
+
+	if !annotatedOld && !annotatedNew {
+		// The vast majority of Pod updates, do not trigger profile calculations.
+		return false
+	}
+
+	if annotatedOld != annotatedNew ||
+		podOld.Spec.NodeName != podNew.Spec.NodeName ||
+		podTerminated(podOld) != podTerminated(podNew) {
+		return true
+	}
+
+	pc.podAnnotationsLock.RLock()
+	defer pc.podAnnotationsLock.RUnlock()
+
+	for a := range pc.podAnnotations {
+		if podOld.Annotations[a] != podNew.Annotations[a] {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getNodePoolNameForNode returns the NodePool name from a label on the hosted cluster Node
 func (pc *ProfileCalculator) getNodePoolNameForNode(node *corev1.Node) (string, error) {
 	nodePoolName := node.GetLabels()[hypershiftNodePoolLabel]
@@ -566,6 +791,47 @@ func tunedRecommend(tunedSlice []*tunedv1.Tuned) []tunedv1.TunedRecommend {
 	return recommendAll
 }
 
+// podTerminated returns true if Pod 'pod' reached a terminal phase and its
+// containers no longer run on the Node.
+func podTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// podNodeNameIndexFunc indexes Pods by the name of the Node they are scheduled on.
+func podNodeNameIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return []string{}, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// podAnnotationTransform strips Pod objects of everything the Pod annotation
+// based profile matching does not need to reduce the Pod informer cache size.
+func podAnnotationTransform(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		// For example cache.DeletedFinalStateUnknown
+		return obj, nil
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+			Annotations:     pod.Annotations,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: pod.Spec.NodeName,
+		},
+		Status: corev1.PodStatus{
+			Phase: pod.Status.Phase,
+		},
+	}, nil
+}
+
 // podLabelsUnique goes through Pod labels of all the Pods on a Node-wide
 // 'podLabelsNodeWide' map and returns a subset of 'podLabels' unique to 'podNsName'
 // Pod; i.e. the retuned labels (key & value) will not exist on any other Pod
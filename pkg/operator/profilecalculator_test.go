@@ -0,0 +1,285 @@
+package operator
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
+)
+
+const testPodAnnotation = "tuned.openshift.io/profile"
+
+func testAnnotatedPod(name, nodeName string, annotations map[string]string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        name,
+			Annotations: annotations,
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func testPodAnnotationCalculator(t *testing.T, pods ...*corev1.Pod) *ProfileCalculator {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{podNodeNameIndex: podNodeNameIndexFunc})
+	for _, pod := range pods {
+		if err := indexer.Add(pod); err != nil {
+			t.Fatalf("failed to add Pod %s: %v", pod.Name, err)
+		}
+	}
+	pc := NewProfileCalculator(&ntoclient.Listers{PodIndexer: indexer}, nil)
+	pc.podAnnotationsSet(map[string]bool{testPodAnnotation: true})
+	return pc
+}
+
+func TestPodAnnotationMatches(t *testing.T) {
+	annotation := testPodAnnotation
+	value := "realtime"
+	otherValue := "throughput"
+
+	var tests = []struct {
+		name            string
+		pods            []*corev1.Pod
+		annotation      *string
+		value           *string
+		expectedMatch   bool
+		expectedPodName string
+	}{
+		{
+			name:          "undefined annotation",
+			annotation:    nil,
+			expectedMatch: true,
+		},
+		{
+			name:            "annotation and value match",
+			pods:            []*corev1.Pod{testAnnotatedPod("pod1", "node1", map[string]string{annotation: value}, corev1.PodRunning)},
+			annotation:      &annotation,
+			value:           &value,
+			expectedMatch:   true,
+			expectedPodName: "default/pod1",
+		},
+		{
+			name:            "annotation matches any value",
+			pods:            []*corev1.Pod{testAnnotatedPod("pod1", "node1", map[string]string{annotation: otherValue}, corev1.PodRunning)},
+			annotation:      &annotation,
+			expectedMatch:   true,
+			expectedPodName: "default/pod1",
+		},
+		{
+			name:       "value does not match",
+			pods:       []*corev1.Pod{testAnnotatedPod("pod1", "node1", map[string]string{annotation: otherValue}, corev1.PodRunning)},
+			annotation: &annotation,
+			value:      &value,
+		},
+		{
+			name:       "annotation does not match",
+			pods:       []*corev1.Pod{testAnnotatedPod("pod1", "node1", map[string]string{"other": value}, corev1.PodRunning)},
+			annotation: &annotation,
+			value:      &value,
+		},
+		{
+			name: "terminated pods do not match",
+			pods: []*corev1.Pod{
+				testAnnotatedPod("pod1", "node1", map[string]string{annotation: value}, corev1.PodSucceeded),
+				testAnnotatedPod("pod2", "node1", map[string]string{annotation: value}, corev1.PodFailed),
+			},
+			annotation: &annotation,
+			value:      &value,
+		},
+		{
+			name:       "pods on other nodes do not match",
+			pods:       []*corev1.Pod{testAnnotatedPod("pod1", "node2", map[string]string{annotation: value}, corev1.PodRunning)},
+			annotation: &annotation,
+			value:      &value,
+		},
+		{
+			name: "first matching pod by name",
+			pods: []*corev1.Pod{
+				testAnnotatedPod("pod3", "node1", map[string]string{annotation: value}, corev1.PodRunning),
+				testAnnotatedPod("pod1", "node1", map[string]string{annotation: value}, corev1.PodSucceeded),
+				testAnnotatedPod("pod2", "node1", map[string]string{annotation: value}, corev1.PodRunning),
+			},
+			annotation:      &annotation,
+			value:           &value,
+			expectedMatch:   true,
+			expectedPodName: "default/pod2",
+		},
+	}
+
+	for _, tc := range tests {
+		pc := testPodAnnotationCalculator(t, tc.pods...)
+		match, podName := pc.podAnnotationMatches(tc.annotation, tc.value, "node1")
+		if match != tc.expectedMatch || podName != tc.expectedPodName {
+			t.Errorf("%s: want %t %q, have %t %q", tc.name, tc.expectedMatch, tc.expectedPodName, match, podName)
+		}
+	}
+}
+
+func TestPodAnnotationMatchesNoInformer(t *testing.T) {
+	annotation := testPodAnnotation
+	pc := NewProfileCalculator(&ntoclient.Listers{}, nil)
+	if match, _ := pc.podAnnotationMatches(&annotation, nil, "node1"); match {
+		t.Errorf("want no match without the Pod informer")
+	}
+}
+
+func TestPodAnnotationsChange(t *testing.T) {
+	annotated := map[string]string{testPodAnnotation: "realtime"}
+
+	var tests = []struct {
+		name           string
+		podOld         *corev1.Pod
+		podNew         *corev1.Pod
+		expectedChange bool
+	}{
+		{
+			name:   "unannotated pod",
+			podOld: testAnnotatedPod("pod1", "node1", map[string]string{"other": "a"}, corev1.PodRunning),
+			podNew: testAnnotatedPod("pod1", "node2", map[string]string{"other": "b"}, corev1.PodFailed),
+		},
+		{
+			name:   "annotated pod unchanged",
+			podOld: testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			podNew: testAnnotatedPod("pod1", "node1", map[string]string{testPodAnnotation: "realtime", "other": "a"}, corev1.PodRunning),
+		},
+		{
+			name:           "annotation added",
+			podOld:         testAnnotatedPod("pod1", "node1", nil, corev1.PodRunning),
+			podNew:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			expectedChange: true,
+		},
+		{
+			name:           "annotation removed",
+			podOld:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			podNew:         testAnnotatedPod("pod1", "node1", nil, corev1.PodRunning),
+			expectedChange: true,
+		},
+		{
+			name:           "annotation value changed",
+			podOld:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			podNew:         testAnnotatedPod("pod1", "node1", map[string]string{testPodAnnotation: "throughput"}, corev1.PodRunning),
+			expectedChange: true,
+		},
+		{
+			name:           "pod scheduled",
+			podOld:         testAnnotatedPod("pod1", "", annotated, corev1.PodPending),
+			podNew:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodPending),
+			expectedChange: true,
+		},
+		{
+			name:           "pod reassigned to another node",
+			podOld:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			podNew:         testAnnotatedPod("pod1", "node2", annotated, corev1.PodRunning),
+			expectedChange: true,
+		},
+		{
+			name:           "pod terminated",
+			podOld:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning),
+			podNew:         testAnnotatedPod("pod1", "node1", annotated, corev1.PodSucceeded),
+			expectedChange: true,
+		},
+		{
+			name:   "terminated pod phase change",
+			podOld: testAnnotatedPod("pod1", "node1", annotated, corev1.PodSucceeded),
+			podNew: testAnnotatedPod("pod1", "node1", annotated, corev1.PodFailed),
+		},
+	}
+
+	pc := testPodAnnotationCalculator(t)
+	for _, tc := range tests {
+		if change := pc.podAnnotationsChange(tc.podOld, tc.podNew); change != tc.expectedChange {
+			t.Errorf("%s: want %t, have %t", tc.name, tc.expectedChange, change)
+		}
+	}
+}
+
+// fakeDelayingQueue records the names of the objects added with a delay.
+type fakeDelayingQueue struct {
+	workqueue.RateLimitingInterface
+	added []string
+}
+
+func (q *fakeDelayingQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.added = append(q.added, item.(wqKey).name)
+}
+
+func TestPodAnnotationEventHandler(t *testing.T) {
+	annotated := map[string]string{testPodAnnotation: "realtime"}
+	podRunning := testAnnotatedPod("pod1", "node1", annotated, corev1.PodRunning)
+
+	var tests = []struct {
+		name          string
+		event         func(h cache.ResourceEventHandlerFuncs)
+		expectedNodes []string
+	}{
+		{
+			name: "annotated pod added",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnAdd(podRunning)
+			},
+			expectedNodes: []string{"node1"},
+		},
+		{
+			name: "unannotated pod added",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnAdd(testAnnotatedPod("pod1", "node1", nil, corev1.PodRunning))
+			},
+		},
+		{
+			name: "unscheduled pod added",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnAdd(testAnnotatedPod("pod1", "", annotated, corev1.PodPending))
+			},
+		},
+		{
+			name: "annotated pod reassigned to another node",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnUpdate(podRunning, testAnnotatedPod("pod1", "node2", annotated, corev1.PodRunning))
+			},
+			expectedNodes: []string{"node1", "node2"},
+		},
+		{
+			name: "annotated pod terminated",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnUpdate(podRunning, testAnnotatedPod("pod1", "node1", annotated, corev1.PodSucceeded))
+			},
+			expectedNodes: []string{"node1"},
+		},
+		{
+			name: "annotated pod status update",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				podReady := podRunning.DeepCopy()
+				podReady.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+				h.OnUpdate(podRunning, podReady)
+			},
+		},
+		{
+			name: "annotated pod deleted",
+			event: func(h cache.ResourceEventHandlerFuncs) {
+				h.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/pod1", Obj: podRunning})
+			},
+			expectedNodes: []string{"node1"},
+		},
+	}
+
+	for _, tc := range tests {
+		queue := &fakeDelayingQueue{}
+		c := &Controller{
+			pc:        testPodAnnotationCalculator(t),
+			workqueue: queue,
+		}
+		tc.event(c.podAnnotationEventHandler())
+		sort.Strings(queue.added)
+		if !reflect.DeepEqual(queue.added, tc.expectedNodes) {
+			t.Errorf("%s: want Profiles %v enqueued, have %v", tc.name, tc.expectedNodes, queue.added)
+		}
+	}
+}
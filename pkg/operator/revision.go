@@ -0,0 +1,182 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+)
+
+const (
+	// maximum number of rendered content revisions kept as ControllerRevisions
+	revisionHistoryLimit = 10
+	// label on ControllerRevisions denoting the revision history they belong to
+	revisionHistoryLabel = "tuned.openshift.io/revision-history"
+	// revision history of the rendered content
+	revisionHistoryRendered = tunedv1.TunedRenderedResourceName
+	// revision histories of individual Tuned CRs with an autoRevert policy
+	revisionHistoryTuned = "tuned"
+	// annotation on ControllerRevisions holding the time the revision became the newest one
+	revisionTimestampAnnotation = "tuned.openshift.io/revision-timestamp"
+)
+
+// revisionName returns the name of the ControllerRevision for content 'data'
+// of Tuned 'owner' in revision history 'history'.
+func revisionName(history string, owner *tunedv1.Tuned, data []byte) string {
+	hf := fnv.New32a()
+	hf.Write([]byte(owner.UID))
+	hf.Write(data)
+	return fmt.Sprintf("%s-%s", history, rand.SafeEncodeString(fmt.Sprint(hf.Sum32())))
+}
+
+// revisionTimestamp returns the time ControllerRevision 'cr' became the newest revision.
+func revisionTimestamp(cr *appsv1.ControllerRevision) time.Time {
+	if t, err := time.Parse(time.RFC3339, cr.Annotations[revisionTimestampAnnotation]); err == nil {
+		return t
+	}
+	return cr.CreationTimestamp.Time
+}
+
+// revisionTuned decodes ControllerRevision 'cr' into a Tuned object.
+func revisionTuned(cr *appsv1.ControllerRevision) (*tunedv1.Tuned, error) {
+	tuned := &tunedv1.Tuned{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cr.Name,
+			Namespace: cr.Namespace,
+		},
+	}
+	if err := json.Unmarshal(cr.Data.Raw, &tuned.Spec); err != nil {
+		return nil, fmt.Errorf("failed to decode ControllerRevision %s: %v", cr.Name, err)
+	}
+	return tuned, nil
+}
+
+// revisionsList returns the revisions of Tuned 'owner' in revision history
+// 'history' sorted from the oldest to the newest.
+func (c *Controller) revisionsList(history string, owner *tunedv1.Tuned) ([]*appsv1.ControllerRevision, error) {
+	list, err := c.listers.ControllerRevisions.List(labels.SelectorFromSet(labels.Set{revisionHistoryLabel: history}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ControllerRevisions: %v", err)
+	}
+	revisions := []*appsv1.ControllerRevision{}
+	for _, cr := range list {
+		if metav1.IsControlledBy(cr, owner) {
+			revisions = append(revisions, cr)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// revisionGet returns the rendered content revision 'revision' identified
+// either by its ControllerRevision name or by its revision number.
+func (c *Controller) revisionGet(tuned *tunedv1.Tuned, revision string) (*appsv1.ControllerRevision, error) {
+	revisions, err := c.revisionsList(revisionHistoryRendered, tuned)
+	if err != nil {
+		return nil, err
+	}
+	for _, cr := range revisions {
+		if cr.Name == revision || strconv.FormatInt(cr.Revision, 10) == revision {
+			return cr, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %s not found in the last %d revisions", revision, revisionHistoryLimit)
+}
+
+// rollbackTuned returns the rendered content of revision 'revision' of the
+// default Tuned 'tuned' to roll back to.
+func (c *Controller) rollbackTuned(tuned *tunedv1.Tuned, revision string) (*tunedv1.Tuned, error) {
+	cr, err := c.revisionGet(tuned, revision)
+	if err != nil {
+		return nil, err
+	}
+	return revisionTuned(cr)
+}
+
+// syncRevision records content 'spec' of Tuned 'owner' as the newest
+// ControllerRevision in revision history 'history' and prunes the history down
+// to revisionHistoryLimit revisions.  The content of the rendered revision
+// history are the rendered TuneD profiles and the priority-sorted recommend
+// rules they were rendered from.
+// Returns the name of the ControllerRevision and an error if any.
+func (c *Controller) syncRevision(owner *tunedv1.Tuned, history string, spec tunedv1.TunedSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Tuned content: %v", err)
+	}
+	name := revisionName(history, owner, data)
+
+	revisions, err := c.revisionsList(history, owner)
+	if err != nil {
+		return "", err
+	}
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	cr, err := c.listers.ControllerRevisions.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get ControllerRevision %s: %v", name, err)
+		}
+		cr = &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       ntoconfig.WatchNamespace(),
+				Labels:          map[string]string{revisionHistoryLabel: history},
+				Annotations:     map[string]string{revisionTimestampAnnotation: time.Now().UTC().Format(time.RFC3339)},
+				OwnerReferences: getDefaultTunedRefs(owner),
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: next,
+		}
+		_, err = c.clients.Apps.ControllerRevisions(ntoconfig.WatchNamespace()).Create(context.TODO(), cr, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			// Created by a previous sync, the informer cache is not up-to-date yet.
+			return name, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create ControllerRevision %s: %v", name, err)
+		}
+		klog.Infof("created ControllerRevision %s (revision %d)", name, next)
+		revisions = append(revisions, cr)
+	} else if cr.Revision != revisions[len(revisions)-1].Revision {
+		// Previously rendered content is rendered again, make it the newest revision.
+		cr = cr.DeepCopy() // never update the objects from cache
+		cr.Revision = next
+		setAnnotation(&cr.ObjectMeta, revisionTimestampAnnotation, time.Now().UTC().Format(time.RFC3339))
+		_, err = c.clients.Apps.ControllerRevisions(ntoconfig.WatchNamespace()).Update(context.TODO(), cr, metav1.UpdateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to update ControllerRevision %s: %v", name, err)
+		}
+		klog.Infof("updated ControllerRevision %s (revision %d)", name, next)
+		return name, nil
+	}
+
+	for i := 0; i < len(revisions)-revisionHistoryLimit; i++ {
+		err = c.clients.Apps.ControllerRevisions(ntoconfig.WatchNamespace()).Delete(context.TODO(), revisions[i].Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return name, fmt.Errorf("failed to delete ControllerRevision %s: %v", revisions[i].Name, err)
+		}
+		klog.Infof("deleted ControllerRevision %s (revision %d)", revisions[i].Name, revisions[i].Revision)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,75 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestRevisionName(t *testing.T) {
+	owner := &tunedv1.Tuned{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid-1"}}
+	otherOwner := &tunedv1.Tuned{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid-2"}}
+
+	name := revisionName(revisionHistoryRendered, owner, []byte("a"))
+	if name != revisionName(revisionHistoryRendered, owner, []byte("a")) {
+		t.Errorf("revision name of the same content is not stable")
+	}
+	if name == revisionName(revisionHistoryRendered, owner, []byte("b")) {
+		t.Errorf("revision names of different content collide")
+	}
+	if name == revisionName(revisionHistoryRendered, otherOwner, []byte("a")) {
+		t.Errorf("revision names of different owners collide")
+	}
+}
+
+func TestRevisionTimestamp(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		annotation string
+		expected   time.Time
+	}{
+		{
+			annotation: "2026-02-01T10:00:00Z",
+			expected:   time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			// falls back to the creation timestamp
+			annotation: "invalid",
+			expected:   created,
+		},
+	}
+
+	for i, tc := range tests {
+		cr := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations:       map[string]string{revisionTimestampAnnotation: tc.annotation},
+		}}
+		if ts := revisionTimestamp(cr); !ts.Equal(tc.expected) {
+			t.Errorf("failed test case %d:\n\twant: %v\n\thave: %v", i+1, tc.expected, ts)
+		}
+	}
+}
+
+func TestRevisionTuned(t *testing.T) {
+	cr := &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "rendered-abc", Namespace: "ns"},
+		Data:       runtime.RawExtension{Raw: []byte(`{"profile":[{"name":"p","data":"[main]"}]}`)},
+	}
+	tuned, err := revisionTuned(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tuned.Name != cr.Name || len(tuned.Spec.Profile) != 1 || *tuned.Spec.Profile[0].Name != "p" {
+		t.Errorf("unexpected Tuned decoded: %+v", tuned)
+	}
+
+	cr.Data.Raw = []byte(`{`)
+	if _, err := revisionTuned(cr); err == nil {
+		t.Errorf("expected an error decoding invalid revision data")
+	}
+}
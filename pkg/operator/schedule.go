@@ -0,0 +1,172 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // IANA time zones for schedule windows regardless of the host's zoneinfo
+
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+// scheduleDays maps the schedule window days to time.Weekday.
+var scheduleDays = map[tunedv1.TunedScheduleDay]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// scheduleWindowString returns a human-readable representation of schedule
+// window 'w', such as "Mon,Tue 22:00-06:00 Europe/Prague".
+func scheduleWindowString(w tunedv1.TunedScheduleWindow) string {
+	var sb strings.Builder
+
+	for i, day := range w.Days {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(string(day))
+	}
+	if len(w.Days) > 0 {
+		sb.WriteString(" ")
+	}
+	tz := w.TimeZone
+	if tz == "" {
+		tz = "UTC"
+	}
+	fmt.Fprintf(&sb, "%s-%s %s", w.Start, w.End, tz)
+
+	return sb.String()
+}
+
+// scheduleClock parses time of day 'hhmm' in the 24-hour HH:MM format.
+func scheduleClock(hhmm string) (int, int, error) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q: %v", hhmm, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// scheduleWindowEval evaluates schedule window 'w' at time 'now'.
+//
+// Returns
+// * whether the window is active at 'now'
+// * the next time after 'now' the window starts or ends
+// * an error if any
+func scheduleWindowEval(w tunedv1.TunedScheduleWindow, now time.Time) (bool, time.Time, error) {
+	var (
+		active bool
+		next   time.Time
+	)
+
+	tz := w.TimeZone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return false, next, fmt.Errorf("invalid time zone %q: %v", tz, err)
+	}
+	startHour, startMin, err := scheduleClock(w.Start)
+	if err != nil {
+		return false, next, err
+	}
+	endHour, endMin, err := scheduleClock(w.End)
+	if err != nil {
+		return false, next, err
+	}
+	days := map[time.Weekday]bool{}
+	for _, day := range w.Days {
+		wd, ok := scheduleDays[day]
+		if !ok {
+			return false, next, fmt.Errorf("invalid day %q", day)
+		}
+		days[wd] = true
+	}
+
+	nextSet := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	t := now.In(loc)
+	// A window started yesterday can still be active, the next start is at most a week away.
+	for d := -1; d <= 7; d++ {
+		start := time.Date(t.Year(), t.Month(), t.Day()+d, startHour, startMin, 0, 0, loc)
+		if len(days) > 0 && !days[start.Weekday()] {
+			continue
+		}
+		end := time.Date(t.Year(), t.Month(), t.Day()+d, endHour, endMin, 0, 0, loc)
+		if !end.After(start) {
+			// The window ends on the following day.
+			end = time.Date(t.Year(), t.Month(), t.Day()+d+1, endHour, endMin, 0, 0, loc)
+		}
+		if !now.Before(start) && now.Before(end) {
+			active = true
+		}
+		nextSet(start)
+		nextSet(end)
+	}
+
+	return active, next, nil
+}
+
+// scheduleActive evaluates the schedule windows 'schedule' of a profile
+// selection rule for Node 'nodeName' at the current time and records the
+// earliest time the rule's activity can change for the Node.
+//
+// Returns
+// * whether the rule applies, i.e. it has no schedule or any of its windows is active
+// * the active window
+func (pc *ProfileCalculator) scheduleActive(nodeName string, schedule []tunedv1.TunedScheduleWindow) (bool, string) {
+	var (
+		active bool
+		window string
+	)
+
+	if len(schedule) == 0 {
+		return true, ""
+	}
+
+	now := time.Now()
+	for _, w := range schedule {
+		wActive, wNext, err := scheduleWindowEval(w, now)
+		if err != nil {
+			klog.Errorf("ignoring schedule window %q: %v", scheduleWindowString(w), err)
+			continue
+		}
+		if wActive && !active {
+			active = true
+			window = scheduleWindowString(w)
+		}
+		if next, ok := pc.state.scheduleNext[nodeName]; !wNext.IsZero() && (!ok || wNext.Before(next)) {
+			pc.state.scheduleNext[nodeName] = wNext
+		}
+	}
+
+	return active, window
+}
+
+// scheduleWindowSet records schedule window 'window' as the active window of
+// the rule that selected the profile for Node 'nodeName'.
+func (pc *ProfileCalculator) scheduleWindowSet(nodeName string, window string) {
+	if window == "" {
+		delete(pc.state.scheduleWindows, nodeName)
+		return
+	}
+	pc.state.scheduleWindows[nodeName] = window
+}
+
+// scheduleReset removes the schedule state of Node 'nodeName'.
+func (pc *ProfileCalculator) scheduleReset(nodeName string) {
+	delete(pc.state.scheduleWindows, nodeName)
+	delete(pc.state.scheduleNext, nodeName)
+}
@@ -0,0 +1,193 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestScheduleWindowEval(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	var tests = []struct {
+		name           string
+		window         tunedv1.TunedScheduleWindow
+		now            time.Time
+		expectedActive bool
+		expectedNext   time.Time
+	}{
+		{
+			name:           "inside window",
+			window:         tunedv1.TunedScheduleWindow{Start: "09:00", End: "17:00"},
+			now:            utc(2026, 1, 2, 12, 0),
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 2, 17, 0),
+		},
+		{
+			name:           "before window",
+			window:         tunedv1.TunedScheduleWindow{Start: "09:00", End: "17:00"},
+			now:            utc(2026, 1, 2, 8, 0),
+			expectedActive: false,
+			expectedNext:   utc(2026, 1, 2, 9, 0),
+		},
+		{
+			name:           "start is inclusive",
+			window:         tunedv1.TunedScheduleWindow{Start: "09:00", End: "17:00"},
+			now:            utc(2026, 1, 2, 9, 0),
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 2, 17, 0),
+		},
+		{
+			name:           "end is exclusive",
+			window:         tunedv1.TunedScheduleWindow{Start: "09:00", End: "17:00"},
+			now:            utc(2026, 1, 2, 17, 0),
+			expectedActive: false,
+			expectedNext:   utc(2026, 1, 3, 9, 0),
+		},
+		{
+			name:           "crossing midnight, before midnight",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00"},
+			now:            utc(2026, 1, 2, 23, 0),
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 3, 6, 0),
+		},
+		{
+			name:           "crossing midnight, after midnight",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00"},
+			now:            utc(2026, 1, 2, 2, 0),
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 2, 6, 0),
+		},
+		{
+			name:           "crossing midnight, outside window",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00"},
+			now:            utc(2026, 1, 2, 12, 0),
+			expectedActive: false,
+			expectedNext:   utc(2026, 1, 2, 22, 0),
+		},
+		{
+			name:           "crossing midnight into a day not listed",
+			window:         tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Fri"}, Start: "22:00", End: "06:00"},
+			now:            utc(2026, 1, 3, 2, 0), // Saturday
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 3, 6, 0),
+		},
+		{
+			name:           "next start on a listed day",
+			window:         tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Fri"}, Start: "22:00", End: "06:00"},
+			now:            utc(2026, 1, 3, 23, 0), // Saturday
+			expectedActive: false,
+			expectedNext:   utc(2026, 1, 9, 22, 0),
+		},
+		{
+			name:           "day not listed",
+			window:         tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Mon"}, Start: "09:00", End: "17:00"},
+			now:            utc(2026, 1, 4, 12, 0), // Sunday
+			expectedActive: false,
+			expectedNext:   utc(2026, 1, 5, 9, 0),
+		},
+		{
+			name:           "equal start and end span a whole day",
+			window:         tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Fri"}, Start: "00:00", End: "00:00"},
+			now:            utc(2026, 1, 2, 23, 59),
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 3, 0, 0),
+		},
+		{
+			name:           "time zone",
+			window:         tunedv1.TunedScheduleWindow{Start: "09:00", End: "17:00", TimeZone: "America/New_York"},
+			now:            utc(2026, 1, 2, 14, 0), // 09:00 EST
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 2, 22, 0),
+		},
+		{
+			name:           "time zone day differs from UTC day",
+			window:         tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Fri"}, Start: "20:00", End: "23:00", TimeZone: "America/New_York"},
+			now:            utc(2026, 1, 3, 2, 0), // Friday 21:00 EST, Saturday in UTC
+			expectedActive: true,
+			expectedNext:   utc(2026, 1, 3, 4, 0),
+		},
+		{
+			name:           "spring forward inside a window crossing midnight",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00", TimeZone: "Europe/Prague"},
+			now:            utc(2026, 3, 29, 3, 30), // 05:30 CEST
+			expectedActive: true,
+			expectedNext:   utc(2026, 3, 29, 4, 0), // 06:00 CEST, the window lasted 7 hours
+		},
+		{
+			name:           "spring forward, window ended",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00", TimeZone: "Europe/Prague"},
+			now:            utc(2026, 3, 29, 4, 0), // 06:00 CEST
+			expectedActive: false,
+			expectedNext:   utc(2026, 3, 29, 20, 0), // 22:00 CEST
+		},
+		{
+			name:           "fall back inside a window crossing midnight",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00", TimeZone: "Europe/Prague"},
+			now:            utc(2026, 10, 25, 4, 30), // 05:30 CET
+			expectedActive: true,
+			expectedNext:   utc(2026, 10, 25, 5, 0), // 06:00 CET, the window lasted 9 hours
+		},
+		{
+			name:           "fall back, window ended",
+			window:         tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00", TimeZone: "Europe/Prague"},
+			now:            utc(2026, 10, 25, 5, 0), // 06:00 CET
+			expectedActive: false,
+			expectedNext:   utc(2026, 10, 25, 21, 0), // 22:00 CET
+		},
+	}
+
+	for _, tc := range tests {
+		active, next, err := scheduleWindowEval(tc.window, tc.now)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if active != tc.expectedActive {
+			t.Errorf("%s: want active %v, have %v", tc.name, tc.expectedActive, active)
+		}
+		if !next.Equal(tc.expectedNext) {
+			t.Errorf("%s: want next %v, have %v", tc.name, tc.expectedNext, next.UTC())
+		}
+	}
+}
+
+func TestScheduleWindowEvalErrors(t *testing.T) {
+	var tests = []tunedv1.TunedScheduleWindow{
+		{Start: "09:00", End: "17:00", TimeZone: "Mars/Olympus_Mons"},
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "24:00"},
+		{Days: []tunedv1.TunedScheduleDay{"Monday"}, Start: "09:00", End: "17:00"},
+	}
+
+	for i, w := range tests {
+		if _, _, err := scheduleWindowEval(w, time.Now()); err == nil {
+			t.Errorf("failed test case %d: want an error for window %q", i+1, scheduleWindowString(w))
+		}
+	}
+}
+
+func TestScheduleWindowString(t *testing.T) {
+	var tests = []struct {
+		window   tunedv1.TunedScheduleWindow
+		expected string
+	}{
+		{
+			window:   tunedv1.TunedScheduleWindow{Start: "22:00", End: "06:00"},
+			expected: "22:00-06:00 UTC",
+		},
+		{
+			window:   tunedv1.TunedScheduleWindow{Days: []tunedv1.TunedScheduleDay{"Mon", "Tue"}, Start: "22:00", End: "06:00", TimeZone: "Europe/Prague"},
+			expected: "Mon,Tue 22:00-06:00 Europe/Prague",
+		},
+	}
+
+	for i, tc := range tests {
+		if s := scheduleWindowString(tc.window); s != tc.expected {
+			t.Errorf("failed test case %d:\n\twant: %s\n\thave: %s", i+1, tc.expected, s)
+		}
+	}
+}
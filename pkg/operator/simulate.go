@@ -0,0 +1,157 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	kcorelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+	ntolisters "github.com/openshift/cluster-node-tuning-operator/pkg/generated/listers/tuned/v1"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	mcfglisters "github.com/openshift/machine-config-operator/pkg/generated/listers/machineconfiguration.openshift.io/v1"
+)
+
+// SimulationInput are the objects the profiles are calculated from offline.
+type SimulationInput struct {
+	// Tuned CRs; Tuned CRs without a namespace are assumed to be in the operator's namespace
+	Tuneds []*tunedv1.Tuned
+	// Nodes to calculate the profiles for
+	Nodes []*corev1.Node
+	// MachineConfigPools for machineConfigLabels based matching
+	MachineConfigPools []*mcfgv1.MachineConfigPool
+	// Pods for Pod label and annotation based matching
+	Pods []*corev1.Pod
+	// Namespaces with tenant Tuned entitlements
+	Namespaces []*corev1.Namespace
+}
+
+// SimulatedProfile is the profile calculated offline for a Node.
+type SimulatedProfile struct {
+	// Node name
+	Node string `json:"node"`
+	// TuneD profile the Node would receive
+	TunedProfile string `json:"tunedProfile"`
+	// operand configuration the Node would receive
+	Operand tunedv1.OperandConfig `json:"operand"`
+	// MachineConfig labels if the profile was selected by machineConfigLabels
+	MachineConfigLabels map[string]string `json:"machineConfigLabels,omitempty"`
+	// name of the MachineConfig the operator would create for the Node's pools
+	MachineConfig string `json:"machineConfig,omitempty"`
+	// namespace/name of the Pod that selected the profile
+	MatchedPod string `json:"matchedPod,omitempty"`
+	// active schedule window of the rule that selected the profile
+	ActiveWindow string `json:"activeWindow,omitempty"`
+	// error calculating the profile
+	Error string `json:"error,omitempty"`
+}
+
+// newSimulationListers returns listers backed by indexers populated with the
+// objects from 'in' rather than by informers.
+func newSimulationListers(in SimulationInput) (*ntoclient.Listers, error) {
+	tunedIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, tuned := range in.Tuneds {
+		if tuned.Namespace == "" {
+			tuned = tuned.DeepCopy()
+			tuned.Namespace = ntoconfig.WatchNamespace()
+		}
+		if err := tunedIndexer.Add(tuned); err != nil {
+			return nil, fmt.Errorf("failed to add Tuned %s: %v", tuned.Name, err)
+		}
+	}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, node := range in.Nodes {
+		if err := nodeIndexer.Add(node); err != nil {
+			return nil, fmt.Errorf("failed to add Node %s: %v", node.Name, err)
+		}
+	}
+
+	mcpIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, mcp := range in.MachineConfigPools {
+		if err := mcpIndexer.Add(mcp); err != nil {
+			return nil, fmt.Errorf("failed to add MachineConfigPool %s: %v", mcp.Name, err)
+		}
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		podNodeNameIndex:     podNodeNameIndexFunc,
+	})
+	for _, pod := range in.Pods {
+		if err := podIndexer.Add(pod); err != nil {
+			return nil, fmt.Errorf("failed to add Pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range in.Namespaces {
+		if err := nsIndexer.Add(ns); err != nil {
+			return nil, fmt.Errorf("failed to add Namespace %s: %v", ns.Name, err)
+		}
+	}
+
+	return &ntoclient.Listers{
+		TunedResources:     ntolisters.NewTunedLister(tunedIndexer).Tuneds(ntoconfig.WatchNamespace()),
+		TenantTuneds:       ntolisters.NewTunedLister(tunedIndexer),
+		Nodes:              kcorelisters.NewNodeLister(nodeIndexer),
+		MachineConfigPools: mcfglisters.NewMachineConfigPoolLister(mcpIndexer),
+		Pods:               kcorelisters.NewPodLister(podIndexer),
+		PodIndexer:         podIndexer,
+		Namespaces:         kcorelisters.NewNamespaceLister(nsIndexer),
+	}, nil
+}
+
+// SimulateProfiles calculates the profiles for the Nodes from 'in' the same
+// way the operator does, but without an API server.  The results are sorted
+// by Node name.
+func SimulateProfiles(in SimulationInput) ([]SimulatedProfile, error) {
+	listers, err := newSimulationListers(in)
+	if err != nil {
+		return nil, err
+	}
+	pc := NewProfileCalculator(listers, &ntoclient.Clients{})
+
+	for _, pod := range in.Pods {
+		if pod.Spec.NodeName == "" || podTerminated(pod) {
+			continue
+		}
+		if _, _, err := pc.podChangeHandler(pod.Namespace, pod.Name); err != nil {
+			return nil, fmt.Errorf("failed to process Pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	nodes := make([]string, 0, len(in.Nodes))
+	for _, node := range in.Nodes {
+		if _, err := pc.nodeChangeHandler(node.Name); err != nil {
+			return nil, fmt.Errorf("failed to process Node %s: %v", node.Name, err)
+		}
+		nodes = append(nodes, node.Name)
+	}
+	sort.Strings(nodes)
+
+	profiles := make([]SimulatedProfile, 0, len(nodes))
+	for _, nodeName := range nodes {
+		profile := SimulatedProfile{Node: nodeName}
+		tunedProfile, mcLabels, pools, operand, err := pc.calculateProfile(nodeName)
+		if err != nil {
+			profile.Error = err.Error()
+		}
+		profile.TunedProfile = tunedProfile
+		profile.Operand = operand
+		profile.MachineConfigLabels = mcLabels
+		if mcLabels != nil {
+			profile.MachineConfig = getMachineConfigNameForPools(pools)
+		}
+		profile.MatchedPod = pc.state.podMatches[nodeName]
+		profile.ActiveWindow = pc.state.scheduleWindows[nodeName]
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
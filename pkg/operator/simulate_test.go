@@ -0,0 +1,252 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+)
+
+func simulationTuned(name string, recommend ...tunedv1.TunedRecommend) *tunedv1.Tuned {
+	return &tunedv1.Tuned{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       tunedv1.TunedSpec{Recommend: recommend},
+	}
+}
+
+func simulationRecommend(profile string, priority *uint64, match ...tunedv1.TunedMatch) tunedv1.TunedRecommend {
+	return tunedv1.TunedRecommend{
+		Profile:  &profile,
+		Priority: priority,
+		Match:    match,
+	}
+}
+
+func simulationNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func simulationPod(namespace, name, nodeName string, labels map[string]string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestSimulateProfiles(t *testing.T) {
+	defaultTuned := simulationTuned(tunedv1.TunedDefaultResourceName,
+		simulationRecommend("openshift-node", uint64Ptr(40)))
+
+	workerPool := &mcfgv1.MachineConfigPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-rt"},
+		Spec: mcfgv1.MachineConfigPoolSpec{
+			NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"node-role.kubernetes.io/worker-rt": ""},
+			},
+			MachineConfigSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "worker-rt"},
+			},
+		},
+	}
+
+	var tests = []struct {
+		name     string
+		in       SimulationInput
+		expected []SimulatedProfile
+	}{
+		{
+			name: "catch-all default profile, results sorted by Node name",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{defaultTuned},
+				Nodes:  []*corev1.Node{simulationNode("node-b", nil), simulationNode("node-a", nil)},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node-a", TunedProfile: "openshift-node"},
+				{Node: "node-b", TunedProfile: "openshift-node"},
+			},
+		},
+		{
+			name: "Node label match",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("ingress",
+						simulationRecommend("openshift-ingress", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("node-role.kubernetes.io/infra")})),
+				},
+				Nodes: []*corev1.Node{
+					simulationNode("infra", map[string]string{"node-role.kubernetes.io/infra": ""}),
+					simulationNode("worker", map[string]string{"node-role.kubernetes.io/worker": ""}),
+				},
+			},
+			expected: []SimulatedProfile{
+				{Node: "infra", TunedProfile: "openshift-ingress"},
+				{Node: "worker", TunedProfile: "openshift-node"},
+			},
+		},
+		{
+			name: "Node label value must match",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("zone",
+						simulationRecommend("zone-a", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("zone"), Value: stringPtr("a")})),
+				},
+				Nodes: []*corev1.Node{
+					simulationNode("node-a", map[string]string{"zone": "a"}),
+					simulationNode("node-b", map[string]string{"zone": "b"}),
+				},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node-a", TunedProfile: "zone-a"},
+				{Node: "node-b", TunedProfile: "openshift-node"},
+			},
+		},
+		{
+			name: "Pod label match records the matching Pod, terminated Pods ignored",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("es",
+						simulationRecommend("openshift-es", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("tuned.openshift.io/elasticsearch"), Type: stringPtr("pod")})),
+				},
+				Nodes: []*corev1.Node{simulationNode("node-a", nil), simulationNode("node-b", nil)},
+				Pods: []*corev1.Pod{
+					simulationPod("logging", "es-0", "node-a", map[string]string{"tuned.openshift.io/elasticsearch": ""}, corev1.PodRunning),
+					simulationPod("logging", "es-1", "node-b", map[string]string{"tuned.openshift.io/elasticsearch": ""}, corev1.PodSucceeded),
+				},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node-a", TunedProfile: "openshift-es", MatchedPod: "logging/es-0"},
+				{Node: "node-b", TunedProfile: "openshift-node"},
+			},
+		},
+		{
+			name: "lower priority value wins",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("a",
+						simulationRecommend("profile-low", uint64Ptr(30), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+					simulationTuned("b",
+						simulationRecommend("profile-high", uint64Ptr(10), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+				},
+				Nodes: []*corev1.Node{simulationNode("node", map[string]string{"tuned": ""})},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node", TunedProfile: "profile-high"},
+			},
+		},
+		{
+			name: "undefined priority has the lowest priority",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("a",
+						simulationRecommend("profile-unprioritized", nil, tunedv1.TunedMatch{Label: stringPtr("tuned")}),
+						simulationRecommend("profile-prioritized", uint64Ptr(30), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+				},
+				Nodes: []*corev1.Node{simulationNode("node", map[string]string{"tuned": ""})},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node", TunedProfile: "profile-prioritized"},
+			},
+		},
+		{
+			name: "same priority ties broken by Tuned name",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("zz",
+						simulationRecommend("profile-zz", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+					simulationTuned("aa",
+						simulationRecommend("profile-aa", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+				},
+				Nodes: []*corev1.Node{simulationNode("node", map[string]string{"tuned": ""})},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node", TunedProfile: "profile-aa"},
+			},
+		},
+		{
+			name: "same priority ties within a Tuned broken by recommend order",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("a",
+						simulationRecommend("profile-first", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("tuned")}),
+						simulationRecommend("profile-second", uint64Ptr(20), tunedv1.TunedMatch{Label: stringPtr("tuned")})),
+				},
+				Nodes: []*corev1.Node{simulationNode("node", map[string]string{"tuned": ""})},
+			},
+			expected: []SimulatedProfile{
+				{Node: "node", TunedProfile: "profile-first"},
+			},
+		},
+		{
+			name: "machineConfigLabels match",
+			in: SimulationInput{
+				Tuneds: []*tunedv1.Tuned{
+					defaultTuned,
+					simulationTuned("rt", tunedv1.TunedRecommend{
+						Profile:             stringPtr("openshift-realtime"),
+						Priority:            uint64Ptr(20),
+						MachineConfigLabels: map[string]string{"machineconfiguration.openshift.io/role": "worker-rt"},
+					}),
+				},
+				Nodes: []*corev1.Node{
+					simulationNode("rt", map[string]string{"node-role.kubernetes.io/worker-rt": ""}),
+					simulationNode("worker", map[string]string{"node-role.kubernetes.io/worker": ""}),
+				},
+				MachineConfigPools: []*mcfgv1.MachineConfigPool{workerPool},
+			},
+			expected: []SimulatedProfile{
+				{
+					Node:                "rt",
+					TunedProfile:        "openshift-realtime",
+					MachineConfigLabels: map[string]string{"machineconfiguration.openshift.io/role": "worker-rt"},
+					MachineConfig:       getMachineConfigNameForPools([]*mcfgv1.MachineConfigPool{workerPool}),
+				},
+				{Node: "worker", TunedProfile: "openshift-node"},
+			},
+		},
+		{
+			name: "no default Tuned",
+			in: SimulationInput{
+				Nodes: []*corev1.Node{simulationNode("node", nil)},
+			},
+			expected: []SimulatedProfile{
+				{
+					Node:         "node",
+					TunedProfile: defaultProfile,
+					Error:        `failed to get Tuned default: tuned.tuned.openshift.io "default" not found`,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			profiles, err := SimulateProfiles(tc.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(profiles, tc.expected) {
+				t.Errorf("want: %+v\nhave: %+v", tc.expected, profiles)
+			}
+		})
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
 	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,6 +25,15 @@ import (
 	"github.com/openshift/cluster-node-tuning-operator/pkg/metrics"
 )
 
+const (
+	// maximum number of items such as Nodes listed in status condition messages
+	maxStatusListItems = 5
+	// Profiles generated by an operand version other than the one of the current
+	// release make the operator not Upgradeable only if the version skew persists
+	// for this long after the operand DaemonSet rolled out
+	operandVersionSkewGracePeriod = 15 * time.Minute
+)
+
 // syncOperatorStatus computes the operator's current status and therefrom
 // creates or updates the ClusterOperator resource for the operator.
 func (c *Controller) syncOperatorStatus(tuned *tunedv1.Tuned) error {
@@ -43,9 +57,14 @@ func (c *Controller) syncOperatorStatus(tuned *tunedv1.Tuned) error {
 			operatorv1helpers.SetOperandVersion(&co.Status.Versions, configv1.OperandVersion{Name: "operator", Version: releaseVersion})
 		}
 	}
-	co.Status.RelatedObjects = getRelatedObjects()
+	oldRelatedObjects := co.Status.RelatedObjects
+	co.Status.RelatedObjects, err = c.getRelatedObjects()
+	if err != nil {
+		return err
+	}
 
-	if clusteroperator.ConditionsEqual(oldConditions, co.Status.Conditions) {
+	if clusteroperator.ConditionsEqual(oldConditions, co.Status.Conditions) &&
+		reflect.DeepEqual(oldRelatedObjects, co.Status.RelatedObjects) {
 		klog.V(2).Infof("syncOperatorStatus(): ConditionsEqual")
 		return nil
 	}
@@ -113,23 +132,164 @@ func profileDegraded(profile *tunedv1.Profile) bool {
 	return false
 }
 
-// numProfilesProgressingDegraded returns two ints which count
-// the number of Profiles in the slice 'profileList' which are
-// waiting to be applied and in a degraded state, respectively.
-func numProfilesProgressingDegraded(profileList []*tunedv1.Profile) (int, int) {
-	numDegraded := 0
-	numProgressing := 0
+// profileRolledBack returns true if the operand confirmed the rollback of the
+// node-level tuning of Profile 'profile'.
+func profileRolledBack(profile *tunedv1.Profile) bool {
+	if profile == nil {
+		return false
+	}
+
+	for _, sc := range profile.Status.Conditions {
+		if sc.Type == tunedv1.TunedRolledBack && sc.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// profilesProgressingDegraded returns two slices of Profiles from the slice
+// 'profileList' which are waiting to be applied and in a degraded state,
+// respectively.
+func profilesProgressingDegraded(profileList []*tunedv1.Profile) ([]*tunedv1.Profile, []*tunedv1.Profile) {
+	var (
+		progressing []*tunedv1.Profile
+		degraded    []*tunedv1.Profile
+	)
 	for _, profile := range profileList {
 		if profileDegraded(profile) {
-			numDegraded++
+			degraded = append(degraded, profile)
 			continue
 		}
 		if !profileApplied(profile) {
-			numProgressing++
+			progressing = append(progressing, profile)
+		}
+	}
+
+	return progressing, degraded
+}
+
+// boundedList returns a sorted, comma-separated list of at most
+// maxStatusListItems items from 'items' for use in status condition messages.
+func boundedList(items []string) string {
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	if len(sorted) <= maxStatusListItems {
+		return strings.Join(sorted, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(sorted[:maxStatusListItems], ", "), len(sorted)-maxStatusListItems)
+}
+
+// profilesList returns a bounded list of Nodes and the TuneD profiles selected
+// for them from the slice 'profileList' for use in status condition messages.
+func profilesList(profileList []*tunedv1.Profile) string {
+	items := make([]string, 0, len(profileList))
+	for _, profile := range profileList {
+		items = append(items, fmt.Sprintf("%s (%s)", profile.Name, profile.Spec.Config.TunedProfile))
+	}
+	return boundedList(items)
+}
+
+// daemonSetRolledOut returns true if the rollout of DaemonSet 'ds' completed,
+// i.e. all of its Pods are updated and available.
+func daemonSetRolledOut(ds *appsv1.DaemonSet) bool {
+	return ds != nil &&
+		ds.Status.ObservedGeneration >= ds.Generation &&
+		ds.Status.DesiredNumberScheduled > 0 &&
+		ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled
+}
+
+// operandVersionSkew returns the Profiles from 'profileList' generated by an
+// operand version other than 'releaseVersion' along with their version.
+func operandVersionSkew(profileList []*tunedv1.Profile, releaseVersion string) []string {
+	oldProfiles := []string{}
+	for _, profile := range profileList {
+		v := profile.Annotations[tunedv1.GeneratedByOperandVersionAnnotationKey]
+		if len(v) > 0 && v != releaseVersion {
+			oldProfiles = append(oldProfiles, fmt.Sprintf("%s (%s)", profile.Name, v))
+		}
+	}
+	return oldProfiles
+}
+
+// operandVersionSkewSince returns the time the operand version skew was first
+// observed after the operand DaemonSet rolled out given whether there is a
+// version 'skew', whether the DaemonSet is 'rolledOut', the time 'since' the
+// skew was previously observed and the current time 'now'.  The zero time is
+// returned if there is no skew or the rollout is still in progress.
+func operandVersionSkewSince(skew bool, rolledOut bool, since time.Time, now time.Time) time.Time {
+	if !skew || !rolledOut {
+		return time.Time{}
+	}
+	if since.IsZero() {
+		return now
+	}
+	return since
+}
+
+// computeUpgradeableCondition computes the operator's Upgradeable condition.
+// The operator is not upgradeable when any of the Tuned CRs uses the deprecated
+// Pod label matching or when any of the Profiles was generated by an operand
+// version other than the one of the current release for longer than
+// operandVersionSkewGracePeriod after the operand DaemonSet 'ds' rolled out.
+// Version skew during a rolling update of the operands is expected.
+func (c *Controller) computeUpgradeableCondition(profileList []*tunedv1.Profile, ds *appsv1.DaemonSet) (configv1.ClusterOperatorStatusCondition, error) {
+	var (
+		reasons  []string
+		messages []string
+	)
+	upgradeableCondition := configv1.ClusterOperatorStatusCondition{
+		Type: configv1.OperatorUpgradeable,
+	}
+
+	tunedList, err := c.listers.TunedResources.List(labels.Everything())
+	if err != nil {
+		return upgradeableCondition, fmt.Errorf("failed to list Tuned: %v", err)
+	}
+	podLabelTuneds := []string{}
+	for _, tuned := range tunedList {
+		if c.pc.tunedsUsePodLabels([]*tunedv1.Tuned{tuned}) {
+			podLabelTuneds = append(podLabelTuneds, tuned.Name)
+		}
+	}
+	if len(podLabelTuneds) > 0 {
+		reasons = append(reasons, "PodLabelMatching")
+		messages = append(messages, fmt.Sprintf("Tuned CRs use deprecated Pod label matching: %s", boundedList(podLabelTuneds)))
+	}
+
+	if releaseVersion := os.Getenv("RELEASE_VERSION"); len(releaseVersion) > 0 {
+		oldProfiles := operandVersionSkew(profileList, releaseVersion)
+		now := time.Now()
+		c.operandVersionSkewSince = operandVersionSkewSince(len(oldProfiles) > 0, daemonSetRolledOut(ds), c.operandVersionSkewSince, now)
+		if !c.operandVersionSkewSince.IsZero() {
+			if persisted := now.Sub(c.operandVersionSkewSince); persisted > operandVersionSkewGracePeriod {
+				reasons = append(reasons, "OperandVersionMismatch")
+				messages = append(messages, fmt.Sprintf("%v/%v Profiles were generated by an operand version other than %q for more than %v after the operands rolled out: %s",
+					len(oldProfiles), len(profileList), releaseVersion, operandVersionSkewGracePeriod, boundedList(oldProfiles)))
+			} else {
+				// Re-evaluate once the grace period is over.
+				c.workqueue.AddAfter(wqKey{kind: wqKindClusterOperator}, operandVersionSkewGracePeriod-persisted+time.Second)
+			}
 		}
 	}
 
-	return numProgressing, numDegraded
+	switch len(reasons) {
+	case 0:
+		upgradeableCondition.Status = configv1.ConditionTrue
+		upgradeableCondition.Reason = "AsExpected"
+		upgradeableCondition.Message = "No risky configuration detected"
+	case 1:
+		upgradeableCondition.Status = configv1.ConditionFalse
+		upgradeableCondition.Reason = reasons[0]
+		upgradeableCondition.Message = messages[0]
+	default:
+		upgradeableCondition.Status = configv1.ConditionFalse
+		upgradeableCondition.Reason = "MultipleRisks"
+		upgradeableCondition.Message = strings.Join(messages, "; ")
+	}
+
+	return upgradeableCondition, nil
 }
 
 // computeStatusConditions computes the operator's current state.
@@ -150,6 +310,12 @@ func (c *Controller) computeStatusConditions(tuned *tunedv1.Tuned, conditions []
 	degradedCondition := configv1.ClusterOperatorStatusCondition{
 		Type: configv1.OperatorDegraded,
 	}
+	upgradeableCondition := configv1.ClusterOperatorStatusCondition{
+		Type: configv1.OperatorUpgradeable,
+	}
+	sysctlConflictsCondition := configv1.ClusterOperatorStatusCondition{
+		Type: OperatorSysctlConflicts,
+	}
 
 	copyAvailableCondition := func() {
 		progressingCondition.Status = availableCondition.Status
@@ -169,6 +335,14 @@ func (c *Controller) computeStatusConditions(tuned *tunedv1.Tuned, conditions []
 	case operatorv1.Removed:
 		availableCondition.Reason = "Removed"
 		availableCondition.Message = "The operator is removed"
+		if !c.removal.completed {
+			availableCondition.Reason = "Removing"
+			availableCondition.Message = "The operator is being removed"
+			if len(c.removal.pending) > 0 {
+				availableCondition.Message = fmt.Sprintf("Waiting for %v Profiles to roll back: %s",
+					len(c.removal.pending), boundedList(c.removal.pending))
+			}
+		}
 
 	default:
 		ds, err := c.listers.DaemonSets.Get(dsMf.Name)
@@ -253,18 +427,34 @@ func (c *Controller) computeStatusConditions(tuned *tunedv1.Tuned, conditions []
 			copyAvailableCondition()
 		}
 
-		numProgressingProfiles, numDegradedProfiles := numProfilesProgressingDegraded(profileList)
+		progressingProfiles, degradedProfiles := profilesProgressingDegraded(profileList)
 
-		if numProgressingProfiles > 0 {
+		if len(progressingProfiles) > 0 {
 			progressingCondition.Status = configv1.ConditionTrue
 			progressingCondition.Reason = "ProfileProgressing"
-			progressingCondition.Message = fmt.Sprintf("Waiting for %v/%v Profiles to be applied", numProgressingProfiles, len(profileList))
+			progressingCondition.Message = fmt.Sprintf("Waiting for %v/%v Profiles to be applied: %s",
+				len(progressingProfiles), len(profileList), profilesList(progressingProfiles))
 		}
 
-		if numDegradedProfiles > 0 {
-			klog.Infof(fmt.Sprintf("%v/%v Profiles failed to be applied", numDegradedProfiles, len(profileList)))
+		if len(degradedProfiles) > 0 {
 			availableCondition.Reason = "ProfileDegraded"
-			availableCondition.Message = fmt.Sprintf("%v/%v Profiles failed to be applied", numDegradedProfiles, len(profileList))
+			availableCondition.Message = fmt.Sprintf("%v/%v Profiles failed to be applied: %s",
+				len(degradedProfiles), len(profileList), profilesList(degradedProfiles))
+			klog.Info(availableCondition.Message)
+		}
+
+		upgradeableCondition, err = c.computeUpgradeableCondition(profileList, ds)
+		if err != nil {
+			upgradeableCondition.Status = configv1.ConditionUnknown
+			upgradeableCondition.Reason = "Unknown"
+			upgradeableCondition.Message = fmt.Sprintf("Unable to determine upgradeability: %v", err)
+		}
+
+		sysctlConflictsCondition, err = c.computeSysctlConflictsCondition(profileList)
+		if err != nil {
+			sysctlConflictsCondition.Status = configv1.ConditionUnknown
+			sysctlConflictsCondition.Reason = "Unknown"
+			sysctlConflictsCondition.Message = fmt.Sprintf("Unable to determine sysctl conflicts: %v", err)
 		}
 
 		// If the operator is not available for an extensive period of time, set the Degraded operator status.
@@ -287,6 +477,10 @@ func (c *Controller) computeStatusConditions(tuned *tunedv1.Tuned, conditions []
 		availableCondition.Status = configv1.ConditionTrue
 
 		progressingCondition.Status = configv1.ConditionFalse
+		if tuned.Spec.ManagementState == operatorv1.Removed && !c.removal.completed {
+			// Rolling back the node-level tuning and removing the operand.
+			progressingCondition.Status = configv1.ConditionTrue
+		}
 		progressingCondition.Reason = availableCondition.Reason
 		progressingCondition.Message = availableCondition.Message
 
@@ -294,23 +488,35 @@ func (c *Controller) computeStatusConditions(tuned *tunedv1.Tuned, conditions []
 		degradedCondition.Reason = availableCondition.Reason
 		degradedCondition.Message = availableCondition.Message
 
+		upgradeableCondition.Status = configv1.ConditionTrue
+		upgradeableCondition.Reason = availableCondition.Reason
+		upgradeableCondition.Message = availableCondition.Message
+
+		sysctlConflictsCondition.Status = configv1.ConditionFalse
+		sysctlConflictsCondition.Reason = availableCondition.Reason
+		sysctlConflictsCondition.Message = availableCondition.Message
+
 	default:
 	}
 
 	conditions = clusteroperator.SetStatusCondition(conditions, &availableCondition)
 	conditions = clusteroperator.SetStatusCondition(conditions, &progressingCondition)
 	conditions = clusteroperator.SetStatusCondition(conditions, &degradedCondition)
+	conditions = clusteroperator.SetStatusCondition(conditions, &upgradeableCondition)
+	conditions = clusteroperator.SetStatusCondition(conditions, &sysctlConflictsCondition)
 
 	klog.V(3).Infof("operator status conditions: %v", conditions)
 
 	return conditions, nil
 }
 
-func getRelatedObjects() []configv1.ObjectReference {
+// getRelatedObjects returns the objects related to the operator including
+// the MachineConfigs created by the operator.
+func (c *Controller) getRelatedObjects() ([]configv1.ObjectReference, error) {
 	tunedMf := ntomf.TunedCustomResource()
 	dsMf := ntomf.TunedDaemonSet()
 
-	return []configv1.ObjectReference{
+	relatedObjects := []configv1.ObjectReference{
 		// The `resource` property of `relatedObjects` stanza should be the lowercase, plural value like `daemonsets`.
 		// See BZ1851214
 		{Group: "", Resource: "namespaces", Name: tunedMf.Namespace},
@@ -318,6 +524,27 @@ func getRelatedObjects() []configv1.ObjectReference {
 		{Group: "tuned.openshift.io", Resource: "tuneds", Name: "", Namespace: tunedMf.Namespace},
 		{Group: "apps", Resource: "daemonsets", Name: dsMf.Name, Namespace: dsMf.Namespace},
 	}
+
+	if c.listers.MachineConfigs == nil {
+		// No MachineConfig informer in HyperShift
+		return relatedObjects, nil
+	}
+	mcList, err := c.listers.MachineConfigs.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MachineConfigs: %v", err)
+	}
+	mcNames := []string{}
+	for _, mc := range mcList {
+		if _, ok := mc.ObjectMeta.Annotations[GeneratedByControllerVersionAnnotationKey]; ok {
+			mcNames = append(mcNames, mc.ObjectMeta.Name)
+		}
+	}
+	sort.Strings(mcNames)
+	for _, name := range mcNames {
+		relatedObjects = append(relatedObjects, configv1.ObjectReference{Group: "machineconfiguration.openshift.io", Resource: "machineconfigs", Name: name})
+	}
+
+	return relatedObjects, nil
 }
 
 func conditionTrue(conditions []configv1.ClusterOperatorStatusCondition, condType configv1.ClusterStatusConditionType) bool {
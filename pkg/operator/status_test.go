@@ -0,0 +1,151 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoclient "github.com/openshift/cluster-node-tuning-operator/pkg/client"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+	ntolisters "github.com/openshift/cluster-node-tuning-operator/pkg/generated/listers/tuned/v1"
+)
+
+func TestDaemonSetRolledOut(t *testing.T) {
+	ds := func(generation, observed int64, desired, updated, available int32) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Generation: generation},
+			Status: appsv1.DaemonSetStatus{
+				ObservedGeneration:     observed,
+				DesiredNumberScheduled: desired,
+				UpdatedNumberScheduled: updated,
+				NumberAvailable:        available,
+			},
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		ds       *appsv1.DaemonSet
+		expected bool
+	}{
+		{name: "rolled out", ds: ds(2, 2, 3, 3, 3), expected: true},
+		{name: "no DaemonSet", ds: nil, expected: false},
+		{name: "new generation not observed", ds: ds(3, 2, 3, 3, 3), expected: false},
+		{name: "Pods being updated", ds: ds(2, 2, 3, 1, 3), expected: false},
+		{name: "updated Pods not available", ds: ds(2, 2, 3, 3, 2), expected: false},
+		{name: "no Nodes", ds: ds(2, 2, 0, 0, 0), expected: false},
+	}
+
+	for _, tc := range tests {
+		if rolledOut := daemonSetRolledOut(tc.ds); rolledOut != tc.expected {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.expected, rolledOut)
+		}
+	}
+}
+
+func TestOperandVersionSkew(t *testing.T) {
+	profile := func(name, version string) *tunedv1.Profile {
+		p := &tunedv1.Profile{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if version != "" {
+			p.Annotations = map[string]string{tunedv1.GeneratedByOperandVersionAnnotationKey: version}
+		}
+		return p
+	}
+	profileList := []*tunedv1.Profile{
+		profile("node-1", "4.12.0"),
+		profile("node-2", "4.11.0"),
+		profile("node-3", ""), // not updated by any operand yet
+	}
+
+	if skew := operandVersionSkew(profileList, "4.12.0"); !reflect.DeepEqual(skew, []string{"node-2 (4.11.0)"}) {
+		t.Errorf("unexpected version skew: %v", skew)
+	}
+	if skew := operandVersionSkew(profileList[:1], "4.12.0"); len(skew) != 0 {
+		t.Errorf("want no version skew, have %v", skew)
+	}
+}
+
+func TestOperandVersionSkewSince(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+
+	var tests = []struct {
+		name      string
+		skew      bool
+		rolledOut bool
+		since     time.Time
+		expected  time.Time
+	}{
+		{name: "no skew", skew: false, rolledOut: true, since: earlier, expected: time.Time{}},
+		{name: "skew during rollout", skew: true, rolledOut: false, since: earlier, expected: time.Time{}},
+		{name: "skew first observed after rollout", skew: true, rolledOut: true, since: time.Time{}, expected: now},
+		{name: "skew persists after rollout", skew: true, rolledOut: true, since: earlier, expected: earlier},
+	}
+
+	for _, tc := range tests {
+		if since := operandVersionSkewSince(tc.skew, tc.rolledOut, tc.since, now); !since.Equal(tc.expected) {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.expected, since)
+		}
+	}
+}
+
+func TestComputeUpgradeableConditionVersionSkew(t *testing.T) {
+	t.Setenv("RELEASE_VERSION", "4.12.0")
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &Controller{
+		listers: &ntoclient.Listers{
+			TunedResources: ntolisters.NewTunedLister(indexer).Tuneds(ntoconfig.WatchNamespace()),
+		},
+		pc:        &ProfileCalculator{},
+		workqueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	defer c.workqueue.ShutDown()
+
+	oldProfile := &tunedv1.Profile{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node-1",
+		Annotations: map[string]string{tunedv1.GeneratedByOperandVersionAnnotationKey: "4.11.0"},
+	}}
+	profileList := []*tunedv1.Profile{oldProfile}
+	rollingOut := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3}}
+	rolledOut := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}}
+
+	// Version skew during the operand rollout is expected.
+	cond, err := c.computeUpgradeableCondition(profileList, rollingOut)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cond.Status != configv1.ConditionTrue {
+		t.Errorf("want Upgradeable during the rollout, have %s: %s", cond.Status, cond.Message)
+	}
+
+	// Version skew right after the rollout is within the grace period.
+	cond, _ = c.computeUpgradeableCondition(profileList, rolledOut)
+	if cond.Status != configv1.ConditionTrue {
+		t.Errorf("want Upgradeable within the grace period, have %s: %s", cond.Status, cond.Message)
+	}
+	if c.operandVersionSkewSince.IsZero() {
+		t.Errorf("version skew after the rollout not recorded")
+	}
+
+	// Version skew persisting past the grace period.
+	c.operandVersionSkewSince = time.Now().Add(-operandVersionSkewGracePeriod - time.Minute)
+	cond, _ = c.computeUpgradeableCondition(profileList, rolledOut)
+	if cond.Status != configv1.ConditionFalse || cond.Reason != "OperandVersionMismatch" {
+		t.Errorf("want not Upgradeable due to OperandVersionMismatch, have %s/%s: %s", cond.Status, cond.Reason, cond.Message)
+	}
+
+	// Profiles updated by the new operand.
+	oldProfile.Annotations[tunedv1.GeneratedByOperandVersionAnnotationKey] = "4.12.0"
+	cond, _ = c.computeUpgradeableCondition(profileList, rolledOut)
+	if cond.Status != configv1.ConditionTrue || !c.operandVersionSkewSince.IsZero() {
+		t.Errorf("want Upgradeable once the version skew is gone, have %s: %s", cond.Status, cond.Message)
+	}
+}
@@ -0,0 +1,484 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	ign3types "github.com/coreos/ignition/v2/config/v3_2/types"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/vincent-petithory/dataurl"
+	"gopkg.in/ini.v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+)
+
+const (
+	// OperatorSysctlConflicts is the ClusterOperator condition type reporting
+	// sysctls set by more than one source on the same Node.
+	OperatorSysctlConflicts configv1.ClusterStatusConditionType = "SysctlConflicts"
+
+	sysctlConfDir  = "/etc/sysctl.d"
+	sysctlConfFile = "/etc/sysctl.conf"
+)
+
+// sysctlSetting is a sysctl value set by 'source'.
+type sysctlSetting struct {
+	source string
+	value  string
+}
+
+// sysctlKey normalizes sysctl key 'key' to the dot-separated form.
+func sysctlKey(key string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(strings.TrimSpace(key), "-"), "/", ".")
+}
+
+// parseSysctlConf returns the keys and values set by sysctl.d configuration
+// 'data' in the order they are set.
+func parseSysctlConf(data string) [][2]string {
+	var kvs [][2]string
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		kvs = append(kvs, [2]string{sysctlKey(kv[0]), strings.TrimSpace(kv[1])})
+	}
+
+	return kvs
+}
+
+// tunedProfileSysctls returns the sysctls TuneD profile 'profileName' sets as
+// defined by the TuneD profiles 'profiles' (name -> data) of the rendered Tuned
+// CR.  Included profiles are processed first so that the including profile
+// overrides them.  Profiles shipped with the operand are not known to the
+// operator and includes of them as well as includes using TuneD built-in
+// functions are skipped.
+func tunedProfileSysctls(profiles map[string]string, profileName string, seen map[string]bool) map[string]string {
+	sysctls := map[string]string{}
+
+	data, ok := profiles[profileName]
+	if !ok || seen[profileName] {
+		return sysctls
+	}
+	seen[profileName] = true
+
+	cfg, err := ini.Load([]byte(data))
+	if err != nil {
+		klog.V(2).Infof("unable to read INI data of TuneD profile %s: %v", profileName, err)
+		return sysctls
+	}
+
+	if cfg.Section("main").HasKey("include") {
+		for _, include := range strings.Split(cfg.Section("main").Key("include").String(), ",") {
+			include = strings.TrimPrefix(strings.TrimSpace(include), "-")
+			if len(include) == 0 || strings.Contains(include, "${") {
+				continue
+			}
+			for k, v := range tunedProfileSysctls(profiles, include, seen) {
+				sysctls[k] = v
+			}
+		}
+	}
+
+	for _, section := range cfg.Sections() {
+		if section.Name() != "sysctl" && section.Key("type").String() != "sysctl" {
+			continue
+		}
+		for _, key := range section.Keys() {
+			if key.Name() == "type" || key.Name() == "replace" || key.Name() == "devices" {
+				// Plug-in instance options rather than sysctls.
+				continue
+			}
+			sysctls[sysctlKey(key.Name())] = key.String()
+		}
+	}
+
+	return sysctls
+}
+
+// machineConfigSysctlFiles returns the sysctl configuration files (path ->
+// data) MachineConfig 'mc' writes to the Node.
+func machineConfigSysctlFiles(mc *mcfgv1.MachineConfig) map[string]string {
+	files := map[string]string{}
+
+	if len(mc.Spec.Config.Raw) == 0 {
+		return files
+	}
+	// Only the storage section is needed and its format is the same for all
+	// Ignition 3.x versions; avoid the version-specific validation.
+	ignCfg := ign3types.Config{}
+	if err := json.Unmarshal(mc.Spec.Config.Raw, &ignCfg); err != nil {
+		klog.V(2).Infof("unable to read Ignition config of MachineConfig %s: %v", mc.Name, err)
+		return files
+	}
+	for _, file := range ignCfg.Storage.Files {
+		if file.Path != sysctlConfFile && !(path.Dir(file.Path) == sysctlConfDir && strings.HasSuffix(file.Path, ".conf")) {
+			continue
+		}
+		if file.Contents.Source == nil || (file.Contents.Compression != nil && len(*file.Contents.Compression) > 0) {
+			continue
+		}
+		du, err := dataurl.DecodeString(*file.Contents.Source)
+		if err != nil {
+			klog.V(2).Infof("unable to decode %s of MachineConfig %s: %v", file.Path, mc.Name, err)
+			continue
+		}
+		files[file.Path] = string(du.Data)
+	}
+
+	return files
+}
+
+// machineConfigSysctlFilesCache caches the sysctl configuration files of
+// MachineConfigs so that their Ignition configs are not parsed on every
+// ClusterOperator status sync.
+type machineConfigSysctlFilesCache struct {
+	entries map[string]machineConfigSysctlFilesEntry
+}
+
+type machineConfigSysctlFilesEntry struct {
+	uid        types.UID
+	generation int64
+	files      map[string]string
+}
+
+// get returns the sysctl configuration files of MachineConfig 'mc'.  The
+// Ignition config is parsed only if the MachineConfig was not seen before or
+// its generation changed.
+func (mcc *machineConfigSysctlFilesCache) get(mc *mcfgv1.MachineConfig) map[string]string {
+	if mcc.entries == nil {
+		mcc.entries = map[string]machineConfigSysctlFilesEntry{}
+	}
+	if e, ok := mcc.entries[mc.Name]; ok && e.uid == mc.UID && e.generation == mc.Generation {
+		return e.files
+	}
+	files := machineConfigSysctlFiles(mc)
+	mcc.entries[mc.Name] = machineConfigSysctlFilesEntry{uid: mc.UID, generation: mc.Generation, files: files}
+	return files
+}
+
+// prune drops the cached files of the MachineConfigs 'exists' reports deleted.
+func (mcc *machineConfigSysctlFilesCache) prune(exists func(name string) bool) {
+	for name := range mcc.entries {
+		if !exists(name) {
+			delete(mcc.entries, name)
+		}
+	}
+}
+
+// machineConfigSysctls returns the sysctls set by the sysctl configuration
+// files 'mcFiles' (MachineConfig name -> path -> data) MachineConfigs write to
+// the Node in the order systemd-sysctl applies them.  Files written by more
+// than one MachineConfig are taken from the last one in the order of their
+// names as done by the Machine Config Operator when rendering MachineConfigs.
+func machineConfigSysctls(mcFiles map[string]map[string]string) map[string][]sysctlSetting {
+	type sysctlFile struct {
+		mc   string
+		data string
+	}
+	files := map[string]sysctlFile{}
+
+	mcNames := make([]string, 0, len(mcFiles))
+	for name := range mcFiles {
+		mcNames = append(mcNames, name)
+	}
+	sort.Strings(mcNames)
+	for _, name := range mcNames {
+		for p, data := range mcFiles[name] {
+			files[p] = sysctlFile{mc: name, data: data}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		// /etc/sysctl.conf is applied last
+		if paths[i] == sysctlConfFile || paths[j] == sysctlConfFile {
+			return paths[j] == sysctlConfFile
+		}
+		return path.Base(paths[i]) < path.Base(paths[j])
+	})
+
+	sysctls := map[string][]sysctlSetting{}
+	for _, p := range paths {
+		for _, kv := range parseSysctlConf(files[p].data) {
+			source := fmt.Sprintf("MachineConfig %s (%s)", files[p].mc, p)
+			sysctls[kv[0]] = append(sysctls[kv[0]], sysctlSetting{source: source, value: kv[1]})
+		}
+	}
+
+	return sysctls
+}
+
+// kubeletConfigUnsafeSysctls returns the allowedUnsafeSysctls patterns of
+// KubeletConfig 'kc'.
+func kubeletConfigUnsafeSysctls(kc *mcfgv1.KubeletConfig) ([]string, error) {
+	var cfg struct {
+		AllowedUnsafeSysctls []string `json:"allowedUnsafeSysctls,omitempty"`
+	}
+
+	if kc.Spec.KubeletConfig == nil || len(kc.Spec.KubeletConfig.Raw) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(kc.Spec.KubeletConfig.Raw, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.AllowedUnsafeSysctls, nil
+}
+
+// sysctlPatternMatches returns true if sysctl 'key' matches the kubelet
+// allowedUnsafeSysctls pattern 'pattern'.
+func sysctlPatternMatches(pattern string, key string) bool {
+	pattern = sysctlKey(pattern)
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(key, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == key
+}
+
+// sysctlConflict returns a description of the conflict between the sources of
+// sysctl 'key' or an empty string if there is no conflict.  'tuned' is the
+// value set by the TuneD profile 'tunedProfile', if any; 'sysctlD' are the
+// values set by the sysctl configuration files in the order they are applied
+// and 'unsafe' are KubeletConfigs allowing Pods to set the sysctl.  TuneD
+// sysctls are overridden by the sysctl configuration files unless the
+// reapply_sysctl functionality is turned off by 'reapplySysctl'.
+func sysctlConflict(key string, tunedProfile string, tuned *string, sysctlD []sysctlSetting, unsafe []string, reapplySysctl bool) string {
+	var (
+		sources []string
+		winner  *sysctlSetting
+	)
+
+	if tuned != nil {
+		winner = &sysctlSetting{source: fmt.Sprintf("TuneD profile %s", tunedProfile), value: *tuned}
+		sources = append(sources, fmt.Sprintf("%s (%s)", winner.source, winner.value))
+	}
+	for i := range sysctlD {
+		sources = append(sources, fmt.Sprintf("%s (%s)", sysctlD[i].source, sysctlD[i].value))
+		if tuned == nil || reapplySysctl {
+			winner = &sysctlD[i]
+		}
+	}
+	for _, kc := range unsafe {
+		sources = append(sources, fmt.Sprintf("KubeletConfig %s (allowedUnsafeSysctls)", kc))
+	}
+
+	if len(sources) < 2 {
+		return ""
+	}
+	if len(unsafe) == 0 {
+		// Sources agreeing on the value do not conflict.
+		values := map[string]bool{}
+		if tuned != nil {
+			values[strings.Join(strings.Fields(*tuned), " ")] = true
+		}
+		for _, s := range sysctlD {
+			values[strings.Join(strings.Fields(s.value), " ")] = true
+		}
+		if len(values) < 2 {
+			return ""
+		}
+	}
+
+	msg := fmt.Sprintf("%s is set by %s", key, strings.Join(sources, " and "))
+	if winner != nil {
+		msg += fmt.Sprintf("; %s from %s wins", winner.value, winner.source)
+	}
+	if len(unsafe) > 0 {
+		msg += "; Pods may override it in their own namespaces"
+	}
+
+	return msg
+}
+
+// computeSysctlConflicts returns descriptions of the sysctls set by more than
+// one source on the Nodes of Profiles 'profileList'.  The sources are the
+// TuneD profiles of the rendered Tuned CR, the sysctl configuration files of
+// the MachineConfigs rendered for the Nodes' MachineConfigPools and the
+// allowedUnsafeSysctls of the KubeletConfigs selecting the MachineConfigPools.
+func (c *Controller) computeSysctlConflicts(profileList []*tunedv1.Profile) ([]string, error) {
+	if c.listers.MachineConfigs == nil || c.listers.MachineConfigPools == nil ||
+		c.listers.KubeletConfigs == nil || c.listers.Nodes == nil {
+		// MachineConfigs are not used on HyperShift.
+		return nil, nil
+	}
+
+	tunedProfiles := map[string]string{}
+	tuned, err := c.listers.TunedResources.Get(tunedv1.TunedRenderedResourceName)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		for _, profile := range tuned.Spec.Profile {
+			if profile.Name != nil && profile.Data != nil {
+				tunedProfiles[*profile.Name] = *profile.Data
+			}
+		}
+	}
+
+	kcList, err := c.listers.KubeletConfigs.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KubeletConfigs: %v", err)
+	}
+
+	tunedSysctlsCache := map[string]map[string]string{}
+	poolSysctlsCache := map[string]map[string][]sysctlSetting{}
+	poolUnsafeCache := map[string]map[string][]string{}
+
+	// conflict description -> Nodes
+	conflicts := map[string][]string{}
+	for _, profile := range profileList {
+		node, err := c.listers.Nodes.Get(profile.Name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		pool, err := c.pc.getPrimaryPoolForNode(node)
+		if err != nil {
+			return nil, err
+		}
+		if pool == nil {
+			continue
+		}
+
+		tunedProfile := profile.Spec.Config.TunedProfile
+		tunedSysctls, ok := tunedSysctlsCache[tunedProfile]
+		if !ok {
+			tunedSysctls = tunedProfileSysctls(tunedProfiles, tunedProfile, map[string]bool{})
+			tunedSysctlsCache[tunedProfile] = tunedSysctls
+		}
+
+		poolSysctls, ok := poolSysctlsCache[pool.Name]
+		if !ok {
+			mcFiles := map[string]map[string]string{}
+			for _, source := range pool.Status.Configuration.Source {
+				mc, err := c.listers.MachineConfigs.Get(source.Name)
+				if err != nil {
+					if errors.IsNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				mcFiles[mc.Name] = c.mcSysctlFiles.get(mc)
+			}
+			poolSysctls = machineConfigSysctls(mcFiles)
+			poolSysctlsCache[pool.Name] = poolSysctls
+		}
+
+		poolUnsafe, ok := poolUnsafeCache[pool.Name]
+		if !ok {
+			poolUnsafe = map[string][]string{}
+			for _, kc := range kcList {
+				selector, err := metav1.LabelSelectorAsSelector(kc.Spec.MachineConfigPoolSelector)
+				if err != nil || selector.Empty() || !selector.Matches(labels.Set(pool.Labels)) {
+					continue
+				}
+				patterns, err := kubeletConfigUnsafeSysctls(kc)
+				if err != nil {
+					klog.V(2).Infof("unable to read kubelet configuration of KubeletConfig %s: %v", kc.Name, err)
+					continue
+				}
+				poolUnsafe[kc.Name] = patterns
+			}
+			poolUnsafeCache[pool.Name] = poolUnsafe
+		}
+
+		keys := map[string]bool{}
+		for k := range tunedSysctls {
+			keys[k] = true
+		}
+		for k := range poolSysctls {
+			keys[k] = true
+		}
+
+		reapplySysctl := profile.Spec.Config.TuneDConfig.ReapplySysctl == nil || *profile.Spec.Config.TuneDConfig.ReapplySysctl
+		for key := range keys {
+			var (
+				tunedValue *string
+				unsafe     []string
+			)
+			if v, ok := tunedSysctls[key]; ok {
+				tunedValue = &v
+			}
+			for kc, patterns := range poolUnsafe {
+				for _, pattern := range patterns {
+					if sysctlPatternMatches(pattern, key) {
+						unsafe = append(unsafe, kc)
+						break
+					}
+				}
+			}
+			sort.Strings(unsafe)
+
+			if conflict := sysctlConflict(key, tunedProfile, tunedValue, poolSysctls[key], unsafe, reapplySysctl); len(conflict) > 0 {
+				conflicts[conflict] = append(conflicts[conflict], node.Name)
+			}
+		}
+	}
+
+	c.mcSysctlFiles.prune(func(name string) bool {
+		_, err := c.listers.MachineConfigs.Get(name)
+		return !errors.IsNotFound(err)
+	})
+
+	messages := make([]string, 0, len(conflicts))
+	for conflict, nodes := range conflicts {
+		messages = append(messages, fmt.Sprintf("%s on %s", conflict, boundedList(nodes)))
+	}
+	sort.Strings(messages)
+
+	return messages, nil
+}
+
+// computeSysctlConflictsCondition computes the operator's SysctlConflicts
+// condition from the sysctls set by more than one source on the Nodes of
+// Profiles 'profileList'.
+func (c *Controller) computeSysctlConflictsCondition(profileList []*tunedv1.Profile) (configv1.ClusterOperatorStatusCondition, error) {
+	sysctlConflictsCondition := configv1.ClusterOperatorStatusCondition{
+		Type: OperatorSysctlConflicts,
+	}
+
+	conflicts, err := c.computeSysctlConflicts(profileList)
+	if err != nil {
+		return sysctlConflictsCondition, err
+	}
+
+	if len(conflicts) == 0 {
+		sysctlConflictsCondition.Status = configv1.ConditionFalse
+		sysctlConflictsCondition.Reason = "AsExpected"
+		sysctlConflictsCondition.Message = "No sysctls are set by more than one source"
+		return sysctlConflictsCondition, nil
+	}
+
+	for _, conflict := range conflicts {
+		klog.V(2).Infof("sysctl conflict: %s", conflict)
+	}
+	sysctlConflictsCondition.Status = configv1.ConditionTrue
+	sysctlConflictsCondition.Reason = "SysctlConflicts"
+	if len(conflicts) > maxStatusListItems {
+		conflicts = append(conflicts[:maxStatusListItems], fmt.Sprintf("and %d more", len(conflicts)-maxStatusListItems))
+	}
+	sysctlConflictsCondition.Message = "Sysctls are set by more than one source: " + strings.Join(conflicts, "; ")
+
+	return sysctlConflictsCondition, nil
+}
@@ -0,0 +1,240 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+)
+
+// sysctlMachineConfig returns a MachineConfig 'name' writing files 'files'
+// (path -> data).
+func sysctlMachineConfig(name string, generation int64, files map[string]string) *mcfgv1.MachineConfig {
+	var entries []string
+	for p, data := range files {
+		entries = append(entries, fmt.Sprintf(`{"path":%q,"contents":{"source":%q}}`, p, dataurl.EncodeBytes([]byte(data))))
+	}
+	raw := fmt.Sprintf(`{"ignition":{"version":"3.2.0"},"storage":{"files":[%s]}}`, strings.Join(entries, ","))
+
+	return &mcfgv1.MachineConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name), Generation: generation},
+		Spec: mcfgv1.MachineConfigSpec{
+			Config: runtime.RawExtension{Raw: []byte(raw)},
+		},
+	}
+}
+
+func TestParseSysctlConf(t *testing.T) {
+	data := "# comment\n; comment\n\nkernel.pid_max = 4194304\n-net/ipv4/ip_forward=1\ninvalid\nvm.swappiness= 10 \n"
+	expected := [][2]string{
+		{"kernel.pid_max", "4194304"},
+		{"net.ipv4.ip_forward", "1"},
+		{"vm.swappiness", "10"},
+	}
+
+	kvs := parseSysctlConf(data)
+	if !reflect.DeepEqual(kvs, expected) {
+		t.Errorf("want: %v\nhave: %v", expected, kvs)
+	}
+}
+
+func TestTunedProfileSysctls(t *testing.T) {
+	profiles := map[string]string{
+		"base":    "[sysctl]\nvm.swappiness=10\nkernel.pid_max=4194304\n",
+		"custom":  "[main]\ninclude=base,openshift-node,${f:virt_check:a:b}\n[sysctl]\nvm.swappiness=1\n[net]\ntype=sysctl\nreplace=1\nnet.core.somaxconn=4096\n",
+		"loop-a":  "[main]\ninclude=loop-b\n[sysctl]\nvm.dirty_ratio=10\n",
+		"loop-b":  "[main]\ninclude=loop-a\n[sysctl]\nvm.dirty_ratio=20\n",
+		"invalid": "[sysctl\n",
+	}
+
+	var tests = []struct {
+		profile  string
+		expected map[string]string
+	}{
+		{
+			profile: "custom",
+			expected: map[string]string{
+				"vm.swappiness":      "1",
+				"kernel.pid_max":     "4194304",
+				"net.core.somaxconn": "4096",
+			},
+		},
+		{
+			profile:  "loop-a",
+			expected: map[string]string{"vm.dirty_ratio": "10"},
+		},
+		{
+			profile:  "openshift-node",
+			expected: map[string]string{},
+		},
+		{
+			profile:  "invalid",
+			expected: map[string]string{},
+		},
+	}
+
+	for i, tc := range tests {
+		sysctls := tunedProfileSysctls(profiles, tc.profile, map[string]bool{})
+		if !reflect.DeepEqual(sysctls, tc.expected) {
+			t.Errorf("failed test case %d (%s):\n\twant: %v\n\thave: %v", i+1, tc.profile, tc.expected, sysctls)
+		}
+	}
+}
+
+func TestMachineConfigSysctlFiles(t *testing.T) {
+	mc := sysctlMachineConfig("99-worker-sysctl", 1, map[string]string{
+		"/etc/sysctl.d/99-custom.conf": "vm.swappiness=10\n",
+		"/etc/sysctl.conf":             "kernel.pid_max=4194304\n",
+		"/etc/sysctl.d/README":         "not a sysctl configuration file\n",
+		"/etc/motd":                    "hello\n",
+	})
+	expected := map[string]string{
+		"/etc/sysctl.d/99-custom.conf": "vm.swappiness=10\n",
+		"/etc/sysctl.conf":             "kernel.pid_max=4194304\n",
+	}
+
+	files := machineConfigSysctlFiles(mc)
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("want: %v\nhave: %v", expected, files)
+	}
+
+	mc.Spec.Config.Raw = []byte("{")
+	if files := machineConfigSysctlFiles(mc); len(files) != 0 {
+		t.Errorf("want no files for an invalid Ignition config, have: %v", files)
+	}
+}
+
+func TestMachineConfigSysctls(t *testing.T) {
+	mcFiles := map[string]map[string]string{
+		"50-a": {
+			"/etc/sysctl.d/99-custom.conf": "vm.swappiness=30\n",
+			"/etc/sysctl.conf":             "vm.swappiness=60\n",
+		},
+		"99-b": {
+			"/etc/sysctl.d/99-custom.conf": "vm.swappiness=20\n",
+			"/etc/sysctl.d/10-early.conf":  "vm.swappiness=10\n",
+		},
+	}
+	expected := map[string][]sysctlSetting{
+		"vm.swappiness": {
+			{source: "MachineConfig 99-b (/etc/sysctl.d/10-early.conf)", value: "10"},
+			{source: "MachineConfig 99-b (/etc/sysctl.d/99-custom.conf)", value: "20"},
+			{source: "MachineConfig 50-a (/etc/sysctl.conf)", value: "60"},
+		},
+	}
+
+	sysctls := machineConfigSysctls(mcFiles)
+	if !reflect.DeepEqual(sysctls, expected) {
+		t.Errorf("want: %v\nhave: %v", expected, sysctls)
+	}
+}
+
+func TestMachineConfigSysctlFilesCache(t *testing.T) {
+	var mcc machineConfigSysctlFilesCache
+
+	mc := sysctlMachineConfig("99-worker-sysctl", 1, map[string]string{"/etc/sysctl.d/99-custom.conf": "vm.swappiness=10\n"})
+	if files := mcc.get(mc); files["/etc/sysctl.d/99-custom.conf"] != "vm.swappiness=10\n" {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	// Same generation: the cached files are returned without parsing the config.
+	cached := mc.DeepCopy()
+	cached.Spec.Config.Raw = []byte("{")
+	if files := mcc.get(cached); files["/etc/sysctl.d/99-custom.conf"] != "vm.swappiness=10\n" {
+		t.Errorf("want cached files for an unchanged generation, have: %v", files)
+	}
+
+	// New generation: the config is parsed again.
+	updated := sysctlMachineConfig("99-worker-sysctl", 2, map[string]string{"/etc/sysctl.d/99-custom.conf": "vm.swappiness=20\n"})
+	if files := mcc.get(updated); files["/etc/sysctl.d/99-custom.conf"] != "vm.swappiness=20\n" {
+		t.Errorf("want reparsed files for a new generation, have: %v", files)
+	}
+
+	mcc.prune(func(name string) bool { return name != "99-worker-sysctl" })
+	if len(mcc.entries) != 0 {
+		t.Errorf("want deleted MachineConfigs pruned, have: %v", mcc.entries)
+	}
+}
+
+func TestSysctlPatternMatches(t *testing.T) {
+	var tests = []struct {
+		pattern  string
+		key      string
+		expected bool
+	}{
+		{pattern: "net.core.somaxconn", key: "net.core.somaxconn", expected: true},
+		{pattern: "net/core/somaxconn", key: "net.core.somaxconn", expected: true},
+		{pattern: "net.ipv4.*", key: "net.ipv4.tcp_fin_timeout", expected: true},
+		{pattern: "net.ipv4.*", key: "net.ipv6.conf.all.forwarding", expected: false},
+		{pattern: "kernel.shm_rmid_forced", key: "kernel.shmmax", expected: false},
+	}
+
+	for i, tc := range tests {
+		if matches := sysctlPatternMatches(tc.pattern, tc.key); matches != tc.expected {
+			t.Errorf("failed test case %d (%s, %s): want %t, have %t", i+1, tc.pattern, tc.key, tc.expected, matches)
+		}
+	}
+}
+
+func TestSysctlConflict(t *testing.T) {
+	tunedValue := "10"
+	sameValue := " 10 "
+	mcSetting := []sysctlSetting{{source: "MachineConfig 99-worker (/etc/sysctl.d/99.conf)", value: "20"}}
+
+	var tests = []struct {
+		name          string
+		tuned         *string
+		sysctlD       []sysctlSetting
+		unsafe        []string
+		reapplySysctl bool
+		expected      string
+	}{
+		{
+			name:     "TuneD only",
+			tuned:    &tunedValue,
+			expected: "",
+		},
+		{
+			name:          "TuneD and sysctl.d agree",
+			tuned:         &sameValue,
+			sysctlD:       []sysctlSetting{{source: "MachineConfig 99-worker (/etc/sysctl.d/99.conf)", value: "10"}},
+			reapplySysctl: true,
+			expected:      "",
+		},
+		{
+			name:          "sysctl.d wins with reapply_sysctl",
+			tuned:         &tunedValue,
+			sysctlD:       mcSetting,
+			reapplySysctl: true,
+			expected:      "vm.swappiness is set by TuneD profile custom (10) and MachineConfig 99-worker (/etc/sysctl.d/99.conf) (20); 20 from MachineConfig 99-worker (/etc/sysctl.d/99.conf) wins",
+		},
+		{
+			name:     "TuneD wins without reapply_sysctl",
+			tuned:    &tunedValue,
+			sysctlD:  mcSetting,
+			expected: "vm.swappiness is set by TuneD profile custom (10) and MachineConfig 99-worker (/etc/sysctl.d/99.conf) (20); 10 from TuneD profile custom wins",
+		},
+		{
+			name:     "allowedUnsafeSysctls always conflict",
+			tuned:    &tunedValue,
+			unsafe:   []string{"unsafe"},
+			expected: "vm.swappiness is set by TuneD profile custom (10) and KubeletConfig unsafe (allowedUnsafeSysctls); 10 from TuneD profile custom wins; Pods may override it in their own namespaces",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conflict := sysctlConflict("vm.swappiness", "custom", tc.tuned, tc.sysctlD, tc.unsafe, tc.reapplySysctl)
+			if conflict != tc.expected {
+				t.Errorf("want: %q\nhave: %q", tc.expected, conflict)
+			}
+		})
+	}
+}
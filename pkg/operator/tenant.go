@@ -0,0 +1,472 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	ntoconfig "github.com/openshift/cluster-node-tuning-operator/pkg/config"
+)
+
+const (
+	// reasons for the TunedAccepted condition of tenant Tuned CRs
+	tenantReasonAccepted          = "Accepted"
+	tenantReasonPartiallyAccepted = "PartiallyAccepted"
+	tenantReasonNotEntitled       = "NamespaceNotEntitled"
+	tenantReasonInvalid           = "Invalid"
+	tenantReasonNotAllowed        = "NotAllowed"
+)
+
+// tenantTuned is a tenant Tuned CR accepted for merging into Node profiles.
+type tenantTuned struct {
+	// tenant Tuned CR with its profiles renamed by tenantProfileName() and
+	// fragments not allowed by the Namespace's entitlements dropped
+	tuned *tunedv1.Tuned
+	// Nodes the Namespace of the tenant Tuned CR is entitled to tune
+	nodeSelector labels.Selector
+	// human-readable descriptions of the dropped fragments
+	dropped []string
+}
+
+// tenantTunedError explains why a tenant Tuned CR was rejected.
+type tenantTunedError struct {
+	reason  string
+	message string
+}
+
+func (e *tenantTunedError) Error() string {
+	return e.message
+}
+
+func tenantTunedErrorf(reason, format string, a ...interface{}) *tenantTunedError {
+	return &tenantTunedError{reason: reason, message: fmt.Sprintf(format, a...)}
+}
+
+// isTenantTuned returns true if Tuned 'tuned' lives outside of the operator's namespace.
+func isTenantTuned(tuned *tunedv1.Tuned) bool {
+	return tuned.Namespace != ntoconfig.WatchNamespace()
+}
+
+// tenantProfileName returns the name of TuneD profile 'name' from Namespace
+// 'namespace' in the rendered Tuned CR.  Namespace names cannot contain '_',
+// so profiles from different Namespaces never collide with one another.
+func tenantProfileName(namespace, name string) string {
+	return namespace + "_" + name
+}
+
+// tenantTuneds returns all accepted tenant Tuned CRs sorted by their namespace/name.
+func (pc *ProfileCalculator) tenantTuneds() ([]*tenantTuned, error) {
+	var tenants []*tenantTuned
+
+	if pc.listers.TenantTuneds == nil {
+		return nil, nil
+	}
+
+	tunedList, err := pc.listers.TenantTuneds.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant Tuned: %v", err)
+	}
+	sort.Slice(tunedList, func(i, j int) bool {
+		if tunedList[i].Namespace != tunedList[j].Namespace {
+			return tunedList[i].Namespace < tunedList[j].Namespace
+		}
+		return tunedList[i].Name < tunedList[j].Name
+	})
+
+	for _, tuned := range tunedList {
+		if !isTenantTuned(tuned) {
+			continue
+		}
+		tenant, err := pc.tenantTunedValidate(tuned)
+		if err != nil {
+			klog.V(2).Infof("ignoring tenant Tuned %s/%s: %v", tuned.Namespace, tuned.Name, err)
+			continue
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, nil
+}
+
+// tenantTunedValidate checks tenant Tuned 'tuned' against the entitlements
+// cluster administrators granted to the Tuned's Namespace by annotating it.
+//
+// Returns
+// * the accepted tenant Tuned, see tenantTunedFilter()
+// * a *tenantTunedError explaining why the Tuned was rejected, other errors
+//   for failures to validate the Tuned
+func (pc *ProfileCalculator) tenantTunedValidate(tuned *tunedv1.Tuned) (*tenantTuned, error) {
+	ns, err := pc.listers.Namespaces.Get(tuned.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Namespace %s: %v", tuned.Namespace, err)
+	}
+
+	return tenantTunedFilter(tuned, ns)
+}
+
+// tenantTunedFilter checks tenant Tuned 'tuned' against the entitlements
+// granted to it by the annotations of its Namespace 'ns'.  The Tuned is
+// rejected as a whole only if the Namespace is not entitled to tune any Nodes,
+// the Tuned sets managementState or it is malformed.  Otherwise, the fragments
+// of the Tuned the Namespace is not entitled to, i.e. TuneD profile sections
+// and options outside of the allowlists, boot parameter requirements and
+// recommend rules using restricted features, are dropped and the permitted
+// fragments accepted.
+//
+// Returns
+// * the accepted tenant Tuned with its profiles renamed by tenantProfileName()
+// * a *tenantTunedError explaining why the Tuned was rejected
+func tenantTunedFilter(tuned *tunedv1.Tuned, ns *corev1.Namespace) (*tenantTuned, error) {
+	selector, ok := ns.Annotations[tunedv1.TenantNodeSelectorAnnotationKey]
+	if !ok {
+		return nil, tenantTunedErrorf(tenantReasonNotEntitled, "Namespace %s is not entitled to tune any Nodes: annotation %s not set",
+			ns.Name, tunedv1.TenantNodeSelectorAnnotationKey)
+	}
+	nodeSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, tenantTunedErrorf(tenantReasonNotEntitled, "Namespace %s annotation %s is not a valid label selector: %v",
+			ns.Name, tunedv1.TenantNodeSelectorAnnotationKey, err)
+	}
+	allowedPlugins := map[string]bool{}
+	for _, plugin := range annotationList(ns.Annotations[tunedv1.TenantAllowedPluginsAnnotationKey]) {
+		allowedPlugins[plugin] = true
+	}
+	allowedSysctls := annotationList(ns.Annotations[tunedv1.TenantAllowedSysctlsAnnotationKey])
+
+	if tuned.Spec.ManagementState != "" {
+		return nil, tenantTunedErrorf(tenantReasonNotAllowed, "managementState is not allowed in tenant Tuned")
+	}
+
+	tuned = tuned.DeepCopy() // never update the objects from cache
+	tenant := &tenantTuned{tuned: tuned, nodeSelector: nodeSelector}
+	profiles := map[string]bool{}
+	for i, profile := range tuned.Spec.Profile {
+		if profile.Name == nil || profile.Data == nil {
+			return nil, tenantTunedErrorf(tenantReasonInvalid, "profile %d is missing its name or data", i)
+		}
+		if *profile.Name == "" || strings.ContainsAny(*profile.Name, " \t\n/") {
+			return nil, tenantTunedErrorf(tenantReasonInvalid, "invalid profile name %q", *profile.Name)
+		}
+		if profile.Requirements != nil && len(profile.Requirements.BootParameters) > 0 {
+			tenant.dropped = append(tenant.dropped, fmt.Sprintf("profile %s: boot parameter requirements", *profile.Name))
+			tuned.Spec.Profile[i].Requirements.BootParameters = nil
+		}
+		data, dropped, err := tenantProfileDataFilter(*profile.Data, allowedPlugins, allowedSysctls)
+		if err != nil {
+			return nil, tenantTunedErrorf(tenantReasonInvalid, "profile %s: %v", *profile.Name, err)
+		}
+		for _, d := range dropped {
+			tenant.dropped = append(tenant.dropped, fmt.Sprintf("profile %s: %s", *profile.Name, d))
+		}
+		tuned.Spec.Profile[i].Data = &data
+		profiles[*profile.Name] = true
+		name := tenantProfileName(tuned.Namespace, *profile.Name)
+		tuned.Spec.Profile[i].Name = &name
+	}
+
+	var recommends []tunedv1.TunedRecommend
+	for i, recommend := range tuned.Spec.Recommend {
+		if recommend.Profile == nil || !profiles[*recommend.Profile] {
+			return nil, tenantTunedErrorf(tenantReasonInvalid, "recommend rule %d does not reference a profile defined in this Tuned", i)
+		}
+		if d := tenantRecommendForbidden(recommend); d != "" {
+			tenant.dropped = append(tenant.dropped, fmt.Sprintf("recommend rule %d: %s", i, d))
+			continue
+		}
+		name := tenantProfileName(tuned.Namespace, *recommend.Profile)
+		recommend.Profile = &name
+		recommends = append(recommends, recommend)
+	}
+	tuned.Spec.Recommend = recommends
+
+	return tenant, nil
+}
+
+// tenantRecommendForbidden returns a description of the feature tenant Tuned
+// recommend rule 'recommend' uses that is not offered to tenants or an empty
+// string if the rule is allowed.
+func tenantRecommendForbidden(recommend tunedv1.TunedRecommend) string {
+	if recommend.MachineConfigLabels != nil {
+		return "machineConfigLabels"
+	}
+	if !reflect.DeepEqual(recommend.Operand, tunedv1.OperandConfig{}) {
+		return "operand configuration"
+	}
+	if tenantMatchUsesPodLabels(recommend.Match) {
+		return "Pod label matching"
+	}
+	return ""
+}
+
+// tenantMatchUsesPodLabels returns true if any of the TunedMatch's tree-like
+// definition of profile matching rules 'match' uses Pod labels.  Pod label
+// matching tracks all Pods cluster-wide and is not offered to tenants.
+func tenantMatchUsesPodLabels(match []tunedv1.TunedMatch) bool {
+	for _, m := range match {
+		if m.Type != nil && *m.Type == "pod" {
+			return true
+		}
+		if tenantMatchUsesPodLabels(m.Match) {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantProfileDataFilter drops the parts of TuneD profile 'data' using
+// TuneD plugins other than 'allowedPlugins', setting sysctls other than
+// 'allowedSysctls' or using TuneD features that could escape the allowlists,
+// such as profile inclusion, variables and built-in functions.
+//
+// Returns
+// * the TuneD profile data with only the permitted sections and options
+// * human-readable descriptions of the dropped sections and options
+// * an error if 'data' cannot be parsed
+func tenantProfileDataFilter(data string, allowedPlugins map[string]bool, allowedSysctls []string) (string, []string, error) {
+	var (
+		b       strings.Builder
+		dropped []string
+	)
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowPythonMultilineValues: false, IgnoreContinuation: true}, []byte(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse profile data: %v", err)
+	}
+
+	for _, section := range cfg.Sections() {
+		keys := section.Keys()
+
+		if section.Name() == ini.DefaultSection {
+			for _, key := range keys {
+				dropped = append(dropped, fmt.Sprintf("option %s outside of a section", key.Name()))
+			}
+			continue
+		}
+
+		if section.Name() != "main" {
+			plugin := section.Name()
+			if section.HasKey("type") {
+				plugin = section.Key("type").String()
+			}
+			if !allowedPlugins[plugin] {
+				dropped = append(dropped, fmt.Sprintf("[%s] TuneD plugin %s", section.Name(), plugin))
+				continue
+			}
+		}
+
+		var lines []string
+		for _, key := range keys {
+			if strings.ContainsAny(key.Value(), "\r\n") {
+				// Multi-line values (e.g. triple-quoted) written back verbatim could inject sections.
+				dropped = append(dropped, fmt.Sprintf("[%s] %s: multi-line value", section.Name(), key.Name()))
+				continue
+			}
+			if strings.Contains(key.Value(), "${") {
+				dropped = append(dropped, fmt.Sprintf("[%s] %s: TuneD variables and built-in functions", section.Name(), key.Name()))
+				continue
+			}
+			if !tenantOptionAllowed(section, key.Name(), allowedSysctls) {
+				dropped = append(dropped, fmt.Sprintf("[%s] %s", section.Name(), key.Name()))
+				continue
+			}
+			lines = append(lines, key.Name()+"="+key.Value())
+		}
+		if len(lines) == 0 && section.Name() != "main" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]\n", section.Name())
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return b.String(), dropped, nil
+}
+
+// tenantOptionAllowed returns true if option 'option' of TuneD profile section
+// 'section' of an allowed plugin may be set by tenants.  Only the summary is
+// allowed in section [main], in particular profile inclusion is not, and only
+// sysctls from 'allowedSysctls' in sections of the sysctl plugin.  The 'replace'
+// option is not allowed as it would discard the sysctls of the merged instance
+// set by cluster administrators.
+func tenantOptionAllowed(section *ini.Section, option string, allowedSysctls []string) bool {
+	if section.Name() == "main" {
+		return option == "summary"
+	}
+	plugin := section.Name()
+	if section.HasKey("type") {
+		plugin = section.Key("type").String()
+	}
+	if plugin != "sysctl" {
+		return true
+	}
+	if option == "type" {
+		return true
+	}
+	return sysctlAllowed(option, allowedSysctls)
+}
+
+// sysctlAllowed returns true if sysctl 'sysctl' is on the 'allowedSysctls' list.
+// Sysctls on the list ending with '*' match any sysctl with the same prefix.
+func sysctlAllowed(sysctl string, allowedSysctls []string) bool {
+	sysctl = strings.ReplaceAll(sysctl, "/", ".")
+	for _, allowed := range allowedSysctls {
+		allowed = strings.ReplaceAll(allowed, "/", ".")
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(sysctl, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+		if sysctl == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationList splits a comma-separated annotation value into a slice of
+// non-empty, whitespace-trimmed strings.
+func annotationList(value string) []string {
+	var ret []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// tenantProfiles returns the names of tenant TuneD profiles from 'tenants'
+// selected for Node 'nodeName'.  At most one profile, the one with the highest
+// priority matching the Node with an active schedule, is selected from each
+// tenant Tuned and only if the Node is among the Nodes the tenant's Namespace
+// is entitled to tune.  The next schedule transitions of the tenant recommend
+// rules recalculate the Node's profile as those of the operator's namespace do.
+func (pc *ProfileCalculator) tenantProfiles(nodeName string, tenants []*tenantTuned) []string {
+	var profiles []string
+
+	nodeLabels := labels.Set(pc.state.nodeLabels[nodeName])
+	for _, tenant := range tenants {
+		if !tenant.nodeSelector.Matches(nodeLabels) {
+			continue
+		}
+		for _, recommend := range tunedRecommend([]*tunedv1.Tuned{tenant.tuned}) {
+			if matches, _ := pc.profileMatches(recommend.Match, nodeName); matches {
+				if active, _ := pc.scheduleActive(nodeName, recommend.Schedule); !active {
+					continue
+				}
+				profiles = append(profiles, *recommend.Profile)
+				break
+			}
+		}
+	}
+
+	return profiles
+}
+
+// tenantProfilesMerge appends tenant TuneD profiles selected for Node 'nodeName'
+// to TuneD profile 'profile'.  TuneD merges space-separated profiles from left
+// to right, so the tenant profiles can only add to or override the settings of
+// the profile selected by cluster administrators within their allowlists.
+func (pc *ProfileCalculator) tenantProfilesMerge(nodeName string, profile string) string {
+	if pc.rollback != nil {
+		// Revisions record the recommend rules of the Tuned CRs in the operator's namespace only.
+		return profile
+	}
+
+	tenants, err := pc.tenantTuneds()
+	if err != nil {
+		klog.Errorf("failed to merge tenant profiles for Node %s: %v", nodeName, err)
+		return profile
+	}
+
+	tenantProfiles := pc.tenantProfiles(nodeName, tenants)
+	if len(tenantProfiles) == 0 {
+		return profile
+	}
+
+	return profile + " " + strings.Join(tenantProfiles, " ")
+}
+
+// syncTenantTuned validates tenant Tuned 'namespace/name' and reports the
+// result in its TunedAccepted status condition.
+func (c *Controller) syncTenantTuned(namespace, name string) error {
+	tuned, err := c.listers.TenantTuneds.Tuneds(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get Tuned %s/%s: %v", namespace, name, err)
+	}
+
+	condition := tunedv1.TunedStatusCondition{
+		Type:   tunedv1.TunedAccepted,
+		Status: corev1.ConditionTrue,
+		Reason: tenantReasonAccepted,
+		Message: fmt.Sprintf("Tuned profiles merged into the profiles of Nodes matching Namespace %s annotation %s",
+			namespace, tunedv1.TenantNodeSelectorAnnotationKey),
+	}
+	tenant, err := c.pc.tenantTunedValidate(tuned)
+	if err == nil && len(tenant.dropped) > 0 {
+		condition.Reason = tenantReasonPartiallyAccepted
+		condition.Message = fmt.Sprintf("Tuned profiles merged into the profiles of Nodes matching Namespace %s annotation %s without the fragments the Namespace is not entitled to: %s",
+			namespace, tunedv1.TenantNodeSelectorAnnotationKey, strings.Join(tenant.dropped, "; "))
+	}
+	if err != nil {
+		tErr, ok := err.(*tenantTunedError)
+		if !ok {
+			return err
+		}
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = tErr.reason
+		condition.Message = tErr.message
+	}
+
+	for _, cond := range tuned.Status.Conditions {
+		if cond.Type == condition.Type && cond.Status == condition.Status &&
+			cond.Reason == condition.Reason && cond.Message == condition.Message {
+			klog.V(2).Infof("syncTenantTuned(): no need to update Tuned %s/%s status", namespace, name)
+			return nil
+		}
+	}
+
+	tuned = tuned.DeepCopy() // never update the objects from cache
+	condition.LastTransitionTime = metav1.Now()
+	tuned.Status.Conditions = []tunedv1.TunedStatusCondition{condition}
+
+	klog.V(2).Infof("syncTenantTuned(): updating Tuned %s/%s status", namespace, name)
+	_, err = c.clients.Tuned.TunedV1().Tuneds(namespace).Update(context.TODO(), tuned, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update Tuned %s/%s: %v", namespace, name, err)
+	}
+	if condition.Status == corev1.ConditionTrue {
+		klog.Infof("accepted tenant Tuned %s/%s", namespace, name)
+	} else {
+		klog.Infof("rejected tenant Tuned %s/%s: %s", namespace, name, condition.Message)
+	}
+
+	return nil
+}
+
+// enqueueTenantTuneds enqueues validation of all tenant Tuned CRs in Namespace 'namespace'.
+func (c *Controller) enqueueTenantTuneds(namespace string) error {
+	tunedList, err := c.listers.TenantTuneds.Tuneds(namespace).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list Tuned in Namespace %s: %v", namespace, err)
+	}
+	for _, tuned := range tunedList {
+		c.workqueue.AddRateLimited(wqKey{kind: wqKindTenantTuned, namespace: namespace, name: tuned.Name})
+	}
+	return nil
+}
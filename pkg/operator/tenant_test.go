@@ -0,0 +1,329 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestTenantProfileDataFilter(t *testing.T) {
+	var tests = []struct {
+		name           string
+		data           string
+		allowedPlugins []string
+		allowedSysctls []string
+		expectedData   string
+		expectedDrops  int
+	}{
+		{
+			name:           "allowed plugin and sysctls",
+			data:           "[main]\nsummary=tenant\n[sysctl]\nnet.core.somaxconn=4096\nnet.ipv4.tcp_fin_timeout=30\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn", "net.ipv4.tcp_*"},
+			expectedData:   "[main]\nsummary=tenant\n[sysctl]\nnet.core.somaxconn=4096\nnet.ipv4.tcp_fin_timeout=30\n",
+		},
+		{
+			name:           "forbidden sysctl dropped, allowed sysctl kept",
+			data:           "[sysctl]\nnet.core.somaxconn=4096\nkernel.panic=1\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[sysctl]\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "sysctl allowlist with slashes",
+			data:           "[sysctl]\nnet/core/somaxconn=4096\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[sysctl]\nnet/core/somaxconn=4096\n",
+		},
+		{
+			name:           "forbidden plugin section dropped",
+			data:           "[sysctl]\nnet.core.somaxconn=4096\n[bootloader]\ncmdline=+nosmt\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[sysctl]\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "plugin instance named by type",
+			data:           "[my_sysctl]\ntype=sysctl\nnet.core.somaxconn=4096\n[my_script]\ntype=script\nscript=/bin/true\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[my_sysctl]\ntype=sysctl\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "include dropped",
+			data:           "[main]\nsummary=tenant\ninclude=openshift-node-performance\n[sysctl]\nnet.core.somaxconn=4096\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[main]\nsummary=tenant\n[sysctl]\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "variables dropped",
+			data:           "[variables]\nfoo=1\n[sysctl]\nnet.core.somaxconn=${f:exec:/bin/sh}\nnet.ipv4.tcp_fin_timeout=30\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.*"},
+			expectedData:   "[sysctl]\nnet.ipv4.tcp_fin_timeout=30\n",
+			expectedDrops:  2,
+		},
+		{
+			name:           "non-sysctl allowed plugin keeps all options",
+			data:           "[vm]\ntransparent_hugepages=never\n",
+			allowedPlugins: []string{"vm"},
+			expectedData:   "[vm]\ntransparent_hugepages=never\n",
+		},
+		{
+			name:           "triple-quoted multi-line value dropped",
+			data:           "[sysctl]\nnet.core.somaxconn=\"\"\"4096\n[main]\ninclude=openshift-node-performance\n[bootloader]\ncmdline=+nosmt\"\"\"\nnet.ipv4.tcp_fin_timeout=30\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.*"},
+			expectedData:   "[sysctl]\nnet.ipv4.tcp_fin_timeout=30\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "indented continuation lines are not values",
+			data:           "[sysctl]\nnet.core.somaxconn=4096\n  [bootloader]\n  cmdline=+nosmt\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.*"},
+			expectedData:   "[sysctl]\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:           "replace dropped from sysctl instance",
+			data:           "[sysctl]\ntype=sysctl\nreplace=1\nnet.core.somaxconn=4096\n",
+			allowedPlugins: []string{"sysctl"},
+			allowedSysctls: []string{"net.core.somaxconn"},
+			expectedData:   "[sysctl]\ntype=sysctl\nnet.core.somaxconn=4096\n",
+			expectedDrops:  1,
+		},
+		{
+			name:          "no entitlements",
+			data:          "[sysctl]\nnet.core.somaxconn=4096\n",
+			expectedData:  "",
+			expectedDrops: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		allowedPlugins := map[string]bool{}
+		for _, plugin := range tc.allowedPlugins {
+			allowedPlugins[plugin] = true
+		}
+		data, dropped, err := tenantProfileDataFilter(tc.data, allowedPlugins, tc.allowedSysctls)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if data != tc.expectedData {
+			t.Errorf("%s:\n\twant: %q\n\thave: %q", tc.name, tc.expectedData, data)
+		}
+		if len(dropped) != tc.expectedDrops {
+			t.Errorf("%s: want %d dropped fragments, have %d: %v", tc.name, tc.expectedDrops, len(dropped), dropped)
+		}
+	}
+}
+
+func TestTenantTunedFilter(t *testing.T) {
+	entitled := map[string]string{
+		tunedv1.TenantNodeSelectorAnnotationKey:   "tenant=a",
+		tunedv1.TenantAllowedPluginsAnnotationKey: "sysctl",
+		tunedv1.TenantAllowedSysctlsAnnotationKey: "net.core.somaxconn",
+	}
+	podType, podLabel := "pod", "app"
+
+	var tests = []struct {
+		name               string
+		annotations        map[string]string
+		spec               tunedv1.TunedSpec
+		expectedReason     string
+		expectedRecommends []string
+		expectedDrops      int
+	}{
+		{
+			name:           "namespace not entitled",
+			spec:           tenantTunedSpec(),
+			expectedReason: tenantReasonNotEntitled,
+		},
+		{
+			name:           "invalid node selector",
+			annotations:    map[string]string{tunedv1.TenantNodeSelectorAnnotationKey: "tenant in ("},
+			spec:           tenantTunedSpec(),
+			expectedReason: tenantReasonNotEntitled,
+		},
+		{
+			name:               "accepted",
+			annotations:        entitled,
+			spec:               tenantTunedSpec(),
+			expectedRecommends: []string{"tenant-a_somaxconn"},
+		},
+		{
+			name:        "managementState rejects the Tuned",
+			annotations: entitled,
+			spec: func() tunedv1.TunedSpec {
+				spec := tenantTunedSpec()
+				spec.ManagementState = "Removed"
+				return spec
+			}(),
+			expectedReason: tenantReasonNotAllowed,
+		},
+		{
+			name:        "recommend rule referencing unknown profile",
+			annotations: entitled,
+			spec: func() tunedv1.TunedSpec {
+				spec := tenantTunedSpec()
+				other := "other"
+				spec.Recommend[0].Profile = &other
+				return spec
+			}(),
+			expectedReason: tenantReasonInvalid,
+		},
+		{
+			name:        "forbidden recommend rules dropped",
+			annotations: entitled,
+			spec: func() tunedv1.TunedSpec {
+				spec := tenantTunedSpec()
+				rule := spec.Recommend[0]
+				withMCLabels := rule
+				withMCLabels.MachineConfigLabels = map[string]string{"machineconfiguration.openshift.io/role": "worker"}
+				withPodMatch := rule
+				withPodMatch.Match = []tunedv1.TunedMatch{{Label: &podLabel, Type: &podType}}
+				spec.Recommend = append(spec.Recommend, withMCLabels, withPodMatch)
+				return spec
+			}(),
+			expectedRecommends: []string{"tenant-a_somaxconn"},
+			expectedDrops:      2,
+		},
+		{
+			name:        "boot parameters and forbidden sysctls dropped",
+			annotations: entitled,
+			spec: func() tunedv1.TunedSpec {
+				spec := tenantTunedSpec()
+				data := "[sysctl]\nnet.core.somaxconn=4096\nkernel.panic=1\n"
+				spec.Profile[0].Data = &data
+				spec.Profile[0].Requirements = &tunedv1.TunedProfileRequirements{BootParameters: []string{"nosmt"}}
+				return spec
+			}(),
+			expectedRecommends: []string{"tenant-a_somaxconn"},
+			expectedDrops:      2,
+		},
+	}
+
+	for _, tc := range tests {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Annotations: tc.annotations}}
+		tuned := &tunedv1.Tuned{
+			ObjectMeta: metav1.ObjectMeta{Name: "tuned", Namespace: "tenant-a"},
+			Spec:       tc.spec,
+		}
+		tenant, err := tenantTunedFilter(tuned, ns)
+		if tc.expectedReason != "" {
+			tErr, ok := err.(*tenantTunedError)
+			if !ok || tErr.reason != tc.expectedReason {
+				t.Errorf("%s: want rejection with reason %s, have %v", tc.name, tc.expectedReason, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		var recommends []string
+		for _, recommend := range tenant.tuned.Spec.Recommend {
+			recommends = append(recommends, *recommend.Profile)
+		}
+		if !reflect.DeepEqual(recommends, tc.expectedRecommends) {
+			t.Errorf("%s: want recommended profiles %v, have %v", tc.name, tc.expectedRecommends, recommends)
+		}
+		if len(tenant.dropped) != tc.expectedDrops {
+			t.Errorf("%s: want %d dropped fragments, have %d: %v", tc.name, tc.expectedDrops, len(tenant.dropped), tenant.dropped)
+		}
+		if *tenant.tuned.Spec.Profile[0].Name != "tenant-a_somaxconn" {
+			t.Errorf("%s: profile not renamed: %s", tc.name, *tenant.tuned.Spec.Profile[0].Name)
+		}
+		if *tuned.Spec.Profile[0].Name != "somaxconn" {
+			t.Errorf("%s: input Tuned modified", tc.name)
+		}
+	}
+}
+
+func tenantTunedSpec() tunedv1.TunedSpec {
+	name := "somaxconn"
+	data := "[sysctl]\nnet.core.somaxconn=4096\n"
+	priority := uint64(20)
+	return tunedv1.TunedSpec{
+		Profile:   []tunedv1.TunedProfile{{Name: &name, Data: &data}},
+		Recommend: []tunedv1.TunedRecommend{{Profile: &name, Priority: &priority}},
+	}
+}
+
+func TestTenantProfilesSchedule(t *testing.T) {
+	now := time.Now().UTC()
+	window := func(start, end time.Duration) []tunedv1.TunedScheduleWindow {
+		return []tunedv1.TunedScheduleWindow{{Start: now.Add(start).Format("15:04"), End: now.Add(end).Format("15:04")}}
+	}
+	tenant := func(recommend ...tunedv1.TunedRecommend) *tenantTuned {
+		return &tenantTuned{
+			tuned:        &tunedv1.Tuned{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "tuned"}, Spec: tunedv1.TunedSpec{Recommend: recommend}},
+			nodeSelector: labels.Everything(),
+		}
+	}
+	scheduled := func(profile string, priority uint64, schedule []tunedv1.TunedScheduleWindow) tunedv1.TunedRecommend {
+		return tunedv1.TunedRecommend{Profile: &profile, Priority: &priority, Schedule: schedule}
+	}
+
+	var tests = []struct {
+		name         string
+		tenants      []*tenantTuned
+		expected     []string
+		expectedNext bool
+	}{
+		{
+			name:     "unscheduled rule",
+			tenants:  []*tenantTuned{tenant(scheduled("tenant-a_always", 10, nil))},
+			expected: []string{"tenant-a_always"},
+		},
+		{
+			name:         "active schedule",
+			tenants:      []*tenantTuned{tenant(scheduled("tenant-a_active", 10, window(-2*time.Hour, 2*time.Hour)))},
+			expected:     []string{"tenant-a_active"},
+			expectedNext: true,
+		},
+		{
+			name:         "inactive schedule",
+			tenants:      []*tenantTuned{tenant(scheduled("tenant-a_inactive", 10, window(2*time.Hour, 3*time.Hour)))},
+			expectedNext: true,
+		},
+		{
+			name: "inactive schedule falls back to a lower priority rule",
+			tenants: []*tenantTuned{tenant(
+				scheduled("tenant-a_inactive", 10, window(2*time.Hour, 3*time.Hour)),
+				scheduled("tenant-a_always", 20, nil),
+			)},
+			expected:     []string{"tenant-a_always"},
+			expectedNext: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := NewProfileCalculator(nil, nil)
+			pc.state.nodeLabels["node"] = map[string]string{}
+
+			profiles := pc.tenantProfiles("node", tc.tenants)
+			if !reflect.DeepEqual(profiles, tc.expected) {
+				t.Errorf("want: %v\nhave: %v", tc.expected, profiles)
+			}
+			if _, ok := pc.state.scheduleNext["node"]; ok != tc.expectedNext {
+				t.Errorf("want next schedule transition recorded: %t, have: %t", tc.expectedNext, ok)
+			}
+		})
+	}
+}
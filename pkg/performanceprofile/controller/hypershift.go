@@ -0,0 +1,379 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	performancev2 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/performanceprofile/v2"
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components/manifestset"
+	profileutil "github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components/profile"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serros "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// label on management cluster ConfigMaps with an embedded PerformanceProfile
+	performanceProfileConfigMapLabel = "hypershift.openshift.io/performanceprofile-config"
+	// ConfigMap data key of the embedded PerformanceProfile
+	performanceProfileConfigMapConfigKey = "tuning"
+	// label on management cluster ConfigMaps with the PerformanceProfile status of the NodePool
+	// named by the util.HyperShiftNodePoolLabel label, one for every PerformanceProfile ConfigMap
+	// of the NodePool
+	performanceProfileStatusConfigMapLabel = "hypershift.openshift.io/nto-generated-performance-profile-status"
+	// ConfigMap data key of the PerformanceProfile status
+	performanceProfileStatusConfigMapKey = "status"
+	// label on hosted cluster RuntimeClasses and management cluster ConfigMaps with the name of the
+	// ConfigMap they were rendered from
+	performanceProfileSourceLabel = "hypershift.openshift.io/performanceprofile-config-source"
+
+	conditionFailedToParsePerformanceProfile = "ParsingPerformanceProfileFailed"
+)
+
+// PerformanceProfileHyperShiftReconciler reconciles PerformanceProfiles embedded in NodePool-referenced
+// ConfigMaps in management's cluster hosted namespace.  The MachineConfig and KubeletConfig components
+// are rendered into ConfigMaps the NodePool controller rolls out, the Tuned component into a ConfigMap
+// the operator syncs into the hosted cluster and the RuntimeClass is created in the hosted cluster.
+type PerformanceProfileHyperShiftReconciler struct {
+	// reconciler of the hosted cluster objects
+	PerformanceProfileReconciler
+	// client of management's cluster hosted namespace
+	ManagementClient client.Client
+	// namespace of the hosted control plane in the management cluster
+	Namespace string
+}
+
+// SetupWithManager creates a new PerformanceProfile HyperShift Controller watching ConfigMaps in
+// management cluster 'managementCluster' and adds it to the Manager of the hosted cluster.
+func (r *PerformanceProfileHyperShiftReconciler) SetupWithManager(mgr ctrl.Manager, managementCluster cluster.Cluster) error {
+	// the Manager starts the management cluster cache
+	if err := mgr.Add(managementCluster); err != nil {
+		return err
+	}
+
+	c, err := controller.New("performanceprofile-hypershift", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	configMapPredicates := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.Namespace && obj.GetLabels()[performanceProfileConfigMapLabel] == "true"
+	})
+	if err := c.Watch(
+		source.NewKindWithCache(&corev1.ConfigMap{}, managementCluster.GetCache()),
+		&handler.EnqueueRequestForObject{},
+		configMapPredicates); err != nil {
+		return err
+	}
+
+	tunedProfilePredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !validateUpdateEvent(&e) {
+				return false
+			}
+
+			tunedProfileOld := e.ObjectOld.(*tunedv1.Profile)
+			tunedProfileNew := e.ObjectNew.(*tunedv1.Profile)
+
+			return !reflect.DeepEqual(tunedProfileOld.Status.Conditions, tunedProfileNew.Status.Conditions)
+		},
+	}
+	return c.Watch(
+		&source.Kind{Type: &tunedv1.Profile{}},
+		handler.EnqueueRequestsFromMapFunc(r.tunedProfileToConfigMaps),
+		tunedProfilePredicates)
+}
+
+// tunedProfileToConfigMaps maps a hosted cluster Tuned Profile to the PerformanceProfile ConfigMaps
+// referenced by the NodePool of the Profile's Node.
+func (r *PerformanceProfileHyperShiftReconciler) tunedProfileToConfigMaps(tunedProfileObj client.Object) []reconcile.Request {
+	node := &corev1.Node{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: tunedProfileObj.GetName()}, node); err != nil {
+		klog.Errorf("failed to get Node %q: %v", tunedProfileObj.GetName(), err)
+		return nil
+	}
+	nodePoolName := node.Labels[util.HyperShiftNodePoolLabel]
+	if nodePoolName == "" {
+		return nil
+	}
+
+	cms := &corev1.ConfigMapList{}
+	if err := r.ManagementClient.List(context.TODO(), cms, client.InNamespace(r.Namespace), client.MatchingLabels{performanceProfileConfigMapLabel: "true"}); err != nil {
+		klog.Errorf("failed to get PerformanceProfile ConfigMaps: %v", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range cms.Items {
+		if util.ParseNamespacedName(cms.Items[i].Annotations[util.HyperShiftNodePoolLabel]) == nodePoolName {
+			requests = append(requests, reconcile.Request{NamespacedName: namespacedName(&cms.Items[i])})
+		}
+	}
+	return requests
+}
+
+// Reconcile renders the components of the PerformanceProfile embedded in a management cluster ConfigMap
+// for the NodePool referencing the ConfigMap and reports the PerformanceProfile status of the NodePool.
+func (r *PerformanceProfileHyperShiftReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	klog.Info("Reconciling PerformanceProfile ConfigMap")
+	cm := &corev1.ConfigMap{}
+	err := r.ManagementClient.Get(ctx, req.NamespacedName, cm)
+	if err != nil {
+		if k8serros.IsNotFound(err) {
+			// The ConfigMaps rendered from the PerformanceProfile ConfigMap are garbage collected,
+			// the hosted cluster objects need to be removed.
+			return reconcile.Result{}, r.deleteRuntimeClassesHyperShift(req.Name)
+		}
+		return reconcile.Result{}, err
+	}
+
+	nodePoolNamespacedName, ok := cm.Annotations[util.HyperShiftNodePoolLabel]
+	if !ok {
+		klog.Warningf("ignoring PerformanceProfile ConfigMap %s without annotation %s", cm.Name, util.HyperShiftNodePoolLabel)
+		return reconcile.Result{}, nil
+	}
+	nodePoolName := util.ParseNamespacedName(nodePoolNamespacedName)
+
+	profile, err := parsePerformanceProfileConfigMap(cm)
+	if err != nil {
+		klog.Errorf("failed to parse PerformanceProfile ConfigMap %s: %v", cm.Name, err)
+		conditions := r.getDegradedConditions(conditionFailedToParsePerformanceProfile, err.Error())
+		return reconcile.Result{}, r.updateStatusHyperShift(cm, nodePoolName, nil, conditions)
+	}
+
+	if err := r.applyComponentsHyperShift(cm, nodePoolName, profile); err != nil {
+		klog.Errorf("failed to deploy performance profile %q components: %v", profile.Name, err)
+		conditions := r.getDegradedConditions(conditionReasonComponentsCreationFailed, err.Error())
+		if err := r.updateStatusHyperShift(cm, nodePoolName, profile, conditions); err != nil {
+			klog.Errorf("failed to update performance profile %q status: %v", profile.Name, err)
+		}
+		return reconcile.Result{}, err
+	}
+
+	// get tuned profile degraded conditions of the NodePool's Nodes
+	selector := labels.SelectorFromSet(labels.Set{util.HyperShiftNodePoolLabel: nodePoolName})
+	conditions, err := r.getTunedConditionsByNodeSelector(profile.Name, selector)
+	if err != nil {
+		conditions = r.getDegradedConditions(conditionFailedGettingTunedProfileStatus, err.Error())
+		if err := r.updateStatusHyperShift(cm, nodePoolName, profile, conditions); err != nil {
+			klog.Errorf("failed to update performance profile %q status: %v", profile.Name, err)
+		}
+		return reconcile.Result{}, err
+	}
+
+	if conditions == nil {
+		conditions = r.getAvailableConditions()
+	}
+
+	return reconcile.Result{}, r.updateStatusHyperShift(cm, nodePoolName, profile, conditions)
+}
+
+// parsePerformanceProfileConfigMap decodes the PerformanceProfile embedded in ConfigMap 'cm'.
+func parsePerformanceProfileConfigMap(cm *corev1.ConfigMap) (*performancev2.PerformanceProfile, error) {
+	data, ok := cm.Data[performanceProfileConfigMapConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("no data for field %s", performanceProfileConfigMapConfigKey)
+	}
+	profile := &performancev2.PerformanceProfile{}
+	if err := yaml.UnmarshalStrict([]byte(data), profile); err != nil {
+		return nil, fmt.Errorf("failed to decode PerformanceProfile: %v", err)
+	}
+	if profile.Name == "" {
+		return nil, fmt.Errorf("PerformanceProfile has no name")
+	}
+	return profile, nil
+}
+
+// applyComponentsHyperShift renders the components of PerformanceProfile 'profile' embedded in
+// ConfigMap 'cm' for NodePool 'nodePoolName'.
+func (r *PerformanceProfileHyperShiftReconciler) applyComponentsHyperShift(cm *corev1.ConfigMap, nodePoolName string, profile *performancev2.PerformanceProfile) error {
+	if profileutil.IsPaused(profile) {
+		klog.Infof("Ignoring reconcile loop for pause performance profile %s", profile.Name)
+		return nil
+	}
+
+	// there are no MachineConfigPools in hosted clusters
+	components, err := manifestset.GetNewComponents(profile, nil)
+	if err != nil {
+		return err
+	}
+
+	// The MachineConfig and KubeletConfig ConfigMaps are owned by ConfigMap 'cm' and labeled with
+	// its name; unlike the Tuned controller's ConfigMaps they are not labeled with
+	// util.HyperShiftTunedMachineConfigLabel, so the Tuned controller never prunes them.
+	machineConfigLabels := map[string]string{
+		util.HyperShiftGeneratedMachineConfigLabel: "true",
+		util.HyperShiftNodePoolLabel:               nodePoolName,
+		performanceProfileSourceLabel:              cm.Name,
+	}
+	if err := r.createOrUpdateConfigMapHyperShift(cm, cm.Name+"-machineconfig", machineConfigLabels, nil,
+		util.HyperShiftMachineConfigConfigMapKey, components.MachineConfig); err != nil {
+		return err
+	}
+	if err := r.createOrUpdateConfigMapHyperShift(cm, cm.Name+"-kubeletconfig", machineConfigLabels, nil,
+		util.HyperShiftMachineConfigConfigMapKey, components.KubeletConfig); err != nil {
+		return err
+	}
+
+	// the operator syncs the Tuned into the hosted cluster for the NodePool
+	tunedLabels := map[string]string{util.HyperShiftTunedConfigMapLabel: "true"}
+	tunedAnnotations := map[string]string{util.HyperShiftNodePoolLabel: cm.Annotations[util.HyperShiftNodePoolLabel]}
+	if err := r.createOrUpdateConfigMapHyperShift(cm, cm.Name+"-tuned", tunedLabels, tunedAnnotations,
+		util.HyperShiftTunedConfigMapConfigKey, components.Tuned); err != nil {
+		return err
+	}
+
+	runtimeClass := components.RuntimeClass
+	if runtimeClass.Labels == nil {
+		runtimeClass.Labels = map[string]string{}
+	}
+	runtimeClass.Labels[performanceProfileSourceLabel] = cm.Name
+	runtimeClassMutated, err := r.getMutatedRuntimeClass(runtimeClass)
+	if err != nil {
+		return err
+	}
+	if runtimeClassMutated != nil {
+		if err := r.createOrUpdateRuntimeClass(runtimeClassMutated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createOrUpdateConfigMapHyperShift creates or updates ConfigMap 'name' in management's cluster hosted
+// namespace with object 'obj' under key 'key'.  The ConfigMap is owned by ConfigMap 'owner'.
+func (r *PerformanceProfileHyperShiftReconciler) createOrUpdateConfigMapHyperShift(owner *corev1.ConfigMap, name string,
+	cmLabels map[string]string, cmAnnotations map[string]string, key string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   owner.Namespace,
+			Labels:      cmLabels,
+			Annotations: cmAnnotations,
+		},
+		Data: map[string]string{key: string(data)},
+	}
+	if err := controllerutil.SetControllerReference(owner, cm, r.Scheme); err != nil {
+		return err
+	}
+
+	existing := &corev1.ConfigMap{}
+	err = r.ManagementClient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: owner.Namespace}, existing)
+	if k8serros.IsNotFound(err) {
+		klog.Infof("Create ConfigMap %q under the namespace %q", name, owner.Namespace)
+		return r.ManagementClient.Create(context.TODO(), cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	mutated := existing.DeepCopy()
+	if mutated.Labels == nil {
+		mutated.Labels = map[string]string{}
+	}
+	if mutated.Annotations == nil {
+		mutated.Annotations = map[string]string{}
+	}
+	mergeMaps(cm.Labels, mutated.Labels)
+	mergeMaps(cm.Annotations, mutated.Annotations)
+	mutated.Data = cm.Data
+	mutated.OwnerReferences = cm.OwnerReferences
+
+	// we do not need to update if it no change between mutated and existing object
+	if apiequality.Semantic.DeepEqual(existing.Data, mutated.Data) &&
+		apiequality.Semantic.DeepEqual(existing.Labels, mutated.Labels) &&
+		apiequality.Semantic.DeepEqual(existing.Annotations, mutated.Annotations) &&
+		apiequality.Semantic.DeepEqual(existing.OwnerReferences, mutated.OwnerReferences) {
+		return nil
+	}
+
+	klog.Infof("Update ConfigMap %q under the namespace %q", name, owner.Namespace)
+	return r.ManagementClient.Update(context.TODO(), mutated)
+}
+
+// updateStatusHyperShift reports conditions 'conditions' of PerformanceProfile 'profile' embedded in
+// ConfigMap 'owner' into the PerformanceProfile status ConfigMap of NodePool 'nodePoolName'.  The status
+// ConfigMap is named after ConfigMap 'owner', so that several PerformanceProfiles of the NodePool
+// do not overwrite each other's status.
+func (r *PerformanceProfileHyperShiftReconciler) updateStatusHyperShift(owner *corev1.ConfigMap, nodePoolName string,
+	profile *performancev2.PerformanceProfile, conditions []conditionsv1.Condition) error {
+	status := performancev2.PerformanceProfileStatus{Conditions: conditions}
+	if profile != nil {
+		tunedNamespacedname := types.NamespacedName{
+			Name:      components.GetComponentName(profile.Name, components.ProfileNamePerformance),
+			Namespace: components.NamespaceNodeTuningOperator,
+		}
+		tunedStatus := tunedNamespacedname.String()
+		status.Tuned = &tunedStatus
+		runtimeClassName := components.GetComponentName(profile.Name, components.ComponentNamePrefix)
+		status.RuntimeClass = &runtimeClassName
+	}
+
+	name := owner.Name + "-status"
+	existing := &corev1.ConfigMap{}
+	err := r.ManagementClient.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: owner.Namespace}, existing)
+	if err != nil && !k8serros.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		existingStatus := performancev2.PerformanceProfileStatus{}
+		if err := yaml.Unmarshal([]byte(existing.Data[performanceProfileStatusConfigMapKey]), &existingStatus); err == nil &&
+			!conditionsModified(existingStatus.Conditions, status.Conditions) &&
+			apiequality.Semantic.DeepEqual(existingStatus.Tuned, status.Tuned) &&
+			apiequality.Semantic.DeepEqual(existingStatus.RuntimeClass, status.RuntimeClass) {
+			return nil
+		}
+	}
+
+	statusLabels := map[string]string{
+		performanceProfileStatusConfigMapLabel: "true",
+		util.HyperShiftNodePoolLabel:           nodePoolName,
+		performanceProfileSourceLabel:          owner.Name,
+	}
+	klog.Infof("Updating the performance profile status of NodePool %q", nodePoolName)
+	return r.createOrUpdateConfigMapHyperShift(owner, name, statusLabels, nil, performanceProfileStatusConfigMapKey, status)
+}
+
+// deleteRuntimeClassesHyperShift deletes the hosted cluster RuntimeClasses rendered from
+// PerformanceProfile ConfigMap 'cmName'.
+func (r *PerformanceProfileHyperShiftReconciler) deleteRuntimeClassesHyperShift(cmName string) error {
+	runtimeClasses := &nodev1.RuntimeClassList{}
+	if err := r.List(context.TODO(), runtimeClasses, client.MatchingLabels{performanceProfileSourceLabel: cmName}); err != nil {
+		return err
+	}
+	for i := range runtimeClasses.Items {
+		if err := r.deleteRuntimeClass(runtimeClasses.Items[i].Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
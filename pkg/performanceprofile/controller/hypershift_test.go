@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	performancev2 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/performanceprofile/v2"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components"
+	testutils "github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/utils/testing"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	testHostedNamespace = "clusters-test"
+	testNodePool        = "clusters/test-pool"
+	testNodePoolName    = "test-pool"
+)
+
+var _ = Describe("HyperShift Controller", func() {
+	var request reconcile.Request
+	var profile *performancev2.PerformanceProfile
+	var cm *corev1.ConfigMap
+
+	BeforeEach(func() {
+		profile = testutils.NewPerformanceProfile("test")
+		data, err := yaml.Marshal(profile)
+		Expect(err).ToNot(HaveOccurred())
+		cm = newPerformanceProfileConfigMap("pp-test", string(data))
+		request = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: testHostedNamespace,
+				Name:      cm.Name,
+			},
+		}
+	})
+
+	It("should render all components for the NodePool", func() {
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm})
+
+		_, err := r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, suffix := range []string{"-machineconfig", "-kubeletconfig"} {
+			generated := getConfigMap(r, cm.Name+suffix)
+			Expect(generated.Labels).To(HaveKeyWithValue(util.HyperShiftGeneratedMachineConfigLabel, "true"))
+			Expect(generated.Labels).To(HaveKeyWithValue(util.HyperShiftNodePoolLabel, testNodePoolName))
+			Expect(generated.Labels).To(HaveKeyWithValue(performanceProfileSourceLabel, cm.Name))
+			Expect(generated.Labels).ToNot(HaveKey(util.HyperShiftTunedMachineConfigLabel))
+			Expect(generated.Data).To(HaveKey(util.HyperShiftMachineConfigConfigMapKey))
+			Expect(generated.OwnerReferences).To(HaveLen(1))
+			Expect(generated.OwnerReferences[0].Name).To(Equal(cm.Name))
+		}
+
+		tunedCM := getConfigMap(r, cm.Name+"-tuned")
+		Expect(tunedCM.Labels).To(HaveKeyWithValue(util.HyperShiftTunedConfigMapLabel, "true"))
+		Expect(tunedCM.Annotations).To(HaveKeyWithValue(util.HyperShiftNodePoolLabel, testNodePool))
+		Expect(tunedCM.Data).To(HaveKey(util.HyperShiftTunedConfigMapConfigKey))
+
+		runtimeClass := &nodev1.RuntimeClass{}
+		key := types.NamespacedName{Name: components.GetComponentName(profile.Name, components.ComponentNamePrefix)}
+		Expect(r.Get(context.TODO(), key, runtimeClass)).ToNot(HaveOccurred())
+		Expect(runtimeClass.Labels).To(HaveKeyWithValue(performanceProfileSourceLabel, cm.Name))
+
+		status := getNodePoolStatus(r, cm)
+		availableCondition := conditionsv1.FindStatusCondition(status.Conditions, conditionsv1.ConditionAvailable)
+		Expect(availableCondition).ToNot(BeNil())
+		Expect(availableCondition.Status).To(Equal(corev1.ConditionTrue))
+	})
+
+	It("should leave the Tuned controller's MachineConfig ConfigMap of the NodePool alone", func() {
+		tunedMC := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "nto-mc-" + testNodePoolName,
+				Namespace: testHostedNamespace,
+				Labels: map[string]string{
+					util.HyperShiftGeneratedMachineConfigLabel: "true",
+					util.HyperShiftTunedMachineConfigLabel:     "true",
+					util.HyperShiftNodePoolLabel:               testNodePoolName,
+				},
+			},
+			Data: map[string]string{util.HyperShiftMachineConfigConfigMapKey: "kernel-args"},
+		}
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm, tunedMC})
+
+		_, err := r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		existing := getConfigMap(r, tunedMC.Name)
+		Expect(existing.Labels).To(Equal(tunedMC.Labels))
+		Expect(existing.Data).To(Equal(tunedMC.Data))
+		Expect(existing.OwnerReferences).To(BeEmpty())
+
+		generated := getConfigMap(r, cm.Name+"-machineconfig")
+		Expect(generated.Labels).ToNot(HaveKey(util.HyperShiftTunedMachineConfigLabel))
+	})
+
+	It("should report a Degraded status for an invalid PerformanceProfile", func() {
+		cm.Data[performanceProfileConfigMapConfigKey] = "spec: [invalid"
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm})
+
+		_, err := r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		status := getNodePoolStatus(r, cm)
+		degradedCondition := conditionsv1.FindStatusCondition(status.Conditions, conditionsv1.ConditionDegraded)
+		Expect(degradedCondition).ToNot(BeNil())
+		Expect(degradedCondition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(degradedCondition.Reason).To(Equal(conditionFailedToParsePerformanceProfile))
+
+		generated := &corev1.ConfigMap{}
+		err = r.ManagementClient.Get(context.TODO(), types.NamespacedName{Name: cm.Name + "-machineconfig", Namespace: testHostedNamespace}, generated)
+		Expect(errors.IsNotFound(err)).To(Equal(true))
+	})
+
+	It("should report the status of every PerformanceProfile of the NodePool", func() {
+		otherCM := newPerformanceProfileConfigMap("pp-other", "spec: [invalid")
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm, otherCM})
+
+		_, err := r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+		otherRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testHostedNamespace, Name: otherCM.Name}}
+		_, err = r.Reconcile(context.TODO(), otherRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		status := getNodePoolStatus(r, cm)
+		availableCondition := conditionsv1.FindStatusCondition(status.Conditions, conditionsv1.ConditionAvailable)
+		Expect(availableCondition).ToNot(BeNil())
+		Expect(availableCondition.Status).To(Equal(corev1.ConditionTrue))
+
+		otherStatus := getNodePoolStatus(r, otherCM)
+		degradedCondition := conditionsv1.FindStatusCondition(otherStatus.Conditions, conditionsv1.ConditionDegraded)
+		Expect(degradedCondition).ToNot(BeNil())
+		Expect(degradedCondition.Status).To(Equal(corev1.ConditionTrue))
+	})
+
+	It("should delete the RuntimeClass once the ConfigMap is removed", func() {
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm})
+
+		_, err := r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.ManagementClient.Delete(context.TODO(), cm)).ToNot(HaveOccurred())
+		_, err = r.Reconcile(context.TODO(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		runtimeClass := &nodev1.RuntimeClass{}
+		key := types.NamespacedName{Name: components.GetComponentName(profile.Name, components.ComponentNamePrefix)}
+		err = r.Get(context.TODO(), key, runtimeClass)
+		Expect(errors.IsNotFound(err)).To(Equal(true))
+	})
+
+	It("should map Tuned Profiles to the ConfigMaps of their Node's NodePool", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{util.HyperShiftNodePoolLabel: testNodePoolName},
+			},
+		}
+		otherCM := newPerformanceProfileConfigMap("pp-other", "")
+		otherCM.Annotations[util.HyperShiftNodePoolLabel] = "clusters/other-pool"
+		r := newFakeHyperShiftReconciler([]runtime.Object{cm, otherCM}, node)
+
+		requests := r.tunedProfileToConfigMaps(node)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal(cm.Name))
+	})
+})
+
+func newPerformanceProfileConfigMap(name string, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   testHostedNamespace,
+			Labels:      map[string]string{performanceProfileConfigMapLabel: "true"},
+			Annotations: map[string]string{util.HyperShiftNodePoolLabel: testNodePool},
+		},
+		Data: map[string]string{performanceProfileConfigMapConfigKey: data},
+	}
+}
+
+func getConfigMap(r *PerformanceProfileHyperShiftReconciler, name string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Name: name, Namespace: testHostedNamespace}
+	Expect(r.ManagementClient.Get(context.TODO(), key, cm)).ToNot(HaveOccurred())
+	return cm
+}
+
+func getNodePoolStatus(r *PerformanceProfileHyperShiftReconciler, cm *corev1.ConfigMap) *performancev2.PerformanceProfileStatus {
+	statusCM := getConfigMap(r, cm.Name+"-status")
+	Expect(statusCM.Labels).To(HaveKeyWithValue(performanceProfileStatusConfigMapLabel, "true"))
+	Expect(statusCM.Labels).To(HaveKeyWithValue(util.HyperShiftNodePoolLabel, testNodePoolName))
+	Expect(statusCM.Labels).To(HaveKeyWithValue(performanceProfileSourceLabel, cm.Name))
+	status := &performancev2.PerformanceProfileStatus{}
+	Expect(yaml.Unmarshal([]byte(statusCM.Data[performanceProfileStatusConfigMapKey]), status)).ToNot(HaveOccurred())
+	return status
+}
+
+// newFakeHyperShiftReconciler returns a new reconcile.Reconciler with fake management and hosted cluster clients
+func newFakeHyperShiftReconciler(managementObjects []runtime.Object, hostedObjects ...runtime.Object) *PerformanceProfileHyperShiftReconciler {
+	return &PerformanceProfileHyperShiftReconciler{
+		PerformanceProfileReconciler: PerformanceProfileReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(hostedObjects...).Build(),
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(10),
+		},
+		ManagementClient: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(managementObjects...).Build(),
+		Namespace:        testHostedNamespace,
+	}
+}
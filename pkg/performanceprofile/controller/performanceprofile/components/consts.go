@@ -29,3 +29,12 @@ const (
 	// HugepagesSize1G contains the size of 1G hugepages
 	HugepagesSize1G = "1G"
 )
+
+const (
+	// DefaultKubeReservedMemory contains the default amount of kube-reserved memory
+	DefaultKubeReservedMemory = "500Mi"
+	// DefaultSystemReservedMemory contains the default amount of system-reserved memory
+	DefaultSystemReservedMemory = "500Mi"
+	// DefaultHardEvictionThreshold contains the default memory.available hard eviction threshold
+	DefaultHardEvictionThreshold = "100Mi"
+)
@@ -30,9 +30,6 @@ const (
 	cpuManagerPolicyStatic               = "static"
 	cpuManagerPolicyOptionFullPCPUsOnly  = "full-pcpus-only"
 	memoryManagerPolicyStatic            = "Static"
-	defaultKubeReservedMemory            = "500Mi"
-	defaultSystemReservedMemory          = "500Mi"
-	defaultHardEvictionThreshold         = "100Mi"
 	evictionHardMemoryAvailable          = "memory.available"
 )
 
@@ -60,7 +57,7 @@ func New(profile *performancev2.PerformanceProfile, profileMCPLabels map[string]
 		kubeletConfig.EvictionHard = map[string]string{}
 	}
 	if _, ok := kubeletConfig.EvictionHard[evictionHardMemoryAvailable]; !ok {
-		kubeletConfig.EvictionHard[evictionHardMemoryAvailable] = defaultHardEvictionThreshold
+		kubeletConfig.EvictionHard[evictionHardMemoryAvailable] = components.DefaultHardEvictionThreshold
 	}
 
 	// set the default memory kube-reserved
@@ -68,7 +65,7 @@ func New(profile *performancev2.PerformanceProfile, profileMCPLabels map[string]
 		kubeletConfig.KubeReserved = map[string]string{}
 	}
 	if _, ok := kubeletConfig.KubeReserved[string(corev1.ResourceMemory)]; !ok {
-		kubeletConfig.KubeReserved[string(corev1.ResourceMemory)] = defaultKubeReservedMemory
+		kubeletConfig.KubeReserved[string(corev1.ResourceMemory)] = components.DefaultKubeReservedMemory
 	}
 
 	// set the default memory system-reserved
@@ -76,11 +73,34 @@ func New(profile *performancev2.PerformanceProfile, profileMCPLabels map[string]
 		kubeletConfig.SystemReserved = map[string]string{}
 	}
 	if _, ok := kubeletConfig.SystemReserved[string(corev1.ResourceMemory)]; !ok {
-		kubeletConfig.SystemReserved[string(corev1.ResourceMemory)] = defaultSystemReservedMemory
+		kubeletConfig.SystemReserved[string(corev1.ResourceMemory)] = components.DefaultSystemReservedMemory
+	}
+
+	// the memory section of the profile takes precedence over the defaults and the snippet
+	if memory := profile.Spec.Memory; memory != nil {
+		if memory.KubeReserved != nil {
+			kubeletConfig.KubeReserved[string(corev1.ResourceMemory)] = memory.KubeReserved.String()
+		}
+		if memory.SystemReserved != nil {
+			kubeletConfig.SystemReserved[string(corev1.ResourceMemory)] = memory.SystemReserved.String()
+		}
+		if memory.EvictionHard != nil {
+			kubeletConfig.EvictionHard[evictionHardMemoryAvailable] = memory.EvictionHard.String()
+		}
 	}
 
 	if profile.Spec.CPU != nil && profile.Spec.CPU.Reserved != nil {
 		kubeletConfig.ReservedSystemCPUs = string(*profile.Spec.CPU.Reserved)
+
+		// the shared CPUs should never be allocated exclusively by the CPU manager,
+		// the runtime gives them to the containers that opted in to the shared CPU pool
+		if profile.Spec.CPU.Shared != nil {
+			reservedSystemCPUs, err := components.CPUListsUnion(string(*profile.Spec.CPU.Reserved), string(*profile.Spec.CPU.Shared))
+			if err != nil {
+				return nil, err
+			}
+			kubeletConfig.ReservedSystemCPUs = reservedSystemCPUs
+		}
 	}
 
 	if profile.Spec.NUMA != nil {
@@ -94,6 +114,18 @@ func New(profile *performancev2.PerformanceProfile, profileMCPLabels map[string]
 				topologyPolicy == kubeletconfigv1beta1.SingleNumaNodeTopologyManagerPolicy {
 				kubeletConfig.MemoryManagerPolicy = memoryManagerPolicyStatic
 
+				if profile.Spec.Memory != nil && len(profile.Spec.Memory.ReservedMemory) > 0 {
+					kubeletConfig.ReservedMemory = nil
+					for _, r := range profile.Spec.Memory.ReservedMemory {
+						kubeletConfig.ReservedMemory = append(kubeletConfig.ReservedMemory, kubeletconfigv1beta1.MemoryReservation{
+							NumaNode: r.NUMANode,
+							Limits: map[corev1.ResourceName]resource.Quantity{
+								corev1.ResourceMemory: r.Limit.DeepCopy(),
+							},
+						})
+					}
+				}
+
 				if kubeletConfig.ReservedMemory == nil {
 					reservedMemory := resource.NewQuantity(0, resource.DecimalSI)
 					if err := addStringToQuantity(reservedMemory, kubeletConfig.KubeReserved[string(corev1.ResourceMemory)]); err != nil {
@@ -108,8 +140,8 @@ func New(profile *performancev2.PerformanceProfile, profileMCPLabels map[string]
 
 					kubeletConfig.ReservedMemory = []kubeletconfigv1beta1.MemoryReservation{
 						{
-							// the NUMA node 0 is the only safe choice for non NUMA machines
-							//  in the future we can extend our API to get this information from a user
+							// the NUMA node 0 is the only safe choice for non NUMA machines,
+							// use spec.memory.reservedMemory to spread the reservation
 							NumaNode: 0,
 							Limits: map[corev1.ResourceName]resource.Quantity{
 								corev1.ResourceMemory: *reservedMemory,
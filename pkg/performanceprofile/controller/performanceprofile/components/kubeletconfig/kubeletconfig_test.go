@@ -6,9 +6,11 @@ import (
 	"github.com/ghodss/yaml"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
 	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
 	"k8s.io/utils/pointer"
 
+	performancev2 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/performanceprofile/v2"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components"
 	testutils "github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/utils/testing"
 )
@@ -88,6 +90,69 @@ var _ = Describe("Kubelet Config", func() {
 		})
 	})
 
+	Context("with shared CPUs", func() {
+		It("should add the shared CPUs to the reserved system CPUs", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			sharedCPUs := performancev2.CPUSet("8-9")
+			profile.Spec.CPU.Shared = &sharedCPUs
+			selectorKey, selectorValue := components.GetFirstKeyAndValue(profile.Spec.MachineConfigPoolSelector)
+			kc, err := New(profile, map[string]string{selectorKey: selectorValue})
+			Expect(err).ToNot(HaveOccurred())
+
+			y, err := yaml.Marshal(kc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			Expect(manifest).To(ContainSubstring("reservedSystemCPUs: 0-3,8-9"))
+		})
+	})
+
+	Context("with memory section", func() {
+		It("should override the kubelet memory reservations", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			kubeReserved := resource.MustParse("1Gi")
+			profile.Spec.Memory = &performancev2.Memory{
+				KubeReserved: &kubeReserved,
+			}
+			selectorKey, selectorValue := components.GetFirstKeyAndValue(profile.Spec.MachineConfigPoolSelector)
+			kc, err := New(profile, map[string]string{selectorKey: selectorValue})
+			Expect(err).ToNot(HaveOccurred())
+
+			y, err := yaml.Marshal(kc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			Expect(manifest).To(ContainSubstring("memory: 1Gi"))
+			Expect(manifest).To(ContainSubstring("memory: 1624Mi"))
+		})
+
+		It("should set the per NUMA node memory reservations", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			profile.Spec.Memory = &performancev2.Memory{
+				ReservedMemory: []performancev2.NUMAReservedMemory{
+					{NUMANode: 0, Limit: resource.MustParse("600Mi")},
+					{NUMANode: 1, Limit: resource.MustParse("500Mi")},
+				},
+			}
+			selectorKey, selectorValue := components.GetFirstKeyAndValue(profile.Spec.MachineConfigPoolSelector)
+			kc, err := New(profile, map[string]string{selectorKey: selectorValue})
+			Expect(err).ToNot(HaveOccurred())
+
+			y, err := yaml.Marshal(kc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			Expect(manifest).ToNot(ContainSubstring(testReservedMemory))
+			Expect(manifest).To(ContainSubstring(`reservedMemory:
+    - limits:
+        memory: 600Mi
+      numaNode: 0
+    - limits:
+        memory: 500Mi
+      numaNode: 1`))
+		})
+	})
+
 	Context("with additional kubelet arguments", func() {
 		It("should not override CPU manager parameters", func() {
 			profile := testutils.NewPerformanceProfile("test")
@@ -5,8 +5,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"text/template"
 
 	assets "github.com/openshift/cluster-node-tuning-operator/assets/performanceprofile"
@@ -49,6 +52,9 @@ const (
 	udevRulesDir         = "/etc/udev/rules.d"
 	udevRpsRules         = "99-netdev-rps.rules"
 	udevPhysicalRpsRules = "99-netdev-physical-rps.rules"
+	udevSteeringRules    = "99-netdev-steering.rules"
+	modulesLoadDir       = "/etc/modules-load.d"
+	modulesLoadConfig    = "99-kernel-modules.conf"
 	// scripts
 	hugepagesAllocation       = "hugepages-allocation"
 	setCPUsOffline            = "set-cpus-offline"
@@ -86,7 +92,9 @@ const (
 )
 
 const (
-	templateReservedCpus = "ReservedCpus"
+	templateReservedCpus    = "ReservedCpus"
+	templateSharedCpus      = "SharedCpus"
+	templateNetDevicesRules = "NetDevicesRules"
 )
 
 // New returns new machine configuration object for performance sensitive workloads
@@ -145,10 +153,20 @@ func getIgnitionConfig(profile *performancev2.PerformanceProfile) (*igntypes.Con
 		},
 	}
 
+	workloadHints, err := performancev2.ResolveWorkloadHints(profile.Spec.WorkloadHints)
+	if err != nil {
+		return nil, err
+	}
+
 	// add script files under the node /usr/local/bin directory
-	if profile.Spec.WorkloadHints != nil && profile.Spec.WorkloadHints.RealTime != nil && !*profile.Spec.WorkloadHints.RealTime {
-		// realtime is explicitly disabled by workload hint
+	steeringDevices := getNetDevicesWithSteering(profile)
+	if !workloadHints.RPS {
+		// the RPS masks are not set by the workload hints
 		scripts = []string{hugepagesAllocation, setCPUsOffline, clearIRQBalanceBannedCPUs}
+		// the RPS, XPS and RFS settings of the network devices are still applied by the set-rps-mask script
+		if len(steeringDevices) > 0 {
+			scripts = append(scripts, setRPSMask)
+		}
 	} else {
 		scripts = []string{hugepagesAllocation, ociHooks, setRPSMask, setCPUsOffline, clearIRQBalanceBannedCPUs}
 	}
@@ -171,8 +189,8 @@ func getIgnitionConfig(profile *performancev2.PerformanceProfile) (*igntypes.Con
 	crioConfSnippetDst := filepath.Join(crioConfd, crioRuntimesConfig)
 	addContent(ignitionConfig, crioConfigSnippetContent, crioConfSnippetDst, &crioConfdRuntimesMode)
 
-	// do not add RPS handling when realtime is explicitly disabled by workload hint
-	if profile.Spec.WorkloadHints == nil || profile.Spec.WorkloadHints.RealTime == nil || *profile.Spec.WorkloadHints.RealTime {
+	// do not add RPS handling when the workload hints do not set the RPS masks
+	if workloadHints.RPS {
 		// add crio hooks config  under the node cri-o hook directory
 		crioHooksConfigsMode := 0644
 		ociHooksConfigContent, err := GetOCIHooksConfigContent(OCIHooksConfig, profile)
@@ -186,9 +204,9 @@ func getIgnitionConfig(profile *performancev2.PerformanceProfile) (*igntypes.Con
 		rpsRulesMode := 0644
 		var rpsRulesContent []byte
 		if profileutil.IsRpsEnabled(profile) {
-			rpsRulesContent, err = assets.Configs.ReadFile(filepath.Join("configs", udevPhysicalRpsRules))
+			rpsRulesContent, err = renderPhysicalRpsRules(profile, filepath.Join("configs", udevPhysicalRpsRules))
 		} else {
-			rpsRulesContent, err = assets.Configs.ReadFile(filepath.Join("configs", udevRpsRules))
+			rpsRulesContent, err = renderNetDevicesSteeringRules(profile, filepath.Join("configs", udevRpsRules))
 		}
 		if err != nil {
 			return nil, err
@@ -214,6 +232,45 @@ func getIgnitionConfig(profile *performancev2.PerformanceProfile) (*igntypes.Con
 		}
 	}
 
+	// the RPS, XPS and RFS settings specified for the network devices do not depend on the workload hints
+	if len(steeringDevices) > 0 && profile.Spec.CPU != nil && profile.Spec.CPU.Reserved != nil {
+		if !workloadHints.RPS {
+			// the RPS udev rules are not added, add only the rules of the network devices
+			steeringRulesMode := 0644
+			steeringRulesContent := []byte(strings.Join(getNetDevicesSteeringRules(steeringDevices), "\n") + "\n")
+			addContent(ignitionConfig, steeringRulesContent, filepath.Join(udevRulesDir, udevSteeringRules), &steeringRulesMode)
+		}
+
+		reservedMask, err := components.CPUListToMaskList(string(*profile.Spec.CPU.Reserved))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, device := range steeringDevices {
+			steeringOptions, err := getNetDeviceSteeringUnitOptions(device, reservedMask)
+			if err != nil {
+				return nil, err
+			}
+
+			steeringService, err := getSystemdContent(steeringOptions)
+			if err != nil {
+				return nil, err
+			}
+
+			ignitionConfig.Systemd.Units = append(ignitionConfig.Systemd.Units, igntypes.Unit{
+				Contents: &steeringService,
+				Name:     getSystemdService(getNetDeviceSteeringService(device)),
+			})
+		}
+	}
+
+	// load the kernel modules on boot, the tuned profile only sets their parameters
+	if profile.Spec.KernelModules != nil && len(profile.Spec.KernelModules.Load) > 0 {
+		modulesLoadMode := 0644
+		modulesLoadContent := []byte(strings.Join(profile.Spec.KernelModules.Load, "\n") + "\n")
+		addContent(ignitionConfig, modulesLoadContent, filepath.Join(modulesLoadDir, modulesLoadConfig), &modulesLoadMode)
+	}
+
 	if profile.Spec.HugePages != nil {
 		for _, page := range profile.Spec.HugePages.Pages {
 			// we already allocated non NUMA specific hugepages via kernel arguments
@@ -322,14 +379,27 @@ func GetOCIHooksConfigContent(configFile string, profile *performancev2.Performa
 
 // GetHugepagesSizeKilobytes retruns hugepages size in kilobytes
 func GetHugepagesSizeKilobytes(hugepagesSize performancev2.HugePageSize) (string, error) {
-	switch hugepagesSize {
-	case "1G":
-		return "1048576", nil
-	case "2M":
-		return "2048", nil
+	size := string(hugepagesSize)
+	if len(size) < 2 {
+		return "", fmt.Errorf("can not convert size %q to kilobytes", hugepagesSize)
+	}
+
+	value, err := strconv.ParseUint(size[:len(size)-1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("can not convert size %q to kilobytes: %v", hugepagesSize, err)
+	}
+
+	switch size[len(size)-1] {
+	case 'K':
+	case 'M':
+		value *= 1024
+	case 'G':
+		value *= 1024 * 1024
 	default:
 		return "", fmt.Errorf("can not convert size %q to kilobytes", hugepagesSize)
 	}
+
+	return strconv.FormatUint(value, 10), nil
 }
 
 func getIRQBalanceBannedCPUsOptions() []*unit.UnitOption {
@@ -412,6 +482,85 @@ func getRPSUnitOptions(rpsMask string) []*unit.UnitOption {
 	}
 }
 
+// getNetDeviceSteeringUnitOptions returns the unit options of the service that sets the RPS, XPS and RFS
+// settings of the network device, the RPS mask defaults to the reserved CPUs mask
+func getNetDeviceSteeringUnitOptions(device performancev2.Device, reservedMask string) ([]*unit.UnitOption, error) {
+	rpsMask := reservedMask
+	if device.RPSCPUs != nil {
+		mask, err := components.CPUListToMaskList(string(*device.RPSCPUs))
+		if err != nil {
+			return nil, err
+		}
+		rpsMask = mask
+	}
+
+	// the set-rps-mask script leaves the XPS and RFS settings unchanged for the "-" value
+	xpsMask := "-"
+	if device.XPSCPUs != nil {
+		mask, err := components.CPUListToMaskList(string(*device.XPSCPUs))
+		if err != nil {
+			return nil, err
+		}
+		xpsMask = mask
+	}
+
+	rfsFlowEntries := "-"
+	if device.RFSFlowEntries != nil {
+		rfsFlowEntries = strconv.Itoa(int(*device.RFSFlowEntries))
+	}
+
+	cmd := fmt.Sprintf("%s %%i %s %s %s", getBashScriptPath(setRPSMask), rpsMask, xpsMask, rfsFlowEntries)
+	return []*unit.UnitOption{
+		// [Unit]
+		// Description
+		unit.NewUnitOption(systemdSectionUnit, systemdDescription, "Sets network devices RPS, XPS and RFS settings"),
+		// [Service]
+		// Type
+		unit.NewUnitOption(systemdSectionService, systemdType, systemdServiceTypeOneshot),
+		// ExecStart
+		unit.NewUnitOption(systemdSectionService, systemdExecStart, cmd),
+	}, nil
+}
+
+// getNetDeviceSteeringService returns the name of the template service that sets the RPS, XPS and RFS settings
+// of the network devices matching the user level networking device, the name is derived from the device matches
+// so it does not change when the devices under spec.net.devices are reordered
+func getNetDeviceSteeringService(device performancev2.Device) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(getNetDeviceMatches(device), ", ")))
+	return fmt.Sprintf("update-rps-net-%08x@", hash.Sum32())
+}
+
+// getNetDevicesWithSteering returns the user level networking devices that specify their own RPS, XPS
+// or RFS settings
+func getNetDevicesWithSteering(profile *performancev2.PerformanceProfile) []performancev2.Device {
+	var devices []performancev2.Device
+	if profile.Spec.Net == nil || profile.Spec.Net.UserLevelNetworking == nil || !*profile.Spec.Net.UserLevelNetworking {
+		return devices
+	}
+
+	for _, device := range profile.Spec.Net.Devices {
+		if hasNetDeviceSteering(device) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// getNetDevicesSteeringRules returns the udev rules that start the services setting the RPS, XPS and RFS
+// settings of the network devices
+func getNetDevicesSteeringRules(devices []performancev2.Device) []string {
+	var rules []string
+	for _, device := range devices {
+		rules = append(rules, getNetDeviceRpsRule(device, getNetDeviceSteeringService(device)))
+	}
+	return rules
+}
+
+func hasNetDeviceSteering(device performancev2.Device) bool {
+	return device.RPSCPUs != nil || device.XPSCPUs != nil || device.RFSFlowEntries != nil
+}
+
 func addContent(ignitionConfig *igntypes.Config, content []byte, dst string, mode *int) {
 	contentBase64 := base64.StdEncoding.EncodeToString(content)
 	ignitionConfig.Storage.Files = append(ignitionConfig.Storage.Files, igntypes.File{
@@ -432,6 +581,9 @@ func renderCrioConfigSnippet(profile *performancev2.PerformanceProfile, src stri
 	if profile.Spec.CPU.Reserved != nil {
 		templateArgs[templateReservedCpus] = string(*profile.Spec.CPU.Reserved)
 	}
+	if profile.Spec.CPU.Shared != nil {
+		templateArgs[templateSharedCpus] = string(*profile.Spec.CPU.Shared)
+	}
 
 	profileTemplate, err := template.ParseFS(assets.Configs, src)
 	if err != nil {
@@ -445,3 +597,89 @@ func renderCrioConfigSnippet(profile *performancev2.PerformanceProfile, src stri
 
 	return crioConfig.Bytes(), nil
 }
+
+// renderPhysicalRpsRules renders the udev rules that set the RPS mask for the physical network devices,
+// when the user level networking devices are specified only the matching physical devices are updated,
+// devices with their own RPS, XPS or RFS settings are updated by a dedicated service
+func renderPhysicalRpsRules(profile *performancev2.PerformanceProfile, src string) ([]byte, error) {
+	templateArgs := make(map[string]string)
+	if profile.Spec.Net != nil && profile.Spec.Net.UserLevelNetworking != nil && *profile.Spec.Net.UserLevelNetworking {
+		var rules []string
+		for _, device := range profile.Spec.Net.Devices {
+			service := "update-rps@"
+			if hasNetDeviceSteering(device) {
+				service = getNetDeviceSteeringService(device)
+			}
+			rules = append(rules, getNetDeviceRpsRule(device, service))
+		}
+		templateArgs[templateNetDevicesRules] = strings.Join(rules, "\n")
+	}
+
+	rulesTemplate, err := template.ParseFS(assets.Configs, src)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &bytes.Buffer{}
+	if err := rulesTemplate.Execute(rules, templateArgs); err != nil {
+		return nil, err
+	}
+
+	return rules.Bytes(), nil
+}
+
+// renderNetDevicesSteeringRules appends to the RPS udev rules of the non physical network devices the rules of
+// the physical network devices that specify their own RPS, XPS or RFS settings
+func renderNetDevicesSteeringRules(profile *performancev2.PerformanceProfile, src string) ([]byte, error) {
+	content, err := assets.Configs.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := getNetDevicesWithSteering(profile)
+	if len(devices) == 0 {
+		return content, nil
+	}
+
+	rules := []string{strings.TrimSuffix(string(content), "\n")}
+	rules = append(rules, getNetDevicesSteeringRules(devices)...)
+
+	return []byte(strings.Join(rules, "\n") + "\n"), nil
+}
+
+func getNetDeviceRpsRule(device performancev2.Device, service string) string {
+	matches := getNetDeviceMatches(device)
+	matches = append(matches, `TAG+="systemd"`, fmt.Sprintf(`ENV{SYSTEMD_WANTS}="%s%%k.service"`, service))
+
+	return strings.Join(matches, ", ")
+}
+
+// getNetDeviceMatches returns the udev rule matches of the network devices matching the user level networking device
+func getNetDeviceMatches(device performancev2.Device) []string {
+	matches := []string{`SUBSYSTEM=="net"`, `ACTION=="add"`, `ENV{ID_BUS}=="pci"`}
+	if device.InterfaceName != nil {
+		if strings.HasPrefix(*device.InterfaceName, "!") {
+			matches = append(matches, fmt.Sprintf(`KERNEL!="%s"`, strings.TrimPrefix(*device.InterfaceName, "!")))
+		} else {
+			matches = append(matches, fmt.Sprintf(`KERNEL=="%s"`, *device.InterfaceName))
+		}
+	}
+	if device.VendorID != nil {
+		matches = append(matches, fmt.Sprintf(`ENV{ID_VENDOR_ID}=="%s"`, *device.VendorID))
+	}
+	if device.DeviceID != nil {
+		matches = append(matches, fmt.Sprintf(`ENV{ID_MODEL_ID}=="%s"`, *device.DeviceID))
+	}
+	if device.PCIAddress != nil {
+		matches = append(matches, fmt.Sprintf(`ENV{ID_PATH}=="pci-%s"`, strings.ToLower(*device.PCIAddress)))
+	}
+	if device.Driver != nil {
+		matches = append(matches, fmt.Sprintf(`ENV{ID_NET_DRIVER}=="%s"`, *device.Driver))
+	}
+	if device.MACAddress != nil {
+		// match the permanent MAC address as the tuned profile does, not the current one
+		matches = append(matches, fmt.Sprintf(`ENV{ID_NET_NAME_MAC}=="%s"`, components.MACAddressToNetName(*device.MACAddress)))
+	}
+
+	return matches
+}
@@ -1,7 +1,9 @@
 package machineconfig
 
 import (
+	"encoding/base64"
 	"fmt"
+	"path/filepath"
 
 	"k8s.io/utils/pointer"
 
@@ -9,6 +11,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	performancev2 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/performanceprofile/v2"
 	"github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/controller/performanceprofile/components"
 	testutils "github.com/openshift/cluster-node-tuning-operator/pkg/performanceprofile/utils/testing"
 )
@@ -84,6 +87,63 @@ var _ = Describe("Machine Config", func() {
 		})
 	})
 
+	Context("with workload hints", func() {
+		getManifest := func(hints *performancev2.WorkloadHints) string {
+			profile := testutils.NewPerformanceProfile("test")
+			profile.Spec.WorkloadHints = hints
+			mc, err := New(profile)
+			Expect(err).ToNot(HaveOccurred())
+			y, err := yaml.Marshal(mc)
+			Expect(err).ToNot(HaveOccurred())
+			return string(y)
+		}
+
+		It("should set the RPS masks when the workload hints are not set", func() {
+			manifest := getManifest(nil)
+			Expect(manifest).To(ContainSubstring("name: update-rps@.service"))
+			Expect(manifest).To(ContainSubstring(getBashScriptPath(setRPSMask)))
+		})
+
+		It("should set the RPS masks when the realtime hint is not set", func() {
+			manifest := getManifest(&performancev2.WorkloadHints{HighPowerConsumption: pointer.BoolPtr(false)})
+			Expect(manifest).To(ContainSubstring("name: update-rps@.service"))
+		})
+
+		It("should not set the RPS masks when the realtime hint is explicitly disabled", func() {
+			manifest := getManifest(&performancev2.WorkloadHints{RealTime: pointer.BoolPtr(false)})
+			Expect(manifest).ToNot(ContainSubstring("name: update-rps@.service"))
+			Expect(manifest).ToNot(ContainSubstring(getBashScriptPath(setRPSMask)))
+		})
+	})
+
+	Context("with kernel modules", func() {
+		It("should load the kernel modules on boot", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			profile.Spec.KernelModules = &performancev2.KernelModules{
+				Load:    []string{"vfio-pci", "ixgbe"},
+				Options: []performancev2.KernelModuleOptions{{Name: "ixgbe", Parameters: "allow_unsupported_sfp=1"}},
+			}
+			mc, err := New(profile)
+			Expect(err).ToNot(HaveOccurred())
+			y, err := yaml.Marshal(mc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			Expect(manifest).To(ContainSubstring("path: " + filepath.Join(modulesLoadDir, modulesLoadConfig)))
+			Expect(manifest).To(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("vfio-pci\nixgbe\n"))))
+		})
+
+		It("should not add the modules load configuration without modules to load", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			profile.Spec.KernelModules = &performancev2.KernelModules{Blacklist: []string{"nouveau"}}
+			mc, err := New(profile)
+			Expect(err).ToNot(HaveOccurred())
+			y, err := yaml.Marshal(mc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(y)).ToNot(ContainSubstring(modulesLoadDir))
+		})
+	})
+
 	Context("with hugepages with specified NUMA node and offlinedCPUs", func() {
 		var manifest string
 
@@ -122,6 +182,169 @@ var _ = Describe("Machine Config", func() {
 		})
 	})
 
+	Context("with shared CPUs", func() {
+		It("should configure the CRI-O shared cpuset", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			sharedCPUs := performancev2.CPUSet("8-9")
+			profile.Spec.CPU.Shared = &sharedCPUs
+
+			content, err := renderCrioConfigSnippet(profile, filepath.Join("configs", crioRuntimesConfig))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`shared_cpuset = "8-9"`))
+			Expect(string(content)).To(ContainSubstring(`"cpu-shared.crio.io"`))
+		})
+
+		It("should not configure the CRI-O shared cpuset without shared CPUs", func() {
+			profile := testutils.NewPerformanceProfile("test")
+
+			content, err := renderCrioConfigSnippet(profile, filepath.Join("configs", crioRuntimesConfig))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).ToNot(ContainSubstring("shared_cpuset"))
+			Expect(string(content)).To(ContainSubstring(`infra_ctr_cpuset = "0-3"`))
+		})
+	})
+
+	Context("with physical devices RPS enabled", func() {
+		It("should set the RPS mask for all devices without net devices", func() {
+			profile := testutils.NewPerformanceProfile("test")
+
+			content, err := renderPhysicalRpsRules(profile, filepath.Join("configs", udevPhysicalRpsRules))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(`SUBSYSTEM=="net", ACTION=="add", ENV{DEVPATH}!="/devices/virtual/net/veth*", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"
+`))
+		})
+
+		It("should set the RPS mask for the physical devices matching the net devices", func() {
+			profile := testutils.NewPerformanceProfile("test")
+			profile.Spec.Net = &performancev2.Net{
+				UserLevelNetworking: pointer.BoolPtr(true),
+				Devices: []performancev2.Device{
+					{
+						PCIAddress: pointer.StringPtr("0000:3B:00.0"),
+						Driver:     pointer.StringPtr("ice"),
+						MACAddress: pointer.StringPtr("52:54:00:AB:CD:EF"),
+					},
+					{
+						InterfaceName: pointer.StringPtr("!eno*"),
+						VendorID:      pointer.StringPtr("0x8086"),
+						DeviceID:      pointer.StringPtr("0x1592"),
+					},
+				},
+			}
+
+			content, err := renderPhysicalRpsRules(profile, filepath.Join("configs", udevPhysicalRpsRules))
+			Expect(err).ToNot(HaveOccurred())
+			rules := string(content)
+			Expect(rules).To(ContainSubstring(`SUBSYSTEM=="net", ACTION=="add", ENV{DEVPATH}!="/devices/virtual/net/veth*", ENV{ID_BUS}!="pci", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"`))
+			Expect(rules).To(ContainSubstring(`SUBSYSTEM=="net", ACTION=="add", ENV{ID_BUS}=="pci", ENV{ID_PATH}=="pci-0000:3b:00.0", ENV{ID_NET_DRIVER}=="ice", ENV{ID_NET_NAME_MAC}=="enx525400abcdef", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"` + "\n"))
+			Expect(rules).To(ContainSubstring(`SUBSYSTEM=="net", ACTION=="add", ENV{ID_BUS}=="pci", KERNEL!="eno*", ENV{ID_VENDOR_ID}=="0x8086", ENV{ID_MODEL_ID}=="0x1592", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"` + "\n"))
+		})
+	})
+
+	Context("with net devices RPS, XPS and RFS settings", func() {
+		var profile *performancev2.PerformanceProfile
+
+		BeforeEach(func() {
+			profile = testutils.NewPerformanceProfile("test")
+			rpsCPUs := performancev2.CPUSet("0-1")
+			xpsCPUs := performancev2.CPUSet("2-3")
+			profile.Spec.Net = &performancev2.Net{
+				UserLevelNetworking: pointer.BoolPtr(true),
+				Devices: []performancev2.Device{
+					{
+						InterfaceName: pointer.StringPtr("eno1"),
+					},
+					{
+						PCIAddress:     pointer.StringPtr("0000:3b:00.0"),
+						RPSCPUs:        &rpsCPUs,
+						XPSCPUs:        &xpsCPUs,
+						RFSFlowEntries: pointer.Int32Ptr(4096),
+					},
+				},
+			}
+		})
+
+		It("should update only the devices with their own settings without physical devices RPS enabled", func() {
+			content, err := renderNetDevicesSteeringRules(profile, filepath.Join("configs", udevRpsRules))
+			Expect(err).ToNot(HaveOccurred())
+			rules := string(content)
+			Expect(rules).To(HavePrefix(`SUBSYSTEM=="net", ACTION=="add", ENV{DEVPATH}!="/devices/virtual/net/veth*", ENV{ID_BUS}!="pci", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"` + "\n"))
+			Expect(rules).ToNot(ContainSubstring(`KERNEL=="eno1"`))
+			service := getNetDeviceSteeringService(profile.Spec.Net.Devices[1])
+			Expect(rules).To(ContainSubstring(`SUBSYSTEM=="net", ACTION=="add", ENV{ID_BUS}=="pci", ENV{ID_PATH}=="pci-0000:3b:00.0", TAG+="systemd", ENV{SYSTEMD_WANTS}="` + service + `%k.service"` + "\n"))
+		})
+
+		It("should update all the net devices with physical devices RPS enabled", func() {
+			content, err := renderPhysicalRpsRules(profile, filepath.Join("configs", udevPhysicalRpsRules))
+			Expect(err).ToNot(HaveOccurred())
+			rules := string(content)
+			Expect(rules).To(ContainSubstring(`SUBSYSTEM=="net", ACTION=="add", ENV{ID_BUS}=="pci", KERNEL=="eno1", TAG+="systemd", ENV{SYSTEMD_WANTS}="update-rps@%k.service"` + "\n"))
+			service := getNetDeviceSteeringService(profile.Spec.Net.Devices[1])
+			Expect(rules).To(ContainSubstring(`ENV{ID_PATH}=="pci-0000:3b:00.0", TAG+="systemd", ENV{SYSTEMD_WANTS}="` + service + `%k.service"` + "\n"))
+		})
+
+		It("should add the systemd unit that sets the device RPS, XPS and RFS settings", func() {
+			mc, err := New(profile)
+			Expect(err).ToNot(HaveOccurred())
+			y, err := yaml.Marshal(mc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			Expect(manifest).To(ContainSubstring("name: update-rps@.service"))
+			Expect(manifest).To(ContainSubstring("name: " + getNetDeviceSteeringService(profile.Spec.Net.Devices[1]) + ".service"))
+			Expect(manifest).ToNot(ContainSubstring("name: " + getNetDeviceSteeringService(profile.Spec.Net.Devices[0]) + ".service"))
+			Expect(manifest).ToNot(ContainSubstring(filepath.Join(udevRulesDir, udevSteeringRules)))
+		})
+
+		It("should name the device service after the device matches", func() {
+			service := getNetDeviceSteeringService(profile.Spec.Net.Devices[1])
+			Expect(service).To(MatchRegexp(`^update-rps-net-[0-9a-f]{8}@$`))
+
+			// reordering the devices or changing their settings keeps the service name
+			profile.Spec.Net.Devices[0], profile.Spec.Net.Devices[1] = profile.Spec.Net.Devices[1], profile.Spec.Net.Devices[0]
+			profile.Spec.Net.Devices[0].RFSFlowEntries = pointer.Int32Ptr(8192)
+			Expect(getNetDeviceSteeringService(profile.Spec.Net.Devices[0])).To(Equal(service))
+
+			profile.Spec.Net.Devices[0].PCIAddress = pointer.StringPtr("0000:3b:00.1")
+			Expect(getNetDeviceSteeringService(profile.Spec.Net.Devices[0])).ToNot(Equal(service))
+		})
+
+		It("should apply the device RPS, XPS and RFS settings when the realtime hint is explicitly disabled", func() {
+			profile.Spec.WorkloadHints = &performancev2.WorkloadHints{RealTime: pointer.BoolPtr(false)}
+			mc, err := New(profile)
+			Expect(err).ToNot(HaveOccurred())
+			y, err := yaml.Marshal(mc)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := string(y)
+			service := getNetDeviceSteeringService(profile.Spec.Net.Devices[1])
+			Expect(manifest).ToNot(ContainSubstring("name: update-rps@.service"))
+			Expect(manifest).ToNot(ContainSubstring(filepath.Join(udevRulesDir, udevRpsRules)))
+			Expect(manifest).To(ContainSubstring("name: " + service + ".service"))
+			Expect(manifest).To(ContainSubstring(filepath.Join(udevRulesDir, udevSteeringRules)))
+			Expect(manifest).To(ContainSubstring(getBashScriptPath(setRPSMask)))
+
+			rules := `SUBSYSTEM=="net", ACTION=="add", ENV{ID_BUS}=="pci", ENV{ID_PATH}=="pci-0000:3b:00.0", TAG+="systemd", ENV{SYSTEMD_WANTS}="` + service + `%k.service"` + "\n"
+			Expect(manifest).To(ContainSubstring(base64.StdEncoding.EncodeToString([]byte(rules))))
+		})
+
+		It("should default the RPS mask to the reserved CPUs and leave the unset settings unchanged", func() {
+			options, err := getNetDeviceSteeringUnitOptions(profile.Spec.Net.Devices[1], "0000000f")
+			Expect(err).ToNot(HaveOccurred())
+			unit, err := getSystemdContent(options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unit).To(ContainSubstring("ExecStart=/usr/local/bin/set-rps-mask.sh %i 00000003 0000000c 4096\n"))
+
+			profile.Spec.Net.Devices[1].RPSCPUs = nil
+			profile.Spec.Net.Devices[1].XPSCPUs = nil
+			options, err = getNetDeviceSteeringUnitOptions(profile.Spec.Net.Devices[1], "0000000f")
+			Expect(err).ToNot(HaveOccurred())
+			unit, err = getSystemdContent(options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unit).To(ContainSubstring("ExecStart=/usr/local/bin/set-rps-mask.sh %i 0000000f - 4096\n"))
+		})
+	})
+
 	Context("check listToString ", func() {
 		It("should create string from CPUSet", func() {
 			res := components.ListToString(CPUs)
@@ -149,4 +372,28 @@ WantedBy=multi-user.target
 			Expect(unit).To(Equal(expected))
 		})
 	})
+
+	Context("with hugepages sizes of different architectures", func() {
+		It("should convert the sizes to kilobytes", func() {
+			for size, kilobytes := range map[string]string{
+				"64K":  "64",
+				"2M":   "2048",
+				"32M":  "32768",
+				"512M": "524288",
+				"1G":   "1048576",
+				"16G":  "16777216",
+			} {
+				value, err := GetHugepagesSizeKilobytes(performancev2.HugePageSize(size))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal(kilobytes))
+			}
+		})
+
+		It("should fail on unknown sizes", func() {
+			for _, size := range []string{"", "1", "1T", "!#@"} {
+				_, err := GetHugepagesSizeKilobytes(performancev2.HugePageSize(size))
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"text/template"
@@ -29,8 +30,27 @@ const (
 	templateGloballyDisableIrqLoadBalancing = "GloballyDisableIrqLoadBalancing"
 	templateNetDevices                      = "NetDevices"
 	nfConntrackHashsize                     = "nf_conntrack_hashsize=131072"
-	templateRealTimeHint                    = "RealTimeHint"
+	templateStalld                          = "Stalld"
+	templateUnlimitedRTRuntime              = "UnlimitedRTRuntime"
+	templateRealTimeTuning                  = "RealTimeTuning"
 	templateHighPowerConsumption            = "HighPowerConsumption"
+	templateCStatesPolling                  = "CStatesPolling"
+	templateCStatesPerPod                   = "CStatesPerPod"
+	templateCPUFrequencyPerPod              = "CPUFrequencyPerPod"
+	templateHousekeepingIRQCpus             = "HousekeepingIRQCpus"
+	templateHousekeepingKernelThreadsCpus   = "HousekeepingKernelThreadsCpus"
+	templateHousekeepingRCUCpus             = "HousekeepingRCUCpus"
+	templatePowerPolicies                   = "PowerPolicies"
+	templatePowerExcludedDevices            = "PowerExcludedDevices"
+	templateSysctls                         = "Sysctls"
+	templateKernelModules                   = "KernelModules"
+	templateKernelModulesBlacklist          = "KernelModulesBlacklist"
+	templateAdditionalTunedProfile          = "AdditionalTunedProfile"
+	defaultCPUGovernor                      = "performance"
+	// cpuIdleStatesMaxIndex contains the index of the deepest idle state matched when disabling idle states
+	cpuIdleStatesMaxIndex = 9
+	// maxRPSSockFlowEntries is the kernel limit of net.core.rps_sock_flow_entries
+	maxRPSSockFlowEntries = 1 << 29
 )
 
 func new(name string, profiles []tunedv1.TunedProfile, recommends []tunedv1.TunedRecommend) *tunedv1.Tuned {
@@ -61,6 +81,18 @@ func NewNodePerformance(profile *performancev2.PerformanceProfile) (*tunedv1.Tun
 		}
 	}
 
+	if housekeeping := profile.Spec.CPU.Housekeeping; housekeeping != nil {
+		if housekeeping.IRQ != nil {
+			templateArgs[templateHousekeepingIRQCpus] = string(*housekeeping.IRQ)
+		}
+		if housekeeping.KernelThreads != nil {
+			templateArgs[templateHousekeepingKernelThreadsCpus] = string(*housekeeping.KernelThreads)
+		}
+		if housekeeping.RCU != nil {
+			templateArgs[templateHousekeepingRCUCpus] = string(*housekeeping.RCU)
+		}
+	}
+
 	if profile.Spec.HugePages != nil {
 		var defaultHugepageSize performancev2.HugePageSize
 		if profile.Spec.HugePages.DefaultHugePagesSize != nil {
@@ -127,12 +159,23 @@ func NewNodePerformance(profile *performancev2.PerformanceProfile) (*tunedv1.Tun
 		var tunedNetDevicesOutput []string
 		netPluginSequence := 0
 		netPluginString := ""
+		var rfsSockFlowEntries int64
 
 		for _, device := range profile.Spec.Net.Devices {
 			devices = make([]string, 0)
 			if device.DeviceID != nil {
 				devices = append(devices, "^ID_MODEL_ID="+*device.DeviceID)
 			}
+			if device.Driver != nil {
+				devices = append(devices, "^ID_NET_DRIVER="+regexp.QuoteMeta(*device.Driver)+"$")
+			}
+			if device.MACAddress != nil {
+				// udev names the device by its permanent MAC address as enx<address without colons>
+				devices = append(devices, "^ID_NET_NAME_MAC="+components.MACAddressToNetName(*device.MACAddress)+"$")
+			}
+			if device.PCIAddress != nil {
+				devices = append(devices, "^ID_PATH=pci-"+regexp.QuoteMeta(strings.ToLower(*device.PCIAddress))+"$")
+			}
 			if device.VendorID != nil {
 				devices = append(devices, "^ID_VENDOR_ID="+*device.VendorID)
 			}
@@ -151,13 +194,31 @@ func NewNodePerformance(profile *performancev2.PerformanceProfile) (*tunedv1.Tun
 			// devicesUdevRegex = ^ID_MODEL_ID=DeviceID[\s\S]*^ID_VENDOR_ID=VendorID'
 			// devicesUdevRegex = ^ID_MODEL_ID=DeviceID[\s\S]*^ID_VENDOR_ID=VendorID[\s\S]*^INTERFACE=InterfaceName'
 			// devicesUdevRegex = ^ID_MODEL_ID=DeviceID[\s\S]*^ID_VENDOR_ID=VendorID[\s\S]*^INTERFACE=(?!InterfaceName)'
-			// Important note: The order of the key must be preserved - INTERFACE, ID_MODEL_ID, ID_VENDOR_ID (in that order)
+			// devicesUdevRegex = ^ID_NET_DRIVER=Driver$[\s\S]*^ID_NET_NAME_MAC=enxMACAddress$[\s\S]*^ID_PATH=pci-PCIAddress$'
+			// Important note: The order of the key must be preserved - ID_MODEL_ID, ID_NET_DRIVER, ID_NET_NAME_MAC, ID_PATH,
+			// ID_VENDOR_ID, INTERFACE (in that order), tuned matches the regex against the alphabetically sorted udev properties
 			devicesUdevRegex := strings.Join(devices, `[\s\S]*`)
 			if netPluginSequence > 0 {
 				netPluginString = "_" + strconv.Itoa(netPluginSequence)
 			}
-			tunedNetDevicesOutput = append(tunedNetDevicesOutput, fmt.Sprintf("\n[net%s]\ntype=net\ndevices_udev_regex=%s\nchannels=combined %d\n%s", netPluginString, devicesUdevRegex, reserveCPUcount, nfConntrackHashsize))
+			channels := int32(reserveCPUcount)
+			if device.Channels != nil {
+				channels = *device.Channels
+			}
+			tunedNetDevicesOutput = append(tunedNetDevicesOutput, fmt.Sprintf("\n[net%s]\ntype=net\ndevices_udev_regex=%s\nchannels=combined %d\n%s", netPluginString, devicesUdevRegex, channels, nfConntrackHashsize))
 			netPluginSequence++
+
+			// the global RFS socket flow table is shared by the receive queues of all the devices
+			if device.RFSFlowEntries != nil {
+				rfsSockFlowEntries += int64(*device.RFSFlowEntries) * int64(channels)
+			}
+		}
+		// the kernel caps the global RFS socket flow table
+		if rfsSockFlowEntries > maxRPSSockFlowEntries {
+			rfsSockFlowEntries = maxRPSSockFlowEntries
+		}
+		if rfsSockFlowEntries > 0 {
+			tunedNetDevicesOutput = append(tunedNetDevicesOutput, fmt.Sprintf("\n[sysctl_rfs]\ntype=sysctl\nnet.core.rps_sock_flow_entries=%d", rfsSockFlowEntries))
 		}
 		//nfConntrackHashsize
 		if len(tunedNetDevicesOutput) == 0 {
@@ -167,14 +228,68 @@ func NewNodePerformance(profile *performancev2.PerformanceProfile) (*tunedv1.Tun
 		}
 	}
 
-	if profile.Spec.WorkloadHints != nil {
-		if profile.Spec.WorkloadHints.RealTime != nil && *profile.Spec.WorkloadHints.RealTime {
-			templateArgs[templateRealTimeHint] = "true"
+	workloadHints, err := performancev2.ResolveWorkloadHints(profile.Spec.WorkloadHints)
+	if err != nil {
+		return nil, err
+	}
+
+	if workloadHints.Stalld {
+		templateArgs[templateStalld] = "true"
+	}
+
+	if workloadHints.UnlimitedRTRuntime {
+		templateArgs[templateUnlimitedRTRuntime] = "true"
+	}
+
+	if workloadHints.RealTimeTuning {
+		templateArgs[templateRealTimeTuning] = "true"
+	}
+
+	if workloadHints.HighPowerConsumption {
+		templateArgs[templateHighPowerConsumption] = "true"
+	}
+
+	switch workloadHints.CStates {
+	case performancev2.CStatesPolling:
+		templateArgs[templateCStatesPolling] = "true"
+	case performancev2.CStatesPerPod:
+		templateArgs[templateCStatesPerPod] = "true"
+	}
+
+	if workloadHints.CPUFrequency == performancev2.CPUFrequencyPerPod {
+		templateArgs[templateCPUFrequencyPerPod] = "true"
+	}
+
+	if profile.Spec.Power != nil {
+		powerPolicies, excludedDevices, err := getPowerPolicies(profile)
+		if err != nil {
+			return nil, err
+		}
+		if powerPolicies != "" {
+			templateArgs[templatePowerPolicies] = powerPolicies
+			templateArgs[templatePowerExcludedDevices] = excludedDevices
 		}
+	}
 
-		if profile.Spec.WorkloadHints.HighPowerConsumption != nil && *profile.Spec.WorkloadHints.HighPowerConsumption {
-			templateArgs[templateHighPowerConsumption] = "true"
+	if len(profile.Spec.Sysctls) > 0 {
+		var sysctls []string
+		for _, sysctl := range profile.Spec.Sysctls {
+			sysctls = append(sysctls, fmt.Sprintf("%s=%s", sysctl.Name, sysctl.Value))
 		}
+		templateArgs[templateSysctls] = strings.Join(sysctls, "\n")
+	}
+
+	if profile.Spec.KernelModules != nil {
+		if modules := getKernelModules(profile.Spec.KernelModules); modules != "" {
+			templateArgs[templateKernelModules] = modules
+		}
+		if len(profile.Spec.KernelModules.Blacklist) > 0 {
+			templateArgs[templateKernelModulesBlacklist] = strings.Join(profile.Spec.KernelModules.Blacklist, ",")
+		}
+	}
+
+	if len(profile.Spec.AdditionalTunedProfile) > 0 {
+		templateArgs[templateAdditionalTunedProfile] = strings.Join(profile.Spec.AdditionalTunedProfile, ",")
 	}
 
 	profileData, err := getProfileData(filepath.Join("tuned", components.ProfileNamePerformance), templateArgs)
@@ -201,6 +316,82 @@ func NewNodePerformance(profile *performancev2.PerformanceProfile) (*tunedv1.Tun
 	return new(name, profiles, recommends), nil
 }
 
+// getKernelModules returns the tuned modules plugin options, tuned only writes the modules parameters
+// that are used on the next load of the modules. The "+r" flag is not used because tuned would unload
+// and load the modules again on every profile apply, the modules are loaded on boot by the machine config.
+func getKernelModules(kernelModules *performancev2.KernelModules) string {
+	var modules []string
+	for _, options := range kernelModules.Options {
+		modules = append(modules, fmt.Sprintf("%s=%s", options.Name, options.Parameters))
+	}
+
+	return strings.Join(modules, "\n")
+}
+
+// getPowerPolicies returns the tuned cpu and sysfs plugin instances that apply the power policies
+// to the CPU sets, and the devices expression that excludes the CPUs covered by the policies
+// from the main cpu plugin instance.
+func getPowerPolicies(profile *performancev2.PerformanceProfile) (string, string, error) {
+	var instances []string
+	var excludedDevices []string
+
+	for _, v := range []struct {
+		name   string
+		cpus   *performancev2.CPUSet
+		policy *performancev2.CPUPowerPolicy
+	}{
+		{name: "reserved", cpus: profile.Spec.CPU.Reserved, policy: profile.Spec.Power.Reserved},
+		{name: "isolated", cpus: profile.Spec.CPU.Isolated, policy: profile.Spec.Power.Isolated},
+		{name: "shared", cpus: profile.Spec.CPU.Shared, policy: profile.Spec.Power.Shared},
+	} {
+		if v.cpus == nil || v.policy == nil {
+			continue
+		}
+
+		cpus, err := cpuset.Parse(string(*v.cpus))
+		if err != nil {
+			return "", "", err
+		}
+		if cpus.IsEmpty() {
+			continue
+		}
+
+		// match the CPUs by globs, so the size of the profile does not grow with the number of CPUs
+		devices := cpuSetDeviceGlobs(cpus)
+		var sysfs []string
+		for _, device := range devices {
+			excludedDevices = append(excludedDevices, "!"+device)
+
+			cpuPath := fmt.Sprintf("/sys/devices/system/cpu/%s", device)
+			if v.policy.MinFrequency != nil {
+				sysfs = append(sysfs, fmt.Sprintf("%s/cpufreq/scaling_min_freq=%d", cpuPath, *v.policy.MinFrequency))
+			}
+			if v.policy.MaxFrequency != nil {
+				sysfs = append(sysfs, fmt.Sprintf("%s/cpufreq/scaling_max_freq=%d", cpuPath, *v.policy.MaxFrequency))
+			}
+			if v.policy.MaxCState != nil && *v.policy.MaxCState < cpuIdleStatesMaxIndex {
+				sysfs = append(sysfs, fmt.Sprintf("%s/cpuidle/state[%d-%d]/disable=1", cpuPath, *v.policy.MaxCState+1, cpuIdleStatesMaxIndex))
+			}
+		}
+
+		governor := defaultCPUGovernor
+		if v.policy.Governor != nil {
+			governor = *v.policy.Governor
+		}
+		instance := fmt.Sprintf("\n[cpu_power_%s]\ntype=cpu\ndevices=%s\ngovernor=%s", v.name, strings.Join(devices, ","), governor)
+		if governor == defaultCPUGovernor {
+			instance += "\nenergy_perf_bias=performance"
+		}
+		instances = append(instances, instance)
+
+		if len(sysfs) > 0 {
+			instances = append(instances, fmt.Sprintf("\n[sysfs_power_%s]\ntype=sysfs\n%s", v.name, strings.Join(sysfs, "\n")))
+		}
+	}
+
+	return strings.Join(instances, "\n"), strings.Join(excludedDevices, ","), nil
+}
+
 func getProfileData(tunedTemplate string, data interface{}) (string, error) {
 	profileTemplate, err := template.ParseFS(assets.Tuned, tunedTemplate)
 	if err != nil {
@@ -217,3 +408,90 @@ func getProfileData(tunedTemplate string, data interface{}) (string, error) {
 func IsIRQBalancingGloballyDisabled(profile *performancev2.PerformanceProfile) bool {
 	return profile.Spec.GloballyDisableIrqLoadBalancing != nil && *profile.Spec.GloballyDisableIrqLoadBalancing
 }
+
+// cpuSetDeviceGlobs returns the glob patterns matching exactly the cpu devices of the CPU set,
+// for example "cpu[0-9]", "cpu1[0-5]" for the CPUs 0-15, the patterns are understood both by
+// the tuned devices option and by the tuned sysfs plugin.
+func cpuSetDeviceGlobs(cpus cpuset.CPUSet) []string {
+	var globs []string
+
+	ids := cpus.ToSlice()
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		for _, glob := range numberRangeGlobs(ids[i], ids[j]) {
+			globs = append(globs, "cpu"+glob)
+		}
+		i = j + 1
+	}
+
+	return globs
+}
+
+// numberRangeGlobs returns the glob patterns matching exactly the decimal numbers from lo to hi.
+func numberRangeGlobs(lo int, hi int) []string {
+	var globs []string
+
+	// split the range into ranges of numbers with the same number of digits
+	for lo <= hi {
+		upper := 9
+		for upper < lo {
+			upper = upper*10 + 9
+		}
+		if upper > hi {
+			upper = hi
+		}
+		globs = append(globs, sameLengthRangeGlobs(strconv.Itoa(lo), strconv.Itoa(upper))...)
+		lo = upper + 1
+	}
+
+	return globs
+}
+
+// sameLengthRangeGlobs returns the glob patterns matching exactly the decimal numbers from lo to hi
+// having the same number of digits.
+func sameLengthRangeGlobs(lo string, hi string) []string {
+	if len(lo) == 1 {
+		return []string{digitRangeGlob(lo[0], hi[0])}
+	}
+	if lo[0] == hi[0] {
+		var globs []string
+		for _, glob := range sameLengthRangeGlobs(lo[1:], hi[1:]) {
+			globs = append(globs, lo[:1]+glob)
+		}
+		return globs
+	}
+
+	var globs []string
+	rest := len(lo) - 1
+	first, last := lo[0], hi[0]
+	if lo[1:] != strings.Repeat("0", rest) {
+		for _, glob := range sameLengthRangeGlobs(lo[1:], strings.Repeat("9", rest)) {
+			globs = append(globs, lo[:1]+glob)
+		}
+		first++
+	}
+	if hi[1:] != strings.Repeat("9", rest) {
+		last--
+	}
+	if first <= last {
+		globs = append(globs, digitRangeGlob(first, last)+strings.Repeat("[0-9]", rest))
+	}
+	if hi[1:] != strings.Repeat("9", rest) {
+		for _, glob := range sameLengthRangeGlobs(strings.Repeat("0", rest), hi[1:]) {
+			globs = append(globs, hi[:1]+glob)
+		}
+	}
+
+	return globs
+}
+
+// digitRangeGlob returns the glob pattern matching the digits from lo to hi.
+func digitRangeGlob(lo byte, hi byte) string {
+	if lo == hi {
+		return string(lo)
+	}
+	return fmt.Sprintf("[%c-%c]", lo, hi)
+}
@@ -29,6 +29,7 @@ var (
 	cmdlineRealtime               = "+nohz_full=${isolated_cores} tsc=nowatchdog nosoftlockup nmi_watchdog=0 mce=off skew_tick=1 rcutree.kthread_prio=11"
 	cmdlineHighPowerConsumption   = "+processor.max_cstate=1 intel_idle.max_cstate=0 intel_pstate=disable"
 	cmdlineIdlePoll               = "+idle=poll"
+	cmdlinePstatePassive          = "+intel_pstate=passive"
 	cmdlineHugepages              = "+ default_hugepagesz=1G   hugepagesz=1G hugepages=4"
 	cmdlineAdditionalArgs         = "+ audit=0 processor.max_cstate=1 idle=poll intel_idle.max_cstate=0"
 	cmdlineDummy2MHugePages       = "+ default_hugepagesz=1G   hugepagesz=1G hugepages=4 hugepagesz=2M hugepages=0"
@@ -110,6 +111,37 @@ var _ = Describe("Tuned", func() {
 			})
 		})
 
+		When("workload hints are not set", func() {
+			It("should render the same realtime related parameters as before the hints were resolved", func() {
+				profile.Spec.WorkloadHints = nil
+				tunedData := getTunedStructuredData(profile)
+				_, err := tunedData.GetSection("service")
+				Expect(err).To(HaveOccurred(), "expected no stalld service")
+
+				schedulerSection, err := tunedData.GetSection("scheduler")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(schedulerSection.HasKey("sched_rt_runtime_us")).To(BeFalse())
+
+				sysctlSection, err := tunedData.GetSection("sysctl")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sysctlSection.HasKey("kernel.hung_task_timeout_secs")).To(BeFalse())
+				Expect(sysctlSection.HasKey("kernel.nmi_watchdog")).To(BeFalse())
+				Expect(sysctlSection.HasKey("kernel.sched_rt_runtime_us")).To(BeFalse())
+				Expect(sysctlSection.HasKey("vm.stat_interval")).To(BeFalse())
+
+				bootLoaderSection, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoaderSection.HasKey("cmdline_realtime")).To(BeFalse())
+				Expect(bootLoaderSection.Key("cmdline_cpu_part").String()).To(Equal(cmdlineCPUsPartitioning))
+				Expect(bootLoaderSection.Key("cmdline_isolation").String()).To(Equal(cmdlineWithoutStaticIsolation))
+
+				cpuSection, err := tunedData.GetSection("cpu")
+				Expect(err).ToNot(HaveOccurred())
+				Expect((cpuSection.Key("force_latency").String())).To(Equal("cstate.id:1|3"))
+				Expect((cpuSection.Key("governor").String())).To(Equal("performance"))
+			})
+		})
+
 		When("realtime hint disabled", func() {
 			It("should not contain realtime related parameters", func() {
 				profile.Spec.WorkloadHints = &performancev2.WorkloadHints{RealTime: pointer.BoolPtr(false)}
@@ -190,6 +222,221 @@ var _ = Describe("Tuned", func() {
 			})
 		})
 
+		Context("per pod power management hint enabled", func() {
+			It("should leave the power management of the pod CPUs to CRI-O", func() {
+				profile.Spec.WorkloadHints = &performancev2.WorkloadHints{PerPodPowerManagement: pointer.BoolPtr(true)}
+				tunedData := getTunedStructuredData(profile)
+
+				cpuSection, err := tunedData.GetSection("cpu")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cpuSection.Key("force_latency").String()).To(Equal("none"))
+				Expect(cpuSection.Key("governor").String()).To(Equal("schedutil|powersave"))
+				Expect(cpuSection.Key("energy_perf_bias").String()).To(Equal("normal"))
+				Expect(cpuSection.Key("min_perf_pct").String()).To(Equal("0"))
+
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.Key("cmdline_pstate").String()).To(Equal(cmdlinePstatePassive))
+				Expect(bootLoader.HasKey("cmdline_power_performance")).To(BeFalse())
+			})
+
+			It("should not set the intel_pstate passive mode when disabled", func() {
+				tunedData := getTunedStructuredData(profile)
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.HasKey("cmdline_pstate")).To(BeFalse())
+			})
+		})
+
+		Context("with housekeeping CPUs", func() {
+			It("should confine the housekeeping work to the housekeeping CPUs", func() {
+				irqCPUs := performancev2.CPUSet("0")
+				kernelThreadsCPUs := performancev2.CPUSet("0-1")
+				rcuCPUs := performancev2.CPUSet("1")
+				profile.Spec.GloballyDisableIrqLoadBalancing = pointer.BoolPtr(true)
+				profile.Spec.CPU.Housekeeping = &performancev2.HousekeepingCPUs{
+					IRQ:           &irqCPUs,
+					KernelThreads: &kernelThreadsCPUs,
+					RCU:           &rcuCPUs,
+				}
+				tunedData := getTunedStructuredData(profile)
+
+				variables, err := tunedData.GetSection("variables")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(variables.Key("housekeeping_irq_cores").String()).To(Equal("0"))
+				Expect(variables.Key("housekeeping_kthread_cores").String()).To(Equal("0-1"))
+				Expect(variables.Key("housekeeping_rcu_cores").String()).To(Equal("1"))
+
+				irqbalance, err := tunedData.GetSection("irqbalance")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(irqbalance.Key("banned_cpus").String()).To(Equal("${f:cpulist_invert:${housekeeping_irq_cores}}"))
+
+				sysfs, err := tunedData.GetSection("sysfs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sysfs.Key("/sys/devices/virtual/workqueue/cpumask").String()).To(Equal("${housekeeping_kthread_cpumask}"))
+
+				scheduler, err := tunedData.GetSection("scheduler")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(scheduler.Key("default_irq_smp_affinity").String()).To(Equal("${housekeeping_irq_cores}"))
+				Expect(scheduler.Key("group.kthreadd").String()).To(Equal(`0:*:1:${housekeeping_kthread_cpumask}:\[kthreadd\]`))
+				Expect(scheduler.Key("group.rcuo").String()).To(Equal(`0:*:1:${housekeeping_rcu_cpumask}:\[rcuo.*\]`))
+
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.Key("cmdline_cpu_part").String()).To(ContainSubstring("rcu_nocbs=${f:cpulist_invert:${housekeeping_rcu_cores}}"))
+				Expect(bootLoader.Key("cmdline_cpu_part").String()).To(ContainSubstring("irqaffinity=${housekeeping_irq_cores}"))
+			})
+
+			It("should keep the default housekeeping without housekeeping CPUs", func() {
+				tunedData := getTunedStructuredData(profile)
+
+				_, err := tunedData.GetSection("sysfs")
+				Expect(err).To(HaveOccurred())
+
+				scheduler, err := tunedData.GetSection("scheduler")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(scheduler.HasKey("group.kthreadd")).To(BeFalse())
+				Expect(scheduler.HasKey("group.rcuo")).To(BeFalse())
+			})
+		})
+
+		Context("with power policies", func() {
+			It("should tune the CPU sets with their own cpu and sysfs instances", func() {
+				profile.Spec.Power = &performancev2.PowerPolicies{
+					Reserved: &performancev2.CPUPowerPolicy{
+						Governor:     pointer.StringPtr("powersave"),
+						MinFrequency: pointer.Int32Ptr(800000),
+						MaxFrequency: pointer.Int32Ptr(2000000),
+					},
+					Isolated: &performancev2.CPUPowerPolicy{
+						MaxCState: pointer.Int32Ptr(1),
+					},
+				}
+				tunedData := getTunedStructuredData(profile)
+
+				cpuSection, err := tunedData.GetSection("cpu")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cpuSection.Key("force_latency").String()).To(Equal("none"))
+				Expect(cpuSection.Key("min_perf_pct").String()).To(Equal("0"))
+				Expect(cpuSection.Key("devices").String()).To(Equal("!cpu[0-3],!cpu[4-5]"))
+
+				reserved, err := tunedData.GetSection("cpu_power_reserved")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reserved.Key("type").String()).To(Equal("cpu"))
+				Expect(reserved.Key("devices").String()).To(Equal("cpu[0-3]"))
+				Expect(reserved.Key("governor").String()).To(Equal("powersave"))
+				Expect(reserved.HasKey("energy_perf_bias")).To(BeFalse())
+
+				reservedSysfs, err := tunedData.GetSection("sysfs_power_reserved")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reservedSysfs.Key("type").String()).To(Equal("sysfs"))
+				Expect(reservedSysfs.Key("/sys/devices/system/cpu/cpu[0-3]/cpufreq/scaling_min_freq").String()).To(Equal("800000"))
+				Expect(reservedSysfs.Key("/sys/devices/system/cpu/cpu[0-3]/cpufreq/scaling_max_freq").String()).To(Equal("2000000"))
+
+				isolated, err := tunedData.GetSection("cpu_power_isolated")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isolated.Key("devices").String()).To(Equal("cpu[4-5]"))
+				Expect(isolated.Key("governor").String()).To(Equal("performance"))
+				Expect(isolated.Key("energy_perf_bias").String()).To(Equal("performance"))
+
+				isolatedSysfs, err := tunedData.GetSection("sysfs_power_isolated")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isolatedSysfs.Key("/sys/devices/system/cpu/cpu[4-5]/cpuidle/state[2-9]/disable").String()).To(Equal("1"))
+				Expect(isolatedSysfs.HasKey("/sys/devices/system/cpu/cpu[4-5]/cpufreq/scaling_min_freq")).To(BeFalse())
+
+				_, err = tunedData.GetSection("cpu_power_shared")
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should match the CPUs by globs rather than one by one", func() {
+				isolated := performancev2.CPUSet("2-127")
+				profile.Spec.CPU.Isolated = &isolated
+				reserved := performancev2.CPUSet("0-1")
+				profile.Spec.CPU.Reserved = &reserved
+				profile.Spec.Power = &performancev2.PowerPolicies{
+					Isolated: &performancev2.CPUPowerPolicy{
+						MaxFrequency: pointer.Int32Ptr(2000000),
+					},
+				}
+				tunedData := getTunedStructuredData(profile)
+
+				isolatedSection, err := tunedData.GetSection("cpu_power_isolated")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isolatedSection.Key("devices").String()).To(Equal("cpu[2-9],cpu[1-9][0-9],cpu1[0-1][0-9],cpu12[0-7]"))
+
+				isolatedSysfs, err := tunedData.GetSection("sysfs_power_isolated")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isolatedSysfs.Keys()).To(HaveLen(5))
+				Expect(isolatedSysfs.Key("/sys/devices/system/cpu/cpu1[0-1][0-9]/cpufreq/scaling_max_freq").String()).To(Equal("2000000"))
+			})
+
+			It("should keep the node wide power settings without power policies", func() {
+				tunedData := getTunedStructuredData(profile)
+
+				cpuSection, err := tunedData.GetSection("cpu")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cpuSection.HasKey("devices")).To(BeFalse())
+
+				_, err = tunedData.GetSection("cpu_power_reserved")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with sysctls, kernel modules and additional tuned profiles", func() {
+			It("should merge them into the generated profile", func() {
+				profile.Spec.Sysctls = []performancev2.Sysctl{
+					{Name: "net.core.somaxconn", Value: "4096"},
+					{Name: "net.ipv4.conf.eno1.rp_filter", Value: "0"},
+				}
+				profile.Spec.KernelModules = &performancev2.KernelModules{
+					Load:      []string{"vfio-pci", "ixgbe"},
+					Blacklist: []string{"nouveau", "ice"},
+					Options: []performancev2.KernelModuleOptions{
+						{Name: "ixgbe", Parameters: "allow_unsupported_sfp=1"},
+						{Name: "i40e", Parameters: "max_vfs=8"},
+					},
+				}
+				profile.Spec.AdditionalTunedProfile = []string{"openshift-node-custom", "network-throughput"}
+				tunedData := getTunedStructuredData(profile)
+
+				mainSection, err := tunedData.GetSection("main")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mainSection.Key("include").String()).To(Equal("openshift-node,cpu-partitioning,openshift-node-custom,network-throughput"))
+
+				sysctlSection, err := tunedData.GetSection("sysctl")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sysctlSection.Key("net.core.somaxconn").String()).To(Equal("4096"))
+				Expect(sysctlSection.Key("net.ipv4.conf.eno1.rp_filter").String()).To(Equal("0"))
+				Expect(sysctlSection.Key("vm.swappiness").String()).To(Equal("10"))
+
+				modulesSection, err := tunedData.GetSection("modules")
+				Expect(err).ToNot(HaveOccurred())
+				// the loaded modules are loaded on boot by the machine config, tuned does not reload them
+				Expect(modulesSection.KeyStrings()).To(Equal([]string{"ixgbe", "i40e"}))
+				Expect(modulesSection.Key("ixgbe").String()).To(Equal("allow_unsupported_sfp=1"))
+				Expect(modulesSection.Key("i40e").String()).To(Equal("max_vfs=8"))
+
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.Key("cmdline_module_blacklist").String()).To(Equal("+modprobe.blacklist=nouveau,ice"))
+			})
+
+			It("should keep the generated profile unchanged without them", func() {
+				tunedData := getTunedStructuredData(profile)
+
+				mainSection, err := tunedData.GetSection("main")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mainSection.Key("include").String()).To(Equal("openshift-node,cpu-partitioning"))
+
+				_, err = tunedData.GetSection("modules")
+				Expect(err).To(HaveOccurred())
+
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.HasKey("cmdline_module_blacklist")).To(BeFalse())
+			})
+		})
+
 		It("should generate yaml with expected parameters for Isolated balancing disabled", func() {
 			profile.Spec.CPU.BalanceIsolated = pointer.BoolPtr(false)
 			tunedData := getTunedStructuredData(profile)
@@ -234,6 +481,22 @@ var _ = Describe("Tuned", func() {
 			Expect(strings.Count(manifest, "hugepages=")).To(BeNumerically("==", 2))
 		})
 
+		Context("with arm64 huge pages sizes", func() {
+			It("should pass the sizes to the kernel arguments", func() {
+				defaultSize := performancev2.HugePageSize("32M")
+				profile.Spec.HugePages.DefaultHugePagesSize = &defaultSize
+				profile.Spec.HugePages.Pages = []performancev2.HugePage{
+					{Size: "32M", Count: 8},
+					{Size: "64K", Count: 128},
+				}
+
+				tunedData := getTunedStructuredData(profile)
+				bootLoader, err := tunedData.GetSection("bootloader")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bootLoader.Key("cmdline_hugepages").String()).To(Equal("+ default_hugepagesz=32M   hugepagesz=32M hugepages=8 hugepagesz=64K hugepages=128"))
+			})
+		})
+
 		Context("with 1G default huge pages", func() {
 			Context("with requested 2M huge pages allocation on the specified node", func() {
 				It("should append the dummy 2M huge pages kernel arguments", func() {
@@ -452,7 +715,136 @@ var _ = Describe("Tuned", func() {
 					channelsRegex := regexp.MustCompile(`\s*\[net\]\\ntype=net\\ndevices_udev_regex=` + devicesUdevRegex + `\\nchannels=combined\s*` + strconv.Itoa(reserveCPUcount) + `\s*`)
 					Expect(channelsRegex.MatchString(manifest)).To(BeTrue())
 				})
+				It("should set by PCI address, driver and MAC address with reserved CPUs count", func() {
+					profile.Spec.Net = &performancev2.Net{
+						UserLevelNetworking: pointer.BoolPtr(true),
+						Devices: []performancev2.Device{
+							{
+								PCIAddress: pointer.StringPtr("0000:3B:00.0"),
+								Driver:     pointer.StringPtr("ice"),
+								MACAddress: pointer.StringPtr("52:54:00:AB:CD:EF"),
+								VendorID:   pointer.StringPtr("0x8086"),
+							},
+						}}
+					tunedData := getTunedStructuredData(profile)
+					netSection, err := tunedData.GetSection("net")
+					Expect(err).ToNot(HaveOccurred())
+					devicesUdevRegex := netSection.Key("devices_udev_regex").String()
+					Expect(devicesUdevRegex).To(Equal(`^ID_NET_DRIVER=ice$[\s\S]*^ID_NET_NAME_MAC=enx525400abcdef$[\s\S]*^ID_PATH=pci-0000:3b:00\.0$[\s\S]*^ID_VENDOR_ID=0x8086`))
+					Expect(netSection.Key("channels").String()).To(Equal("combined 4"))
+
+					// tuned matches the regex against the alphabetically sorted udev properties of the device
+					properties := strings.Join([]string{
+						"ID_MODEL_ID=0x1592",
+						"ID_NET_DRIVER=ice",
+						"ID_NET_NAME_MAC=enx525400abcdef",
+						"ID_PATH=pci-0000:3b:00.0",
+						"ID_VENDOR_ID=0x8086",
+						"INTERFACE=ens1f0",
+					}, "\n")
+					Expect(regexp.MustCompile("(?m)" + devicesUdevRegex).MatchString(properties)).To(BeTrue())
+					Expect(regexp.MustCompile("(?m)" + devicesUdevRegex).MatchString(strings.Replace(properties, "00.0", "00.1", 1))).To(BeFalse())
+				})
+				It("should set the device channels and the RFS socket flow entries", func() {
+					profile.Spec.Net = &performancev2.Net{
+						UserLevelNetworking: pointer.BoolPtr(true),
+						Devices: []performancev2.Device{
+							{
+								InterfaceName:  pointer.StringPtr("ens1f0"),
+								Channels:       pointer.Int32Ptr(16),
+								RFSFlowEntries: pointer.Int32Ptr(2048),
+							},
+							{
+								InterfaceName:  pointer.StringPtr("ens2f0"),
+								RFSFlowEntries: pointer.Int32Ptr(4096),
+							},
+						}}
+					tunedData := getTunedStructuredData(profile)
+					netSection, err := tunedData.GetSection("net")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netSection.Key("channels").String()).To(Equal("combined 16"))
+					netSection, err = tunedData.GetSection("net_1")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netSection.Key("channels").String()).To(Equal("combined 4"))
+
+					rfsSection, err := tunedData.GetSection("sysctl_rfs")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rfsSection.Key("type").String()).To(Equal("sysctl"))
+					// 16 channels * 2048 entries + 4 channels * 4096 entries
+					Expect(rfsSection.Key("net.core.rps_sock_flow_entries").String()).To(Equal("49152"))
+				})
+				It("should cap the RFS socket flow entries to the kernel limit", func() {
+					profile.Spec.Net = &performancev2.Net{
+						UserLevelNetworking: pointer.BoolPtr(true),
+						Devices: []performancev2.Device{
+							{
+								InterfaceName:  pointer.StringPtr("ens1f0"),
+								Channels:       pointer.Int32Ptr(1 << 20),
+								RFSFlowEntries: pointer.Int32Ptr(1 << 20),
+							},
+						}}
+					tunedData := getTunedStructuredData(profile)
+					rfsSection, err := tunedData.GetSection("sysctl_rfs")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rfsSection.Key("net.core.rps_sock_flow_entries").String()).To(Equal(strconv.Itoa(maxRPSSockFlowEntries)))
+				})
+				It("should cap the RFS socket flow entries of several devices to the kernel limit", func() {
+					// every device fits the kernel limit, but their sum does not
+					profile.Spec.Net = &performancev2.Net{
+						UserLevelNetworking: pointer.BoolPtr(true),
+						Devices: []performancev2.Device{
+							{
+								InterfaceName:  pointer.StringPtr("ens1f0"),
+								Channels:       pointer.Int32Ptr(1 << 14),
+								RFSFlowEntries: pointer.Int32Ptr(1 << 14),
+							},
+							{
+								InterfaceName:  pointer.StringPtr("ens2f0"),
+								Channels:       pointer.Int32Ptr(1 << 14),
+								RFSFlowEntries: pointer.Int32Ptr(1 << 14),
+							},
+							{
+								InterfaceName:  pointer.StringPtr("ens3f0"),
+								Channels:       pointer.Int32Ptr(1 << 14),
+								RFSFlowEntries: pointer.Int32Ptr(1 << 14),
+							},
+						}}
+					tunedData := getTunedStructuredData(profile)
+					rfsSection, err := tunedData.GetSection("sysctl_rfs")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rfsSection.Key("net.core.rps_sock_flow_entries").String()).To(Equal(strconv.Itoa(maxRPSSockFlowEntries)))
+				})
+				It("should not set the RFS socket flow entries without RFS", func() {
+					profile.Spec.Net = &performancev2.Net{
+						UserLevelNetworking: pointer.BoolPtr(true),
+						Devices: []performancev2.Device{
+							{
+								InterfaceName: pointer.StringPtr("ens1f0"),
+							},
+						}}
+					tunedData := getTunedStructuredData(profile)
+					_, err := tunedData.GetSection("sysctl_rfs")
+					Expect(err).To(HaveOccurred())
+				})
 			})
 		})
 	})
 })
+
+var _ = Describe("CPU set device globs", func() {
+	It("should match exactly the CPUs of the CPU set", func() {
+		for cpus, expected := range map[string][]string{
+			"7":             {"cpu7"},
+			"0-3":           {"cpu[0-3]"},
+			"1,3,5":         {"cpu1", "cpu3", "cpu5"},
+			"8-23":          {"cpu[8-9]", "cpu1[0-9]", "cpu2[0-3]"},
+			"10-39":         {"cpu[1-3][0-9]"},
+			"95-205":        {"cpu9[5-9]", "cpu1[0-9][0-9]", "cpu20[0-5]"},
+			"0-1,64-65,100": {"cpu[0-1]", "cpu6[4-5]", "cpu100"},
+		} {
+			set, err := cpuset.Parse(cpus)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpuSetDeviceGlobs(set)).To(Equal(expected), "unexpected globs for the CPUs %s", cpus)
+		}
+	})
+})
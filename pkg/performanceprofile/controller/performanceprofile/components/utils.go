@@ -122,6 +122,19 @@ func NewCPULists(reservedList, isolatedList, offlinedList string) (*CPULists, er
 	}, nil
 }
 
+// CPUListsUnion returns the text representation of the union of the provided cpu lists
+func CPUListsUnion(cpuLists ...string) (string, error) {
+	union := cpuset.NewCPUSet()
+	for _, cpuList := range cpuLists {
+		cpus, err := cpuset.Parse(cpuList)
+		if err != nil {
+			return "", err
+		}
+		union = union.Union(cpus)
+	}
+	return union.String(), nil
+}
+
 // CPUMaskToCPUSet parses a CPUSet received in a Mask Format, see:
 // https://man7.org/linux/man-pages/man7/cpuset.7.html#FORMATS
 func CPUMaskToCPUSet(cpuMask string) (cpuset.CPUSet, error) {
@@ -153,6 +166,13 @@ func CPUMaskToCPUSet(cpuMask string) (cpuset.CPUSet, error) {
 	return builder.Result(), nil
 }
 
+// MACAddressToNetName returns the enx<address> name udev gives the network device with the
+// permanent MAC address in its ID_NET_NAME_MAC property, the property is not set for the
+// devices whose address is not the permanent one
+func MACAddressToNetName(macAddress string) string {
+	return "enx" + strings.ToLower(strings.Replace(macAddress, ":", "", -1))
+}
+
 func ListToString(cpus []int) string {
 	items := make([]string, len(cpus))
 	for idx, cpu := range cpus {
@@ -31,6 +31,12 @@ func intersectHelper(cpuListA, cpuListB string) ([]int, error) {
 }
 
 var _ = Describe("Components utils", func() {
+	Context("Convert MAC address to network device name", func() {
+		It("should generate the udev name of the permanent MAC address", func() {
+			Expect(MACAddressToNetName("52:54:00:AB:CD:EF")).To(Equal("enx525400abcdef"))
+		})
+	})
+
 	Context("Convert CPU list to CPU mask", func() {
 		It("should generate a valid CPU mask from CPU list ", func() {
 			for _, cpuEntry := range cpuListToMask {
@@ -99,5 +105,18 @@ var _ = Describe("Components utils", func() {
 				}
 			}
 		})
+
+		It("should compute cpulist unions", func() {
+			union, err := CPUListsUnion("0-3", "8-9", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(union).To(Equal("0-3,8-9"))
+
+			union, err = CPUListsUnion("0-3", "2-5")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(union).To(Equal("0-5"))
+
+			_, err = CPUListsUnion("0-3", "0-")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
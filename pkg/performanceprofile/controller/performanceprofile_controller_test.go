@@ -563,6 +563,24 @@ var _ = Describe("Controller", func() {
 				Expect(*updatedProfile.Status.Tuned).To(Equal(tunedNamespacedName))
 			})
 
+			It("should update status with resolved workload hints", func() {
+				r := newFakeReconciler(profile, mc, kc, tunedPerformance, profileMCP)
+				Expect(reconcileTimes(r, request, 1)).To(Equal(reconcile.Result{}))
+
+				updatedProfile := &performancev2.PerformanceProfile{}
+				key := types.NamespacedName{
+					Name:      profile.Name,
+					Namespace: metav1.NamespaceNone,
+				}
+				Expect(r.Get(context.TODO(), key, updatedProfile)).ToNot(HaveOccurred())
+				Expect(updatedProfile.Status.WorkloadHints).NotTo(BeNil())
+				Expect(updatedProfile.Status.WorkloadHints.RealTime).To(BeTrue())
+				Expect(updatedProfile.Status.WorkloadHints.Stalld).To(BeTrue())
+				Expect(updatedProfile.Status.WorkloadHints.UnlimitedRTRuntime).To(BeTrue())
+				Expect(updatedProfile.Status.WorkloadHints.CStates).To(Equal(performancev2.CStatesLatencyLimited))
+				Expect(updatedProfile.Status.WorkloadHints.CPUFrequency).To(Equal(performancev2.CPUFrequencyPerformance))
+			})
+
 			It("should update status with generated runtime class", func() {
 				r := newFakeReconciler(profile, mc, kc, tunedPerformance, runtimeClass, profileMCP)
 				Expect(reconcileTimes(r, request, 1)).To(Equal(reconcile.Result{}))
@@ -3,6 +3,7 @@ package controller
 import (
 	"bytes"
 	"context"
+	"reflect"
 	"time"
 
 	performancev2 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/performanceprofile/v2"
@@ -39,25 +40,7 @@ func (r *PerformanceProfileReconciler) updateStatus(profile *performancev2.Perfo
 	}
 
 	// check if we need to update the status
-	modified := false
-
-	// since we always set the same four conditions, we don't need to check if we need to remove old conditions
-	for _, newCondition := range profileCopy.Status.Conditions {
-		oldCondition := conditionsv1.FindStatusCondition(profile.Status.Conditions, newCondition.Type)
-		if oldCondition == nil {
-			modified = true
-			break
-		}
-
-		// ignore timestamps to avoid infinite reconcile loops
-		if oldCondition.Status != newCondition.Status ||
-			oldCondition.Reason != newCondition.Reason ||
-			oldCondition.Message != newCondition.Message {
-
-			modified = true
-			break
-		}
-	}
+	modified := conditionsModified(profile.Status.Conditions, profileCopy.Status.Conditions)
 
 	if profileCopy.Status.Tuned == nil {
 		tunedNamespacedname := types.NamespacedName{
@@ -75,6 +58,14 @@ func (r *PerformanceProfileReconciler) updateStatus(profile *performancev2.Perfo
 		modified = true
 	}
 
+	// an unsupported combination is reported by the components creation failure condition
+	if workloadHints, err := performancev2.ResolveWorkloadHints(profile.Spec.WorkloadHints); err == nil {
+		if !reflect.DeepEqual(profileCopy.Status.WorkloadHints, workloadHints) {
+			profileCopy.Status.WorkloadHints = workloadHints
+			modified = true
+		}
+	}
+
 	if !modified {
 		return nil
 	}
@@ -83,6 +74,25 @@ func (r *PerformanceProfileReconciler) updateStatus(profile *performancev2.Perfo
 	return r.Status().Update(context.TODO(), profileCopy)
 }
 
+// conditionsModified returns true if any of the conditions 'newConditions' differs from 'oldConditions'.
+func conditionsModified(oldConditions, newConditions []conditionsv1.Condition) bool {
+	// since we always set the same four conditions, we don't need to check if we need to remove old conditions
+	for _, newCondition := range newConditions {
+		oldCondition := conditionsv1.FindStatusCondition(oldConditions, newCondition.Type)
+		if oldCondition == nil {
+			return true
+		}
+
+		// ignore timestamps to avoid infinite reconcile loops
+		if oldCondition.Status != newCondition.Status ||
+			oldCondition.Reason != newCondition.Reason ||
+			oldCondition.Message != newCondition.Message {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *PerformanceProfileReconciler) getAvailableConditions() []conditionsv1.Condition {
 	now := time.Now()
 	return []conditionsv1.Condition{
@@ -221,13 +231,18 @@ func (r *PerformanceProfileReconciler) getKubeletConditionsByProfile(profile *pe
 }
 
 func (r *PerformanceProfileReconciler) getTunedConditionsByProfile(profile *performancev2.PerformanceProfile) ([]conditionsv1.Condition, error) {
+	return r.getTunedConditionsByNodeSelector(profile.Name, labels.SelectorFromSet(profile.Spec.NodeSelector))
+}
+
+// getTunedConditionsByNodeSelector returns Degraded conditions for performance profile 'profileName'
+// if any of the Tuned Profiles of the Nodes selected by 'selector' is degraded.
+func (r *PerformanceProfileReconciler) getTunedConditionsByNodeSelector(profileName string, selector labels.Selector) ([]conditionsv1.Condition, error) {
 	tunedProfileList := &tunedv1.ProfileList{}
 	if err := r.List(context.TODO(), tunedProfileList); err != nil {
-		klog.Errorf("Cannot list Tuned Profiles to match with profile %q : %v", profile.Name, err)
+		klog.Errorf("Cannot list Tuned Profiles to match with profile %q : %v", profileName, err)
 		return nil, err
 	}
 
-	selector := labels.SelectorFromSet(profile.Spec.NodeSelector)
 	nodes := &corev1.NodeList{}
 	if err := r.List(context.TODO(), nodes, &client.ListOptions{LabelSelector: selector}); err != nil {
 		return nil, err
@@ -0,0 +1,138 @@
+package tuned
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+const (
+	tunedPluginsGlob      = "/usr/lib/python3*/site-packages/tuned/plugins/" + tunedPluginFileGlob
+	sysCPUFreqDriverFile  = "/sys/devices/system/cpu/cpufreq/policy0/scaling_driver"
+	sysCPUIdleDriverFile  = "/sys/devices/system/cpu/cpuidle/current_driver"
+	sysNUMANodesGlob      = "/sys/devices/system/node/node[0-9]*"
+	sysNetInterfacesGlob  = "/sys/class/net/*"
+	virtWhatCmd           = "/usr/sbin/virt-what"
+	tunedPluginFilePrefix = "plugin_"
+	tunedPluginFileGlob   = tunedPluginFilePrefix + "*.py"
+)
+
+// readSysFile returns the trimmed content of sysfs file 'file' or an empty
+// string if the file cannot be read.
+func readSysFile(file string) string {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// discoverVirtualization returns the virtualization technologies virt-what
+// detects on the Node.
+func discoverVirtualization() []string {
+	out, err := execCmd([]string{virtWhatCmd})
+	if err != nil {
+		klog.Errorf("failed to detect virtualization: %v", err)
+		return nil
+	}
+	return strings.Fields(out)
+}
+
+// discoverTunedPlugins returns the names of the TuneD plug-ins available to
+// the TuneD daemon.
+func discoverTunedPlugins() []string {
+	return tunedPlugins(tunedPluginsGlob)
+}
+
+// tunedPlugins returns the names of the TuneD plug-ins whose sources match
+// glob 'pluginsGlob'.
+func tunedPlugins(pluginsGlob string) []string {
+	var plugins []string
+
+	files, err := filepath.Glob(pluginsGlob)
+	if err != nil {
+		klog.Errorf("failed to list TuneD plug-ins: %v", err)
+		return nil
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".py")
+		plugins = append(plugins, strings.TrimPrefix(name, tunedPluginFilePrefix))
+	}
+	sort.Strings(plugins)
+
+	return plugins
+}
+
+// discoverNUMANodes returns the NUMA nodes of the Node.
+func discoverNUMANodes() []tunedv1.NUMANodeCapabilities {
+	return numaNodes(sysNUMANodesGlob)
+}
+
+// numaNodes returns the NUMA nodes whose sysfs directories match glob
+// 'nodesGlob'.
+func numaNodes(nodesGlob string) []tunedv1.NUMANodeCapabilities {
+	var nodes []tunedv1.NUMANodeCapabilities
+
+	dirs, _ := filepath.Glob(nodesGlob)
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, tunedv1.NUMANodeCapabilities{
+			ID:   id,
+			CPUs: readSysFile(dir + "/cpulist"),
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	return nodes
+}
+
+// discoverNetworkInterfaces returns the network interfaces backed by a device
+// and their drivers.  Virtual interfaces are skipped.
+func discoverNetworkInterfaces() []tunedv1.NetworkInterfaceCapabilities {
+	return networkInterfaces(sysNetInterfacesGlob)
+}
+
+// networkInterfaces returns the network interfaces whose sysfs directories
+// match glob 'ifacesGlob' and are backed by a device, and their drivers.
+func networkInterfaces(ifacesGlob string) []tunedv1.NetworkInterfaceCapabilities {
+	var ifaces []tunedv1.NetworkInterfaceCapabilities
+
+	dirs, _ := filepath.Glob(ifacesGlob)
+	for _, dir := range dirs {
+		driver, err := os.Readlink(dir + "/device/driver")
+		if err != nil {
+			continue
+		}
+		ifaces = append(ifaces, tunedv1.NetworkInterfaceCapabilities{
+			Name:   filepath.Base(dir),
+			Driver: filepath.Base(driver),
+		})
+	}
+	sort.Slice(ifaces, func(i, j int) bool { return ifaces[i].Name < ifaces[j].Name })
+
+	return ifaces
+}
+
+// discoverCapabilities discovers the capabilities of the Node.
+func discoverCapabilities() *tunedv1.NodeCapabilities {
+	klog.Infof("discovering Node capabilities")
+
+	return &tunedv1.NodeCapabilities{
+		Virtualization:    discoverVirtualization(),
+		TunedPlugins:      discoverTunedPlugins(),
+		CPUFreqDriver:     readSysFile(sysCPUFreqDriverFile),
+		CPUIdleDriver:     readSysFile(sysCPUIdleDriverFile),
+		NUMANodes:         discoverNUMANodes(),
+		NetworkInterfaces: discoverNetworkInterfaces(),
+	}
+}
@@ -0,0 +1,96 @@
+package tuned
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func writeTestFile(t *testing.T, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTunedPlugins(t *testing.T) {
+	root := t.TempDir()
+	pluginsDir := filepath.Join(root, "usr/lib/python3.9/site-packages/tuned/plugins")
+	for _, file := range []string{"plugin_sysctl.py", "plugin_cpu.py", "base.py", "plugin_net.pyc"} {
+		writeTestFile(t, filepath.Join(pluginsDir, file), "")
+	}
+
+	plugins := tunedPlugins(filepath.Join(root, "usr/lib/python3*/site-packages/tuned/plugins", tunedPluginFileGlob))
+	expected := []string{"cpu", "sysctl"}
+	if !reflect.DeepEqual(plugins, expected) {
+		t.Errorf("want %v, have %v", expected, plugins)
+	}
+}
+
+func TestNUMANodes(t *testing.T) {
+	root := t.TempDir()
+	nodesDir := filepath.Join(root, "sys/devices/system/node")
+	writeTestFile(t, filepath.Join(nodesDir, "node10/cpulist"), "40-47\n")
+	writeTestFile(t, filepath.Join(nodesDir, "node2/cpulist"), "16-23\n")
+	writeTestFile(t, filepath.Join(nodesDir, "node0/cpulist"), "0-7,64-71\n")
+	// no cpulist, e.g. a memory-only node
+	if err := os.MkdirAll(filepath.Join(nodesDir, "node1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// not a NUMA node
+	writeTestFile(t, filepath.Join(nodesDir, "possible"), "0-10\n")
+
+	nodes := numaNodes(filepath.Join(nodesDir, "node[0-9]*"))
+	expected := []tunedv1.NUMANodeCapabilities{
+		{ID: 0, CPUs: "0-7,64-71"},
+		{ID: 1, CPUs: ""},
+		{ID: 2, CPUs: "16-23"},
+		{ID: 10, CPUs: "40-47"},
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("want %v, have %v", expected, nodes)
+	}
+
+	if nodes := numaNodes(filepath.Join(root, "missing", "node[0-9]*")); nodes != nil {
+		t.Errorf("want no NUMA nodes, have %v", nodes)
+	}
+}
+
+func TestNetworkInterfaces(t *testing.T) {
+	root := t.TempDir()
+	netDir := filepath.Join(root, "sys/class/net")
+	driversDir := filepath.Join(root, "sys/bus/pci/drivers")
+	for _, driver := range []string{"ice", "i40e"} {
+		if err := os.MkdirAll(filepath.Join(driversDir, driver), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for iface, driver := range map[string]string{"ens2f0": "i40e", "ens1f0": "ice"} {
+		if err := os.MkdirAll(filepath.Join(netDir, iface, "device"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(driversDir, driver), filepath.Join(netDir, iface, "device/driver")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// virtual interfaces are not backed by a device
+	for _, iface := range []string{"lo", "br-ex"} {
+		if err := os.MkdirAll(filepath.Join(netDir, iface), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ifaces := networkInterfaces(filepath.Join(netDir, "*"))
+	expected := []tunedv1.NetworkInterfaceCapabilities{
+		{Name: "ens1f0", Driver: "ice"},
+		{Name: "ens2f0", Driver: "i40e"},
+	}
+	if !reflect.DeepEqual(ifaces, expected) {
+		t.Errorf("want %v, have %v", expected, ifaces)
+	}
+}
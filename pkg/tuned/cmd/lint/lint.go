@@ -0,0 +1,169 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/tuned"
+	"github.com/openshift/cluster-node-tuning-operator/pkg/util"
+)
+
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+
+	defaultProfilesDir = "assets/tuned/daemon/profiles"
+	defaultPluginsDir  = "assets/tuned/daemon/tuned/plugins"
+)
+
+type lintOpts struct {
+	tunedInputFiles tunedFiles
+	profilesDir     string
+	pluginsDir      string
+	output          string
+}
+
+type tunedFiles []string
+
+func (tf *tunedFiles) String() string {
+	return fmt.Sprint(*tf)
+}
+
+func (tf *tunedFiles) Type() string {
+	return "tunedFiles"
+}
+
+// Set parses a comma-separated list of files and directories and stores it in tf.
+func (tf *tunedFiles) Set(value string) error {
+	if len(*tf) > 0 {
+		return errors.New("tuned-input-files flag already set")
+	}
+
+	for _, s := range strings.Split(value, ",") {
+		*tf = append(*tf, s)
+	}
+	return nil
+}
+
+// lintResult is the output of the lint command.
+type lintResult struct {
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+	Findings []tuned.LintFinding `json:"findings"`
+}
+
+// NewLintCommand creates a lint command.
+func NewLintCommand() *cobra.Command {
+	lintOpts := lintOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check Tuned manifests against the TuneD profiles shipped with the operand",
+		Long: `Check Tuned manifests for INI syntax errors, unknown TuneD plug-ins, missing includes,
+include cycles, duplicate or conflicting priorities and shadowed recommend rules.
+Exits with status 1 if any findings of severity "error" are reported.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := lintOpts.Validate(); err != nil {
+				klog.Fatal(err)
+			}
+
+			result, err := lintOpts.Run(os.Stdout)
+			if err != nil {
+				klog.Fatal(err)
+			}
+			if result.Errors > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	lintOpts.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (l *lintOpts) AddFlags(fs *pflag.FlagSet) {
+	fs.Var(&l.tunedInputFiles, "tuned-input-files", "A comma-separated list of Tuned manifest files or directories.")
+	fs.StringVar(&l.profilesDir, "profiles-dir", defaultProfilesDir, "Directory with the TuneD profiles shipped with the operand.")
+	fs.StringVar(&l.pluginsDir, "plugins-dir", defaultPluginsDir, "Directory with the sources of the TuneD plug-ins shipped with the operand; plug-ins are not checked if empty.")
+	fs.StringVarP(&l.output, "output", "o", outputJSON, "Output format: json or yaml.")
+}
+
+func (l *lintOpts) Validate() error {
+	if len(l.tunedInputFiles) == 0 {
+		return fmt.Errorf("tuned-input-files must be specified")
+	}
+
+	if l.output != outputJSON && l.output != outputYAML {
+		return fmt.Errorf("unsupported output format %q", l.output)
+	}
+
+	return nil
+}
+
+func (l *lintOpts) Run(w io.Writer) (*lintResult, error) {
+	scheme := runtime.NewScheme()
+	if err := tunedv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	objs, err := util.ReadManifests(l.tunedInputFiles, serializer.NewCodecFactory(scheme).UniversalDeserializer())
+	if err != nil {
+		return nil, err
+	}
+
+	var tuneds []*tunedv1.Tuned
+	for _, obj := range objs {
+		if t, ok := obj.(*tunedv1.Tuned); ok {
+			tuneds = append(tuneds, t)
+		}
+	}
+	if len(tuneds) == 0 {
+		return nil, fmt.Errorf("no Tuned objects found in %s", strings.Join(l.tunedInputFiles, ","))
+	}
+
+	findings, err := tuned.LintTuneds(tuneds, l.profilesDir, l.pluginsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &lintResult{Findings: findings}
+	if result.Findings == nil {
+		result.Findings = []tuned.LintFinding{}
+	}
+	for _, f := range findings {
+		switch f.Severity {
+		case tuned.LintSeverityError:
+			result.Errors++
+		case tuned.LintSeverityWarning:
+			result.Warnings++
+		}
+	}
+
+	var out []byte
+	if l.output == outputYAML {
+		out, err = yaml.Marshal(result)
+	} else {
+		out, err = json.MarshalIndent(result, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(out); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
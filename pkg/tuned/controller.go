@@ -11,6 +11,7 @@ import (
 	"net"       // net.Conn
 	"os"        // os.Exit(), os.Stderr, ...
 	"os/exec"   // os.Exec()
+	"reflect"   // reflect.DeepEqual()
 	"strconv"   // strconv
 	"strings"   // strings.Join()
 	"syscall"   // syscall.SIGHUP, ...
@@ -18,6 +19,7 @@ import (
 
 	fsnotify "gopkg.in/fsnotify.v1"
 	"gopkg.in/ini.v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	kmeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -43,6 +45,7 @@ const (
 	scError
 	scTimeout
 	scUnknown
+	scRolledBack
 )
 
 // Constants
@@ -117,6 +120,8 @@ type Controller struct {
 		// Did the command-line parameters to run the TuneD daemon change?
 		// In other words, is a complete restart of the TuneD daemon needed?
 		daemon bool
+		// Did the operator request or cancel a rollback of the node-level tuning?
+		rollback bool
 	}
 
 	daemon struct {
@@ -136,6 +141,18 @@ type Controller struct {
 		stopping bool
 		// the TuneD profile we wish to be applied.
 		recommendedProfile string
+		// the revision of the rendered Tuned the TuneD profiles were extracted from.
+		renderedRevision string
+		// the revision of the rendered Tuned when the TuneD daemon was last (re)loaded.
+		reloadRevision string
+		// rollback is true while the operator requests the node-level tuning to be rolled back.
+		rollback bool
+		// requirements of the TuneD profile we wish to be applied.
+		requirements tunedv1.TunedProfileRequirements
+		// description of the requirements the Node does not meet.
+		requirementsUnmet string
+		// capabilities of the Node discovered at startup.
+		capabilities *tunedv1.NodeCapabilities
 	}
 
 	tunedCmd     *exec.Cmd       // external command (tuned) being prepared or run
@@ -208,6 +225,7 @@ func newController(stopCh <-chan struct{}) (*Controller, error) {
 		tunedTimeout: tunedInitialTimeout,
 	}
 	controller.tunedTicker.Stop() // The ticker will be started/reset when TuneD starts.
+	controller.daemon.capabilities = discoverCapabilities()
 
 	return controller, nil
 }
@@ -265,6 +283,11 @@ func (c *Controller) sync(key wqKey) error {
 		}
 		klog.V(2).Infof("sync(): Tuned %s", key.name)
 
+		if c.daemon.rollback {
+			// Do not extract any TuneD profiles while the node-level tuning is rolled back.
+			return nil
+		}
+
 		tuned, err := c.listers.TunedResources.Get(key.name)
 		if err != nil {
 			return fmt.Errorf("failed to get Tuned %s: %v", key.name, err)
@@ -275,6 +298,8 @@ func (c *Controller) sync(key wqKey) error {
 			return err
 		}
 		c.change.rendered = change
+		c.daemon.renderedRevision = tuned.Annotations[tunedv1.RenderedRevisionAnnotationKey]
+		c.requirementsUpdate(tuned.Spec.Profile)
 		// Notify the event processor that the Tuned k8s object containing TuneD profiles changed.
 		c.wqTuneD.Add(wqKey{kind: wqKindDaemon})
 
@@ -291,6 +316,16 @@ func (c *Controller) sync(key wqKey) error {
 			return fmt.Errorf("failed to get Profile %s: %v", key.name, err)
 		}
 
+		if profile.Spec.Config.Rollback != c.daemon.rollback {
+			c.change.rollback = true
+			c.daemon.rollback = profile.Spec.Config.Rollback
+		}
+		if c.daemon.rollback {
+			// Notify the event processor that the operator requested a rollback of the node-level tuning.
+			c.wqTuneD.Add(wqKey{kind: wqKindDaemon})
+			return nil
+		}
+
 		err = providerExtract(profile.Spec.Config.ProviderName)
 		if err != nil {
 			return err
@@ -303,6 +338,14 @@ func (c *Controller) sync(key wqKey) error {
 		}
 		c.change.profile = true
 
+		tuned, err := c.listers.TunedResources.Get(tunedv1.TunedRenderedResourceName)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get Tuned %s: %v", tunedv1.TunedRenderedResourceName, err)
+		}
+		if tuned != nil {
+			c.requirementsUpdate(tuned.Spec.Profile)
+		}
+
 		if c.daemon.debug != profile.Spec.Config.Debug {
 			c.change.daemon = true // A complete restart of the TuneD daemon is needed due to a debugging request switched on or off.
 			c.daemon.debug = profile.Spec.Config.Debug
@@ -377,10 +420,17 @@ func profilesExtract(profiles []tunedv1.TunedProfile, recommendedProfile string)
 	)
 	klog.Infof("extracting TuneD profiles")
 
-	// Get a list of TuneD profiles names the recommended profile depends on.
-	recommendedProfileDeps := profileDepends(recommendedProfile)
-	// Add the recommended profile itself.
-	recommendedProfileDeps[recommendedProfile] = true
+	// Get a list of TuneD profiles names the recommended profile depends on.  The recommended
+	// profile can be a space-separated list of profiles TuneD merges, e.g. when tenant profiles
+	// are merged into the profile selected by cluster administrators.
+	recommendedProfileDeps := map[string]bool{}
+	for _, p := range strings.Fields(recommendedProfile) {
+		for dep := range profileDepends(p) {
+			recommendedProfileDeps[dep] = true
+		}
+		// Add the recommended profile itself.
+		recommendedProfileDeps[p] = true
+	}
 	extracted := map[string]bool{} // TuneD profile names present in TuneD CR and successfully extracted to /etc/tuned/<profile>/
 
 	for index, profile := range profiles {
@@ -643,6 +693,7 @@ func (c *Controller) tunedStop() (bool, error) {
 
 func (c *Controller) tunedReload(timeoutInitiated bool) error {
 	c.daemon.reloading = true
+	c.daemon.reloadRevision = c.daemon.renderedRevision
 	c.daemon.status = 0 // clear the set out of which Profile status conditions are created
 	c.daemon.stderr = ""
 
@@ -696,6 +747,95 @@ func (c *Controller) tunedRestart(timeoutInitiated bool) (err error) {
 	return nil
 }
 
+// tunedRollback stops the TuneD daemon, which rolls back the node-level tuning
+// of the active profile, and removes the TuneD profiles and the recommend file
+// written by the operand so that no stale TuneD configuration remains on the node.
+func (c *Controller) tunedRollback() error {
+	if c.tunedCmd != nil {
+		rolledBack, err := c.tunedStop()
+		if err != nil {
+			return err
+		}
+		if !rolledBack {
+			klog.Warningf("TuneD daemon did not terminate gracefully, node-level tuning might not have been rolled back")
+		}
+		c.tunedCmd = nil                 // Cmd.Start() cannot be used more than once
+		c.tunedExit = make(chan bool, 1) // Once tunedStop() terminates, the tunedExit channel is closed!
+	}
+	c.tunedTicker.Stop()
+	c.tunedTimeout = tunedInitialTimeout
+	c.daemon.reloading = false
+	c.daemon.status = scRolledBack
+	c.daemon.stderr = ""
+
+	err := os.Remove(tunedRecommendFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %q: %v", tunedRecommendFile, err)
+	}
+	tuned, err := c.listers.TunedResources.Get(tunedv1.TunedRenderedResourceName)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get Tuned %s: %v", tunedv1.TunedRenderedResourceName, err)
+	}
+	if tuned != nil {
+		if err = profilesRemove(tunedProfilesDirCustom, tuned.Spec.Profile); err != nil {
+			return err
+		}
+	}
+	klog.Infof("node-level tuning rolled back")
+
+	return nil
+}
+
+// profilesRemove removes the directories of TuneD profiles 'profiles' from the
+// TuneD profiles directory 'profilesDir'.  Profile names which do not name
+// a directory directly under 'profilesDir' are skipped.
+func profilesRemove(profilesDir string, profiles []tunedv1.TunedProfile) error {
+	for _, profile := range profiles {
+		if profile.Name == nil || len(*profile.Name) == 0 {
+			continue
+		}
+		if strings.Contains(*profile.Name, "/") || *profile.Name == "." || *profile.Name == ".." {
+			klog.Warningf("not removing TuneD profile %q outside of %q", *profile.Name, profilesDir)
+			continue
+		}
+		profileDir := fmt.Sprintf("%s/%s", profilesDir, *profile.Name)
+		if err := os.RemoveAll(profileDir); err != nil {
+			return fmt.Errorf("failed to remove %q: %v", profileDir, err)
+		}
+		klog.Infof("removed TuneD profile %q", profileDir)
+	}
+
+	return nil
+}
+
+// tunedRollbackCancel restores the TuneD profiles and the recommend file removed
+// by tunedRollback and starts the TuneD daemon again.
+func (c *Controller) tunedRollbackCancel() error {
+	profile, err := c.listers.TunedProfiles.Get(getNodeName())
+	if err != nil {
+		return fmt.Errorf("failed to get Profile %s: %v", getNodeName(), err)
+	}
+	c.daemon.recommendedProfile = profile.Spec.Config.TunedProfile
+	tuned, err := c.listers.TunedResources.Get(tunedv1.TunedRenderedResourceName)
+	if err != nil {
+		return fmt.Errorf("failed to get Tuned %s: %v", tunedv1.TunedRenderedResourceName, err)
+	}
+	if _, err = profilesSync(tuned.Spec.Profile, c.daemon.recommendedProfile); err != nil {
+		return err
+	}
+	c.daemon.renderedRevision = tuned.Annotations[tunedv1.RenderedRevisionAnnotationKey]
+	if err = tunedRecommendFileWrite(c.daemon.recommendedProfile); err != nil {
+		return err
+	}
+	c.change.profile = false
+	c.change.rendered = false
+	c.change.daemon = false
+	c.daemon.status = scUnknown
+	klog.Infof("node-level tuning rollback cancelled")
+
+	return c.tunedReload(false)
+}
+
 // getActiveProfile returns active profile currently in use by the TuneD daemon.
 // On error, an empty string is returned.
 func getActiveProfile() (string, error) {
@@ -752,6 +892,36 @@ func (c *Controller) changeSyncer() (synced bool, err error) {
 		return false, fmt.Errorf("changeSyncer(): called while the TuneD daemon was reloading")
 	}
 
+	if c.change.rollback {
+		// The operator requested or cancelled a rollback of the node-level tuning.
+		if c.daemon.rollback {
+			err = c.tunedRollback()
+		} else {
+			err = c.tunedRollbackCancel()
+		}
+		if err != nil {
+			return false, err
+		}
+		c.change.rollback = false
+		c.daemon.reloaded = c.daemon.rollback // report the rollback in the node Profile k8s object
+	}
+	if c.daemon.rollback {
+		// Do not act on any changes while the node-level tuning is rolled back apart from
+		// reporting the rollback.
+		if c.daemon.reloaded {
+			if err = c.updateTunedProfile(); err != nil {
+				klog.Error(err.Error())
+				return false, nil // retry later
+			}
+			c.daemon.reloaded = false
+		}
+		c.change.profile = false
+		c.change.rendered = false
+		c.change.daemon = false
+		c.change.bootcmdline = false
+		return true, nil
+	}
+
 	if c.change.bootcmdline || c.daemon.reloaded {
 		// One or both of the following happened:
 		// 1) tunedBootcmdlineFile changed on the filesystem.  This is very likely the result of
@@ -817,6 +987,16 @@ func (c *Controller) changeSyncer() (synced bool, err error) {
 		return err == nil, err
 	}
 
+	if revision := computeReloadRevision(reload, c.daemon.reloadRevision, c.daemon.renderedRevision); revision != c.daemon.reloadRevision {
+		// The rendered Tuned k8s object changed without changing the TuneD profiles of this node.
+		// Report the new revision as applied once the running profile is.
+		c.daemon.reloadRevision = revision
+		if err = c.updateTunedProfile(); err != nil {
+			klog.Error(err.Error())
+			return false, nil // retry later
+		}
+	}
+
 	if reload {
 		err = c.tunedReload(false)
 	}
@@ -921,11 +1101,26 @@ func (c *Controller) updateTunedProfile() (err error) {
 	if err != nil {
 		return err
 	}
-
 	statusConditions := computeStatusConditions(c.daemon.status, c.daemon.stderr, profile.Status.Conditions)
+	appliedRevision := computeAppliedRevision(c.daemon.status, c.daemon.reloading, c.daemon.reloadRevision,
+		profile.ObjectMeta.Annotations[tunedv1.AppliedRevisionAnnotationKey])
+	if c.daemon.rollback {
+		// TuneD daemon is stopped and the node-level tuning rolled back.
+		bootcmdline = ""
+		activeProfile = ""
+		appliedRevision = ""
+		statusConditions = removeStatusCondition(statusConditions, tunedv1.TunedRequirementsMet)
+	} else {
+		// Boot-time requirements are reported along with the kernel parameters calculated by TuneD.
+		bootcmdline = bootcmdlineMerge(bootcmdline, c.daemon.requirements.BootParameters)
+		statusConditions = computeRequirementsStatusCondition(statusConditions, c.daemon.requirements, c.daemon.requirementsUnmet)
+	}
 
 	if profile.Status.Bootcmdline == bootcmdline &&
-		profile.Status.TunedProfile == activeProfile && conditionsEqual(profile.Status.Conditions, statusConditions) {
+		profile.Status.TunedProfile == activeProfile && conditionsEqual(profile.Status.Conditions, statusConditions) &&
+		profile.ObjectMeta.Annotations[tunedv1.AppliedRevisionAnnotationKey] == appliedRevision &&
+		profile.ObjectMeta.Annotations[tunedv1.GeneratedByOperandVersionAnnotationKey] == os.Getenv("RELEASE_VERSION") &&
+		reflect.DeepEqual(profile.Status.Capabilities, c.daemon.capabilities) {
 		// Do not update node Profile unnecessarily (e.g. bootcmdline did not change).
 		// This will save operator CPU cycles trying to reconcile objects that do not
 		// need reconciling.
@@ -938,10 +1133,12 @@ func (c *Controller) updateTunedProfile() (err error) {
 	profile.Status.Bootcmdline = bootcmdline
 	profile.Status.TunedProfile = activeProfile
 	profile.Status.Conditions = statusConditions
+	profile.Status.Capabilities = c.daemon.capabilities
 	if profile.ObjectMeta.Annotations == nil {
 		profile.ObjectMeta.Annotations = map[string]string{}
 	}
 	profile.ObjectMeta.Annotations[tunedv1.GeneratedByOperandVersionAnnotationKey] = os.Getenv("RELEASE_VERSION")
+	profile.ObjectMeta.Annotations[tunedv1.AppliedRevisionAnnotationKey] = appliedRevision
 	_, err = c.clients.Tuned.TunedV1().Profiles(operandNamespace).Update(context.TODO(), profile, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update Profile %s status: %v", profile.Name, err)
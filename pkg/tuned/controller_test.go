@@ -0,0 +1,59 @@
+package tuned
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+func TestProfilesRemove(t *testing.T) {
+	root := t.TempDir()
+	profilesDir := filepath.Join(root, "etc", "tuned")
+	for _, dir := range []string{
+		filepath.Join(profilesDir, "openshift-node"),
+		filepath.Join(profilesDir, "user-profile"),
+		filepath.Join(profilesDir, "not-rendered"),
+		filepath.Join(root, "etc", "outside"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tunedConfFile), []byte("[main]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name := func(n string) *string { return &n }
+	profiles := []tunedv1.TunedProfile{
+		{Name: name("openshift-node")},
+		{Name: name("user-profile")},
+		{Name: name("missing")},
+		{Name: name("")},
+		{Name: nil},
+		{Name: name("../outside")},
+		{Name: name("..")},
+		{Name: name(".")},
+	}
+	if err := profilesRemove(profilesDir, profiles); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		dir    string
+		exists bool
+	}{
+		{dir: filepath.Join(profilesDir, "openshift-node")},
+		{dir: filepath.Join(profilesDir, "user-profile")},
+		{dir: filepath.Join(profilesDir, "not-rendered"), exists: true},
+		{dir: filepath.Join(root, "etc", "outside"), exists: true},
+		{dir: profilesDir, exists: true},
+	}
+	for _, tc := range tests {
+		_, err := os.Stat(tc.dir)
+		if exists := err == nil; exists != tc.exists {
+			t.Errorf("%s: want exists %t, have %t", tc.dir, tc.exists, exists)
+		}
+	}
+}
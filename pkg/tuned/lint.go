@@ -0,0 +1,382 @@
+package tuned
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+// LintSeverity is the severity of a LintFinding.
+type LintSeverity string
+
+const (
+	// LintSeverityError is a problem preventing the tuning from being applied as intended.
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning is a likely mistake.
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityInfo is a note about something the linter could not verify.
+	LintSeverityInfo LintSeverity = "info"
+)
+
+// Codes of the LintFindings.
+const (
+	LintCodeINISyntax           = "INISyntax"
+	LintCodeInvalidProfileName  = "InvalidProfileName"
+	LintCodeDuplicateProfile    = "DuplicateProfile"
+	LintCodeUnknownPlugin       = "UnknownPlugin"
+	LintCodeMissingInclude      = "MissingInclude"
+	LintCodeUnresolvedInclude   = "UnresolvedInclude"
+	LintCodeIncludeCycle        = "IncludeCycle"
+	LintCodeUnknownProfile      = "UnknownProfile"
+	LintCodeMissingPriority     = "MissingPriority"
+	LintCodeDuplicatePriority   = "DuplicatePriority"
+	LintCodeConflictingPriority = "ConflictingPriority"
+	LintCodeShadowedRule        = "ShadowedRule"
+)
+
+// LintFinding is a problem found in Tuned CRs.
+type LintFinding struct {
+	Severity LintSeverity `json:"severity"`
+	Code     string       `json:"code"`
+	// namespace/name of the Tuned CR
+	Tuned string `json:"tuned,omitempty"`
+	// TuneD profile the finding is about
+	Profile string `json:"profile,omitempty"`
+	Message string `json:"message"`
+}
+
+type linter struct {
+	// directory the TuneD profiles of the Tuned CRs are extracted to
+	profilesDirCustom string
+	// directory with the TuneD profiles shipped with the operand
+	profilesDirSystem string
+	// TuneD plug-ins shipped with the operand; nil if unknown
+	plugins  map[string]bool
+	findings []LintFinding
+}
+
+// recommendRule is a recommend rule of a Tuned CR.
+type recommendRule struct {
+	tuned     string
+	recommend tunedv1.TunedRecommend
+}
+
+func (l *linter) add(severity LintSeverity, code, tuned, profile, format string, a ...interface{}) {
+	l.findings = append(l.findings, LintFinding{
+		Severity: severity,
+		Code:     code,
+		Tuned:    tuned,
+		Profile:  profile,
+		Message:  strings.TrimSpace(fmt.Sprintf(format, a...)),
+	})
+}
+
+// LintTuneds checks Tuned CRs 'tuneds' against the TuneD profiles in directory
+// 'profilesDir' and the TuneD plug-ins with sources in directory 'pluginsDir'
+// shipped with the operand.  Plug-ins are not checked if 'pluginsDir' is empty.
+// Returns the findings ordered by Tuned CR name.
+func LintTuneds(tuneds []*tunedv1.Tuned, profilesDir string, pluginsDir string) ([]LintFinding, error) {
+	if _, err := os.Stat(profilesDir); err != nil {
+		return nil, fmt.Errorf("failed to read TuneD profiles: %v", err)
+	}
+
+	customDir, err := ioutil.TempDir("", "tuned-lint-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(customDir)
+
+	l := &linter{
+		profilesDirCustom: customDir,
+		profilesDirSystem: profilesDir,
+	}
+
+	if len(pluginsDir) > 0 {
+		plugins := tunedPlugins(filepath.Join(pluginsDir, tunedPluginFileGlob))
+		if len(plugins) == 0 {
+			return nil, fmt.Errorf("no TuneD plug-ins found in %s", pluginsDir)
+		}
+		l.plugins = map[string]bool{}
+		for _, plugin := range plugins {
+			l.plugins[plugin] = true
+		}
+	}
+
+	tuneds = append([]*tunedv1.Tuned{}, tuneds...)
+	sort.Slice(tuneds, func(i, j int) bool {
+		return tunedName(tuneds[i]) < tunedName(tuneds[j])
+	})
+
+	profiles, err := l.profilesExtract(tuneds)
+	if err != nil {
+		return nil, err
+	}
+	for _, tuned := range tuneds {
+		for _, profile := range tuned.Spec.Profile {
+			if profile.Name == nil || profiles[*profile.Name] != tunedName(tuned) {
+				continue
+			}
+			l.lintProfile(tunedName(tuned), *profile.Name)
+		}
+	}
+
+	l.lintRecommend(tuneds, profiles)
+
+	return l.findings, nil
+}
+
+// tunedName returns the namespace/name of Tuned CR 'tuned'.
+func tunedName(tuned *tunedv1.Tuned) string {
+	if len(tuned.Namespace) == 0 {
+		return tuned.Name
+	}
+	return tuned.Namespace + "/" + tuned.Name
+}
+
+// profilesExtract writes the TuneD profiles of Tuned CRs 'tuneds' to the
+// custom profiles directory the same way the operand does.  Returns a map of
+// the extracted profile names to the Tuned CRs defining them.
+func (l *linter) profilesExtract(tuneds []*tunedv1.Tuned) (map[string]string, error) {
+	profiles := map[string]string{}
+	data := map[string]string{}
+
+	for _, tuned := range tuneds {
+		for _, profile := range tuned.Spec.Profile {
+			if profile.Name == nil {
+				l.add(LintSeverityError, LintCodeInvalidProfileName, tunedName(tuned), "", "profile has no name")
+				continue
+			}
+			name := *profile.Name
+			if len(name) == 0 || strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+				l.add(LintSeverityError, LintCodeInvalidProfileName, tunedName(tuned), name, "invalid TuneD profile name %q", name)
+				continue
+			}
+			var content string
+			if profile.Data != nil {
+				content = *profile.Data
+			}
+			if owner, ok := profiles[name]; ok {
+				if data[name] != content {
+					l.add(LintSeverityWarning, LintCodeDuplicateProfile, tunedName(tuned), name,
+						"profile %s is also defined with different content by Tuned %s", name, owner)
+				}
+				continue
+			}
+			profiles[name] = tunedName(tuned)
+			data[name] = content
+
+			profileDir := filepath.Join(l.profilesDirCustom, name)
+			if err := os.MkdirAll(profileDir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create TuneD profile directory %q: %v", profileDir, err)
+			}
+			profileFile := filepath.Join(profileDir, tunedConfFile)
+			if err := ioutil.WriteFile(profileFile, []byte(content), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write TuneD profile file %q: %v", profileFile, err)
+			}
+		}
+	}
+
+	return profiles, nil
+}
+
+// lintProfile checks extracted TuneD profile 'profileName' of Tuned CR 'tuned'.
+func (l *linter) lintProfile(tuned string, profileName string) {
+	cfg, err := iniFileLoad(filepath.Join(l.profilesDirCustom, profileName, tunedConfFile))
+	if err != nil {
+		l.add(LintSeverityError, LintCodeINISyntax, tuned, profileName, "%v", err)
+		return
+	}
+
+	l.lintPlugins(tuned, profileName, cfg)
+	l.lintIncludes(tuned, profileName)
+
+	deps := profileDependsLoop(profileName, map[string]bool{}, l.profileIncludes)
+	if deps[profileName] {
+		var cycle []string
+		for dep := range deps {
+			if dep != profileName && l.profileExists(dep) && profileDependsLoop(dep, map[string]bool{}, l.profileIncludes)[profileName] {
+				cycle = append(cycle, dep)
+			}
+		}
+		sort.Strings(cycle)
+		if len(cycle) == 0 {
+			l.add(LintSeverityError, LintCodeIncludeCycle, tuned, profileName, "profile %s includes itself", profileName)
+		} else {
+			l.add(LintSeverityError, LintCodeIncludeCycle, tuned, profileName, "profile %s includes itself through %s",
+				profileName, strings.Join(cycle, ", "))
+		}
+	}
+}
+
+// lintPlugins checks the sections of TuneD profile 'profileName' with INI data
+// 'cfg' configure TuneD plug-ins shipped with the operand.
+func (l *linter) lintPlugins(tuned string, profileName string, cfg *ini.File) {
+	if l.plugins == nil {
+		return
+	}
+
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if name == ini.DefaultSection || name == "main" || name == "variables" {
+			continue
+		}
+		plugin := name
+		if section.HasKey("type") {
+			plugin = section.Key("type").String()
+		}
+		if !l.plugins[plugin] {
+			l.add(LintSeverityWarning, LintCodeUnknownPlugin, tuned, profileName,
+				"section [%s] configures unknown TuneD plug-in %q", name, plugin)
+		}
+	}
+}
+
+// lintIncludes checks the profiles TuneD profile 'profileName' includes exist.
+func (l *linter) lintIncludes(tuned string, profileName string) {
+	for _, include := range profileIncludesRaw(profileName, l.profilesDirCustom) {
+		include = strings.TrimSpace(include)
+		if len(include) == 0 {
+			continue
+		}
+		optional := strings.HasPrefix(include, "-")
+		name := strings.TrimPrefix(include, "-")
+		if strings.Contains(name, "${") {
+			l.add(LintSeverityInfo, LintCodeUnresolvedInclude, tuned, profileName,
+				"include %q uses TuneD variables or built-in functions and cannot be resolved offline", include)
+			continue
+		}
+		exists := l.profileExists(name)
+		if name == profileName {
+			// A profile including a profile of the same name includes the system profile.
+			exists = profileExists(name, l.profilesDirSystem)
+		}
+		if !exists && !optional {
+			l.add(LintSeverityError, LintCodeMissingInclude, tuned, profileName,
+				"included profile %s does not exist", name)
+		}
+	}
+}
+
+// profileExists returns true if TuneD profile 'profileName' is either defined
+// by the Tuned CRs or shipped with the operand.
+func (l *linter) profileExists(profileName string) bool {
+	return profileExists(profileName, l.profilesDirCustom) || profileExists(profileName, l.profilesDirSystem)
+}
+
+// profileIncludes is the offline counterpart of profileIncludes.  Includes
+// using TuneD variables or built-in functions are skipped rather than expanded
+// as the expansion depends on the node.
+func (l *linter) profileIncludes(profileName string) []string {
+	var includes []string
+
+	custom := profileExists(profileName, l.profilesDirCustom)
+	dir := l.profilesDirSystem
+	if custom {
+		dir = l.profilesDirCustom
+	}
+
+	for _, include := range profileIncludesRaw(profileName, dir) {
+		name := strings.TrimPrefix(strings.TrimSpace(include), "-")
+		if len(name) == 0 || strings.Contains(name, "${") {
+			continue
+		}
+		if name == profileName && custom {
+			// Custom profile 'profileName' includes the system profile of the same name.
+			for _, include := range profileIncludesRaw(profileName, l.profilesDirSystem) {
+				name := strings.TrimPrefix(strings.TrimSpace(include), "-")
+				if len(name) == 0 || strings.Contains(name, "${") {
+					continue
+				}
+				includes = append(includes, name)
+			}
+			continue
+		}
+		includes = append(includes, name)
+	}
+
+	return includes
+}
+
+// lintRecommend checks the recommend rules of Tuned CRs 'tuneds' with TuneD
+// profiles 'profiles' defined by the Tuned CRs.  The rules are ordered the
+// same way the operator orders them for profile selection.
+func (l *linter) lintRecommend(tuneds []*tunedv1.Tuned, profiles map[string]string) {
+	var rules []recommendRule
+
+	for _, tuned := range tuneds {
+		for _, recommend := range tuned.Spec.Recommend {
+			rules = append(rules, recommendRule{tuned: tunedName(tuned), recommend: recommend})
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].recommend.Priority != nil && rules[j].recommend.Priority != nil {
+			return *rules[i].recommend.Priority < *rules[j].recommend.Priority
+		}
+		return rules[i].recommend.Priority != nil // undefined priority has the lowest priority
+	})
+
+	for i, rule := range rules {
+		profile := recommendProfile(rule.recommend)
+		if rule.recommend.Profile == nil {
+			l.add(LintSeverityError, LintCodeUnknownProfile, rule.tuned, "", "recommend rule has no profile")
+		} else if _, ok := profiles[profile]; !ok && !profileExists(profile, l.profilesDirSystem) {
+			l.add(LintSeverityError, LintCodeUnknownProfile, rule.tuned, profile,
+				"recommended profile %s is neither defined by the Tuned CRs nor shipped with the operand", profile)
+		}
+
+		if rule.recommend.Priority == nil {
+			l.add(LintSeverityWarning, LintCodeMissingPriority, rule.tuned, profile,
+				"recommend rule for profile %s has no priority and is considered last", profile)
+		} else if i > 0 && rules[i-1].recommend.Priority != nil && *rules[i-1].recommend.Priority == *rule.recommend.Priority {
+			prev := rules[i-1]
+			if recommendProfile(prev.recommend) == profile {
+				l.add(LintSeverityInfo, LintCodeDuplicatePriority, rule.tuned, profile,
+					"recommend rule for profile %s has the same priority %d as another rule for the same profile in Tuned %s",
+					profile, *rule.recommend.Priority, prev.tuned)
+			} else {
+				l.add(LintSeverityWarning, LintCodeConflictingPriority, rule.tuned, profile,
+					"recommend rule for profile %s has the same priority %d as the rule for profile %s in Tuned %s",
+					profile, *rule.recommend.Priority, recommendProfile(prev.recommend), prev.tuned)
+			}
+		}
+
+		for _, prev := range rules[:i] {
+			if recommendShadows(prev.recommend, rule.recommend) {
+				l.add(LintSeverityWarning, LintCodeShadowedRule, rule.tuned, profile,
+					"recommend rule for profile %s can never match, the rule for profile %s in Tuned %s always matches first",
+					profile, recommendProfile(prev.recommend), prev.tuned)
+				break
+			}
+		}
+	}
+}
+
+// recommendProfile returns the profile recommended by rule 'recommend'.
+func recommendProfile(recommend tunedv1.TunedRecommend) string {
+	if recommend.Profile == nil {
+		return ""
+	}
+	return *recommend.Profile
+}
+
+// recommendShadows returns true if recommend rule 'a' considered before rule
+// 'b' matches whenever 'b' matches, so that 'b' can never match.  This is the
+// case if 'a' is a catch-all rule or if 'a' has the same match conditions as 'b'.
+// Rules with a schedule never shadow other rules as they do not apply at all times.
+func recommendShadows(a, b tunedv1.TunedRecommend) bool {
+	if len(a.Schedule) > 0 {
+		return false
+	}
+	if len(a.Match) == 0 && a.MachineConfigLabels == nil {
+		return true
+	}
+	return reflect.DeepEqual(a.Match, b.Match) && reflect.DeepEqual(a.MachineConfigLabels, b.MachineConfigLabels)
+}
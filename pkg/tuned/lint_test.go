@@ -0,0 +1,137 @@
+package tuned
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+const (
+	testProfilesDir = "../../assets/tuned/daemon/profiles"
+	testPluginsDir  = "../../assets/tuned/daemon/tuned/plugins"
+)
+
+func newTestTuned(name string, profiles map[string]string, recommend ...tunedv1.TunedRecommend) *tunedv1.Tuned {
+	tuned := &tunedv1.Tuned{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       tunedv1.TunedSpec{Recommend: recommend},
+	}
+	for profileName, data := range profiles {
+		profileName, data := profileName, data
+		tuned.Spec.Profile = append(tuned.Spec.Profile, tunedv1.TunedProfile{Name: &profileName, Data: &data})
+	}
+	return tuned
+}
+
+func newTestRecommend(profile string, priority uint64, labels ...string) tunedv1.TunedRecommend {
+	recommend := tunedv1.TunedRecommend{Profile: &profile, Priority: &priority}
+	for _, label := range labels {
+		label := label
+		recommend.Match = append(recommend.Match, tunedv1.TunedMatch{Label: &label})
+	}
+	return recommend
+}
+
+func TestLintTuneds(t *testing.T) {
+	var tests = []struct {
+		name     string
+		tuneds   []*tunedv1.Tuned
+		expected []string // finding codes
+	}{
+		{
+			name: "valid",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"a": "[main]\ninclude=openshift-node\n[sysctl]\nvm.swappiness=10\n"},
+					newTestRecommend("a", 20, "node-role.kubernetes.io/a"),
+					newTestRecommend("openshift-node", 30)),
+			},
+		},
+		{
+			name: "INI syntax",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"a": "[main\n"}, newTestRecommend("a", 20)),
+			},
+			expected: []string{LintCodeINISyntax},
+		},
+		{
+			name: "unknown plug-in",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"a": "[main]\n[foo]\nx=1\n[bar]\ntype=sysctl\n"}, newTestRecommend("a", 20)),
+			},
+			expected: []string{LintCodeUnknownPlugin},
+		},
+		{
+			name: "missing include",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"a": "[main]\ninclude=missing,-optional\n"}, newTestRecommend("a", 20)),
+			},
+			expected: []string{LintCodeMissingInclude},
+		},
+		{
+			name: "include cycle",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"a": "[main]\ninclude=b\n"}, newTestRecommend("a", 20)),
+				newTestTuned("b", map[string]string{"b": "[main]\ninclude=a\n"}),
+			},
+			expected: []string{LintCodeIncludeCycle, LintCodeIncludeCycle},
+		},
+		{
+			name: "self include of system profile",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", map[string]string{"balanced": "[main]\ninclude=balanced\n"}, newTestRecommend("balanced", 20)),
+			},
+		},
+		{
+			name: "priorities",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", nil,
+					newTestRecommend("balanced", 20, "a"),
+					newTestRecommend("balanced", 20, "b"),
+					newTestRecommend("powersave", 20, "c")),
+			},
+			expected: []string{LintCodeDuplicatePriority, LintCodeConflictingPriority},
+		},
+		{
+			name: "shadowed rules",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", nil,
+					newTestRecommend("balanced", 10, "a"),
+					newTestRecommend("powersave", 20, "a"),
+					newTestRecommend("openshift-node", 30),
+					newTestRecommend("balanced", 40, "b")),
+			},
+			expected: []string{LintCodeShadowedRule, LintCodeShadowedRule},
+		},
+		{
+			name: "unknown profile",
+			tuneds: []*tunedv1.Tuned{
+				newTestTuned("a", nil, newTestRecommend("missing", 20)),
+			},
+			expected: []string{LintCodeUnknownProfile},
+		},
+	}
+
+	for _, tc := range tests {
+		findings, err := LintTuneds(tc.tuneds, testProfilesDir, testPluginsDir)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		var codes []string
+		for _, f := range findings {
+			codes = append(codes, f.Code)
+		}
+		if len(codes) != len(tc.expected) {
+			t.Errorf("%s: unexpected findings\n\twant: %v\n\thave: %v", tc.name, tc.expected, findings)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != tc.expected[i] {
+				t.Errorf("%s: unexpected findings\n\twant: %v\n\thave: %v", tc.name, tc.expected, findings)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package tuned
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	tunedv1 "github.com/openshift/cluster-node-tuning-operator/pkg/apis/tuned/v1"
+)
+
+const (
+	procCmdline = "/proc/cmdline"
+	sysModule   = "/sys/module"
+	// hostRoot is the mount point of the host root filesystem in the operand
+	// container.  The host systemd is not reachable from the container.
+	hostRoot = "/host"
+)
+
+// systemdUnitDirs are the directories systemd loads system unit files from.
+var systemdUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// profileRequirements returns the requirements of the TuneD profiles in the
+// space-separated list 'recommendedProfile' and the TuneD profiles they depend
+// on as defined in 'profiles'.  The dependencies are resolved from the TuneD
+// profiles already extracted to the daemon configuration directory.
+func profileRequirements(profiles []tunedv1.TunedProfile, recommendedProfile string) tunedv1.TunedProfileRequirements {
+	var req tunedv1.TunedProfileRequirements
+
+	deps := map[string]bool{}
+	for _, p := range strings.Fields(recommendedProfile) {
+		for dep := range profileDepends(p) {
+			deps[dep] = true
+		}
+		deps[p] = true
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == nil || !deps[*profile.Name] || profile.Requirements == nil {
+			continue
+		}
+		req.Modules = appendUnique(req.Modules, profile.Requirements.Modules...)
+		req.Services = appendUnique(req.Services, profile.Requirements.Services...)
+		req.BootParameters = appendUnique(req.BootParameters, profile.Requirements.BootParameters...)
+	}
+
+	return req
+}
+
+// appendUnique appends the items of 'items' not yet present in slice 's'.
+func appendUnique(s []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, v := range s {
+			if v == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, item)
+		}
+	}
+	return s
+}
+
+// requirementsEmpty returns true if there are no requirements in 'req'.
+func requirementsEmpty(req tunedv1.TunedProfileRequirements) bool {
+	return len(req.Modules) == 0 && len(req.Services) == 0 && len(req.BootParameters) == 0
+}
+
+// moduleAvailable returns true if kernel module 'module' is loaded, built into
+// the kernel or can be loaded.
+func moduleAvailable(module string) bool {
+	if _, err := os.Stat(sysModule + "/" + strings.ReplaceAll(module, "-", "_")); err == nil {
+		return true
+	}
+	_, err := execCmd([]string{"/usr/sbin/modinfo", "-n", module})
+	return err == nil
+}
+
+// serviceInstalled returns true if systemd service 'service' is installed on
+// the host.
+func serviceInstalled(service string) bool {
+	return serviceUnitPresent(hostRoot, service)
+}
+
+// serviceUnitFile returns the name of the unit file of systemd service 'service'.
+// The ".service" suffix is optional and instances of template units resolve to
+// the template unit file.
+func serviceUnitFile(service string) string {
+	if !strings.HasSuffix(service, ".service") {
+		service += ".service"
+	}
+	if i := strings.Index(service, "@"); i >= 0 {
+		service = service[:i+1] + ".service"
+	}
+	return service
+}
+
+// serviceUnitPresent returns true if the unit file of systemd service 'service'
+// exists in any of the systemd unit directories under 'root' and is not masked.
+func serviceUnitPresent(root string, service string) bool {
+	unit := serviceUnitFile(service)
+	for _, dir := range systemdUnitDirs {
+		path := filepath.Join(root, dir, unit)
+		fi, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(path); err == nil && target == "/dev/null" {
+				// Masked.
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// bootParameterPresent returns true if kernel boot parameter 'param' is present
+// in the kernel command-line 'cmdline'.  A parameter without a value matches the
+// parameter with any value.
+func bootParameterPresent(cmdline string, param string) bool {
+	for _, p := range strings.Fields(cmdline) {
+		if p == param {
+			return true
+		}
+		if !strings.Contains(param, "=") && strings.SplitN(p, "=", 2)[0] == param {
+			return true
+		}
+	}
+	return false
+}
+
+// bootcmdlineMerge returns kernel parameters 'bootcmdline' calculated by TuneD
+// with the required boot parameters 'params' not yet present appended.
+func bootcmdlineMerge(bootcmdline string, params []string) string {
+	for _, param := range params {
+		if bootParameterPresent(bootcmdline, param) {
+			continue
+		}
+		if len(bootcmdline) > 0 {
+			bootcmdline += " "
+		}
+		bootcmdline += param
+	}
+	return bootcmdline
+}
+
+// requirementsUnmet checks requirements 'req' against the Node.  Returns a
+// message describing the unmet requirements or an empty string if all of
+// them are met.
+func requirementsUnmet(req tunedv1.TunedProfileRequirements) string {
+	var unmet []string
+
+	var missing []string
+	for _, module := range req.Modules {
+		if !moduleAvailable(module) {
+			missing = append(missing, module)
+		}
+	}
+	if len(missing) > 0 {
+		unmet = append(unmet, fmt.Sprintf("kernel modules not available: %s", strings.Join(missing, ", ")))
+	}
+
+	missing = nil
+	for _, service := range req.Services {
+		if !serviceInstalled(service) {
+			missing = append(missing, service)
+		}
+	}
+	if len(missing) > 0 {
+		unmet = append(unmet, fmt.Sprintf("services not installed: %s", strings.Join(missing, ", ")))
+	}
+
+	if len(req.BootParameters) > 0 {
+		content, err := ioutil.ReadFile(procCmdline)
+		if err != nil {
+			klog.Errorf("failed to read %s: %v", procCmdline, err)
+		}
+		missing = nil
+		for _, param := range req.BootParameters {
+			if !bootParameterPresent(string(content), param) {
+				missing = append(missing, param)
+			}
+		}
+		if len(missing) > 0 {
+			unmet = append(unmet, fmt.Sprintf("Node not booted with kernel parameters (needs a MachineConfig and reboot): %s", strings.Join(missing, " ")))
+		}
+	}
+
+	return strings.Join(unmet, "; ")
+}
+
+// requirementsUpdate recalculates the requirements of the recommended TuneD
+// profile from the rendered Tuned and checks them against the Node.
+func (c *Controller) requirementsUpdate(profiles []tunedv1.TunedProfile) {
+	c.daemon.requirements = profileRequirements(profiles, c.daemon.recommendedProfile)
+	c.daemon.requirementsUnmet = requirementsUnmet(c.daemon.requirements)
+	if len(c.daemon.requirementsUnmet) > 0 {
+		klog.Warningf("TuneD profile %s requirements unmet: %s", c.daemon.recommendedProfile, c.daemon.requirementsUnmet)
+	}
+}
+
+// computeRequirementsStatusCondition returns an updated slice of
+// tunedv1.ProfileStatusCondition 'conditions' with the RequirementsMet condition
+// set based on the unmet requirements 'unmet'.  The condition is removed if the
+// TuneD profile has no requirements.
+func computeRequirementsStatusCondition(conditions []tunedv1.ProfileStatusCondition, req tunedv1.TunedProfileRequirements, unmet string) []tunedv1.ProfileStatusCondition {
+	if requirementsEmpty(req) {
+		return removeStatusCondition(conditions, tunedv1.TunedRequirementsMet)
+	}
+
+	tunedRequirementsMetCondition := tunedv1.ProfileStatusCondition{
+		Type: tunedv1.TunedRequirementsMet,
+	}
+	if len(unmet) == 0 {
+		tunedRequirementsMetCondition.Status = corev1.ConditionTrue
+		tunedRequirementsMetCondition.Reason = "AsExpected"
+		tunedRequirementsMetCondition.Message = "The Node meets all requirements of the TuneD profile."
+	} else {
+		tunedRequirementsMetCondition.Status = corev1.ConditionFalse
+		tunedRequirementsMetCondition.Reason = "RequirementsUnmet"
+		tunedRequirementsMetCondition.Message = "The Node does not meet the requirements of the TuneD profile: " + unmet
+	}
+
+	return setStatusCondition(conditions, &tunedRequirementsMetCondition)
+}
@@ -0,0 +1,95 @@
+package tuned
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBootcmdlineMerge(t *testing.T) {
+	var tests = []struct {
+		bootcmdline string
+		params      []string
+		expected    string
+	}{
+		{
+			bootcmdline: "",
+			params:      []string{"intel_iommu=on"},
+			expected:    "intel_iommu=on",
+		},
+		{
+			bootcmdline: "skew_tick=1",
+			params:      []string{"intel_iommu=on", "iommu=pt"},
+			expected:    "skew_tick=1 intel_iommu=on iommu=pt",
+		},
+		{
+			bootcmdline: "skew_tick=1 intel_iommu=on",
+			params:      []string{"intel_iommu=on"},
+			expected:    "skew_tick=1 intel_iommu=on",
+		},
+		{
+			bootcmdline: "nohz_full=1-3",
+			params:      []string{"nohz_full"},
+			expected:    "nohz_full=1-3",
+		},
+		{
+			bootcmdline: "nohz_full=1-3",
+			params:      []string{"nohz_full=2-3"},
+			expected:    "nohz_full=1-3 nohz_full=2-3",
+		},
+	}
+
+	for i, tc := range tests {
+		bootcmdline := bootcmdlineMerge(tc.bootcmdline, tc.params)
+		if bootcmdline != tc.expected {
+			t.Errorf(
+				"failed test case %d:\n\twant: %s\n\thave: %s",
+				i+1,
+				tc.expected,
+				bootcmdline,
+			)
+		}
+	}
+}
+
+func TestServiceUnitPresent(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"/etc/systemd/system", "/usr/lib/systemd/system"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, unit := range []string{"/usr/lib/systemd/system/stalld.service", "/usr/lib/systemd/system/getty@.service", "/usr/lib/systemd/system/irqbalance.service"} {
+		if err := os.WriteFile(filepath.Join(root, unit), []byte("[Service]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/dev/null", filepath.Join(root, "/etc/systemd/system/irqbalance.service")); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		service  string
+		expected bool
+	}{
+		{service: "stalld", expected: true},
+		{service: "stalld.service", expected: true},
+		{service: "getty@tty1.service", expected: true},
+		{service: "getty@tty1", expected: true},
+		{service: "chronyd", expected: false},
+		{service: "irqbalance", expected: false}, // masked
+	}
+
+	for i, tc := range tests {
+		present := serviceUnitPresent(root, tc.service)
+		if present != tc.expected {
+			t.Errorf(
+				"failed test case %d (%s):\n\twant: %t\n\thave: %t",
+				i+1,
+				tc.service,
+				tc.expected,
+				present,
+			)
+		}
+	}
+}
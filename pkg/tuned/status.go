@@ -92,6 +92,9 @@ func computeStatusConditions(status Bits, stderr string, conditions []tunedv1.Pr
 	if (status & scUnknown) != 0 {
 		return InitializeStatusConditions()
 	}
+	if (status & scRolledBack) != 0 {
+		return computeRolledBackStatusConditions(conditions)
+	}
 
 	tunedProfileAppliedCondition := tunedv1.ProfileStatusCondition{
 		Type: tunedv1.TunedProfileApplied,
@@ -131,5 +134,77 @@ func computeStatusConditions(status Bits, stderr string, conditions []tunedv1.Pr
 	conditions = setStatusCondition(conditions, &tunedProfileAppliedCondition)
 	conditions = setStatusCondition(conditions, &tunedDegradedCondition)
 
+	return removeStatusCondition(conditions, tunedv1.TunedRolledBack)
+}
+
+// computeAppliedRevision returns the revision of the rendered content to report
+// as applied given the set of Bits 'status' of the last TuneD daemon (re)load,
+// whether the daemon is still 'reloading', the revision 'reloadRevision' of the
+// rendered content the daemon was (re)loaded with and the revision
+// 'appliedRevision' previously reported as applied.  A revision is reported as
+// applied only once the TuneD daemon applied its profile without errors;
+// otherwise the previously applied revision is kept.
+func computeAppliedRevision(status Bits, reloading bool, reloadRevision string, appliedRevision string) string {
+	if reloading || (status&scApplied) == 0 || (status&(scError|scUnknown|scRolledBack)) != 0 {
+		return appliedRevision
+	}
+	return reloadRevision
+}
+
+// computeReloadRevision returns the revision of the rendered content the TuneD
+// daemon runs with given whether a 'reload' of the daemon is pending, the
+// revision 'reloadRevision' of the rendered content the daemon was last
+// (re)loaded with and the current revision 'renderedRevision' of the rendered
+// content.  A new rendered revision that leaves the TuneD profiles of this node
+// unchanged does not reload the daemon, which already runs its content.
+func computeReloadRevision(reload bool, reloadRevision string, renderedRevision string) string {
+	if reload {
+		// tunedReload() records the rendered revision.
+		return reloadRevision
+	}
+	return renderedRevision
+}
+
+// computeRolledBackStatusConditions returns an updated slice of
+// tunedv1.ProfileStatusCondition 'conditions' reporting the node-level tuning
+// rolled back on the operator's request.
+func computeRolledBackStatusConditions(conditions []tunedv1.ProfileStatusCondition) []tunedv1.ProfileStatusCondition {
+	tunedProfileAppliedCondition := tunedv1.ProfileStatusCondition{
+		Type:    tunedv1.TunedProfileApplied,
+		Status:  corev1.ConditionFalse,
+		Reason:  "RolledBack",
+		Message: "The TuneD daemon profile rolled back.",
+	}
+	tunedDegradedCondition := tunedv1.ProfileStatusCondition{
+		Type:    tunedv1.TunedDegraded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "RolledBack",
+		Message: "The TuneD daemon profile rolled back.",
+	}
+	tunedRolledBackCondition := tunedv1.ProfileStatusCondition{
+		Type:    tunedv1.TunedRolledBack,
+		Status:  corev1.ConditionTrue,
+		Reason:  "AsExpected",
+		Message: "TuneD daemon stopped, node-level tuning rolled back and TuneD profiles removed.",
+	}
+
+	conditions = setStatusCondition(conditions, &tunedProfileAppliedCondition)
+	conditions = setStatusCondition(conditions, &tunedDegradedCondition)
+	conditions = setStatusCondition(conditions, &tunedRolledBackCondition)
+
 	return conditions
 }
+
+// removeStatusCondition returns the result of removing condition of type
+// 'condType' from the given slice of conditions.
+func removeStatusCondition(conditions []tunedv1.ProfileStatusCondition, condType tunedv1.ProfileConditionType) []tunedv1.ProfileStatusCondition {
+	newConditions := []tunedv1.ProfileStatusCondition{}
+
+	for _, c := range conditions {
+		if c.Type != condType {
+			newConditions = append(newConditions, c)
+		}
+	}
+
+	return newConditions
+}
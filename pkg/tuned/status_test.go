@@ -0,0 +1,143 @@
+package tuned
+
+import (
+	"testing"
+)
+
+func TestComputeAppliedRevision(t *testing.T) {
+	var tests = []struct {
+		status         Bits
+		reloading      bool
+		reloadRevision string
+		applied        string
+		expected       string
+	}{
+		{
+			// profile applied
+			status:         scApplied,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-2",
+		},
+		{
+			// profile applied with warnings
+			status:         scApplied | scWarn,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-2",
+		},
+		{
+			// still reloading
+			status:         scApplied,
+			reloading:      true,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-1",
+		},
+		{
+			// profile not applied yet
+			status:         0,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-1",
+		},
+		{
+			// profile applied with errors
+			status:         scApplied | scError,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-1",
+		},
+		{
+			// timeout waiting for the profile to be applied
+			status:         scTimeout,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-1",
+		},
+		{
+			// status reset by a pending change
+			status:         scUnknown,
+			reloadRevision: "rendered-2",
+			applied:        "rendered-1",
+			expected:       "rendered-1",
+		},
+		{
+			// first successful application
+			status:         scApplied,
+			reloadRevision: "rendered-1",
+			applied:        "",
+			expected:       "rendered-1",
+		},
+	}
+
+	for i, tc := range tests {
+		applied := computeAppliedRevision(tc.status, tc.reloading, tc.reloadRevision, tc.applied)
+		if applied != tc.expected {
+			t.Errorf(
+				"failed test case %d:\n\twant: %s\n\thave: %s",
+				i+1,
+				tc.expected,
+				applied,
+			)
+		}
+	}
+}
+
+func TestComputeReloadRevision(t *testing.T) {
+	var tests = []struct {
+		reload           bool
+		reloadRevision   string
+		renderedRevision string
+		status           Bits
+		applied          string
+		expected         string
+	}{
+		{
+			// new rendered revision without a change of the node's profiles
+			reloadRevision:   "rendered-1",
+			renderedRevision: "rendered-2",
+			status:           scApplied,
+			applied:          "rendered-1",
+			expected:         "rendered-2",
+		},
+		{
+			// rollout of the new rendered revision waits for the reload
+			reload:           true,
+			reloadRevision:   "rendered-1",
+			renderedRevision: "rendered-2",
+			status:           scApplied,
+			applied:          "rendered-1",
+			expected:         "rendered-1",
+		},
+		{
+			// rollback to a rendered revision without a change of the node's profiles
+			reloadRevision:   "rendered-2",
+			renderedRevision: "rendered-1",
+			status:           scApplied,
+			applied:          "rendered-2",
+			expected:         "rendered-1",
+		},
+		{
+			// the running profile was not applied successfully
+			reloadRevision:   "rendered-1",
+			renderedRevision: "rendered-2",
+			status:           scApplied | scError,
+			applied:          "",
+			expected:         "",
+		},
+	}
+
+	for i, tc := range tests {
+		reloadRevision := computeReloadRevision(tc.reload, tc.reloadRevision, tc.renderedRevision)
+		applied := computeAppliedRevision(tc.status, false, reloadRevision, tc.applied)
+		if applied != tc.expected {
+			t.Errorf(
+				"failed test case %d:\n\twant: %s\n\thave: %s",
+				i+1,
+				tc.expected,
+				applied,
+			)
+		}
+	}
+}
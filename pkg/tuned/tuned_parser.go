@@ -191,18 +191,20 @@ func profileExists(profileName string, tunedProfilesDir string) bool {
 // Note: only basic expansion of TuneD built-in functions into profiles is
 // performed.  See expandTuneDBuiltin for more detail.
 func profileDepends(profileName string) map[string]bool {
-	return profileDependsLoop(profileName, map[string]bool{})
+	return profileDependsLoop(profileName, map[string]bool{}, profileIncludes)
 }
 
-func profileDependsLoop(profileName string, seenProfiles map[string]bool) map[string]bool {
-	profiles := profileIncludes(profileName)
+// profileDependsLoop adds the TuneD profiles 'profileName' depends on to
+// 'seenProfiles'.  Function 'includes' returns the profiles a profile includes.
+func profileDependsLoop(profileName string, seenProfiles map[string]bool, includes func(string) []string) map[string]bool {
+	profiles := includes(profileName)
 	for _, profile := range profiles {
 		if seenProfiles[profile] {
 			// We have already seen/processed custom profile 'p'.
 			continue
 		}
 		seenProfiles[profile] = true
-		seenProfiles = profileDependsLoop(profile, seenProfiles)
+		seenProfiles = profileDependsLoop(profile, seenProfiles, includes)
 	}
 	return seenProfiles
 }
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+)
+
+const (
+	// HyperShiftNodePoolLabel is the annotation on management cluster ConfigMaps
+	// with the NodePool ("namespace/name") referencing them and the label on
+	// operator-generated management cluster ConfigMaps and hosted cluster Nodes
+	// with the name of their NodePool.
+	HyperShiftNodePoolLabel = "hypershift.openshift.io/nodePool"
+	// HyperShiftTunedConfigMapLabel is the label on management cluster ConfigMaps
+	// with embedded Tuned objects.
+	HyperShiftTunedConfigMapLabel = "hypershift.openshift.io/tuned-config"
+	// HyperShiftTunedConfigMapConfigKey is the ConfigMap data key of the embedded
+	// Tuned objects.
+	HyperShiftTunedConfigMapConfigKey = "tuned"
+	// HyperShiftGeneratedMachineConfigLabel is the label on management cluster
+	// ConfigMaps with operator-generated MachineConfigs and KubeletConfigs the
+	// NodePool controller rolls out to the NodePool named by the
+	// HyperShiftNodePoolLabel label.
+	HyperShiftGeneratedMachineConfigLabel = "hypershift.openshift.io/nto-generated-machine-config"
+	// HyperShiftTunedMachineConfigLabel is the label on the management cluster
+	// ConfigMaps with the MachineConfigs carrying the kernel parameters calculated
+	// by the operands.  Only the Tuned controller writes and prunes ConfigMaps
+	// with this label; other operator-generated ConfigMaps with the
+	// HyperShiftGeneratedMachineConfigLabel label are left alone.
+	HyperShiftTunedMachineConfigLabel = "tuned.openshift.io/generated-machine-config"
+	// HyperShiftMachineConfigConfigMapKey is the ConfigMap data key the NodePool
+	// controller reads the embedded MachineConfig or KubeletConfig from.
+	HyperShiftMachineConfigConfigMapKey = "config"
+)
+
+// ParseNamespacedName expects a string with the format "namespace/name"
+// and returns the name only.
+// If given a string in the format "name" returns "name".
+func ParseNamespacedName(namespacedName string) string {
+	parts := strings.SplitN(namespacedName, "/", 2)
+	if len(parts) > 1 {
+		return parts[1]
+	}
+	return parts[0]
+}
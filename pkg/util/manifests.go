@@ -0,0 +1,121 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+)
+
+// ReadManifests decodes the objects from YAML or JSON manifests 'paths' using
+// decoder 'decoder'.  Directories are searched recursively for *.yaml, *.yml
+// and *.json files, e.g. to read a must-gather.  A manifest can contain multiple
+// documents and lists of objects.  Objects of kinds unknown to 'decoder' are
+// skipped.
+func ReadManifests(paths []string, decoder runtime.Decoder) ([]runtime.Object, error) {
+	var objs []runtime.Object
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".yaml", ".yml", ".json":
+			default:
+				if file != path {
+					// Only skip files found by walking a directory.
+					return nil
+				}
+			}
+			fileObjs, err := readManifest(file, decoder)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", file, err)
+			}
+			objs = append(objs, fileObjs...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return objs, nil
+}
+
+// readManifest decodes the objects from YAML or JSON manifest 'file'.
+func readManifest(file string, decoder runtime.Decoder) ([]runtime.Object, error) {
+	var objs []runtime.Object
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		raw := runtime.RawExtension{}
+		if err := d.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			// Empty document
+			continue
+		}
+		docObjs, err := decodeManifest(raw.Raw, decoder)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, docObjs...)
+	}
+
+	return objs, nil
+}
+
+// decodeManifest decodes a single manifest document 'data' and flattens lists.
+func decodeManifest(data []byte, decoder runtime.Decoder) ([]runtime.Object, error) {
+	obj, gvk, err := decoder.Decode(data, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+			klog.V(2).Infof("skipping manifest of unknown kind: %v", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if list, ok := obj.(*corev1.List); ok {
+		var objs []runtime.Object
+		for _, item := range list.Items {
+			itemObjs, err := decodeManifest(item.Raw, decoder)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, itemObjs...)
+		}
+		return objs, nil
+	}
+
+	if meta.IsListType(obj) {
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s items: %v", gvk.Kind, err)
+		}
+		return items, nil
+	}
+
+	return []runtime.Object{obj}, nil
+}